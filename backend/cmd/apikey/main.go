@@ -0,0 +1,130 @@
+// Command apikey manages HalalGuard organizations, their quotas and API keys from the command line.
+//
+//	go run ./cmd/apikey org -id bmt-amanah -name "BMT Amanah"
+//	go run ./cmd/apikey quota -org bmt-amanah -daily-transactions 500 -monthly-tokens 2000000
+//	go run ./cmd/apikey create -org bmt-amanah -name ci-pipeline -role admin
+//	go run ./cmd/apikey list -org bmt-amanah
+//	go run ./cmd/apikey revoke -org bmt-amanah -id 3
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"halalguard-backend/config"
+	"halalguard-backend/database"
+	"halalguard-backend/models"
+	"halalguard-backend/services"
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: apikey org -id ID -name NAME | quota [-org ORG] [-daily-transactions N] [-monthly-transactions N] [-daily-tokens N] [-monthly-tokens N] | create [-org ORG] -name NAME -role ROLE | list [-org ORG] | revoke [-org ORG] -id ID")
+	os.Exit(2)
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	cfg := config.Load()
+	if err := database.Connect(cfg); err != nil {
+		log.Fatalf("❌ Failed to connect to database: %v", err)
+	}
+	defer database.Close()
+
+	switch os.Args[1] {
+	case "org":
+		fs := flag.NewFlagSet("org", flag.ExitOnError)
+		id := fs.String("id", "", "organization ID (slug)")
+		name := fs.String("name", "", "display name of the organization")
+		fs.Parse(os.Args[2:])
+		if *id == "" || *name == "" {
+			usage()
+		}
+
+		org, err := services.SaveOrganization(*id, *name)
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		fmt.Printf("Saved organization %s (%s)\n", org.ID, org.Name)
+
+	case "quota":
+		fs := flag.NewFlagSet("quota", flag.ExitOnError)
+		org := fs.String("org", models.DefaultOrganizationID, "organization whose quota to set")
+		var quota models.TenantQuota
+		fs.Int64Var(&quota.DailyTransactions, "daily-transactions", 0, "transactions analyzed per day (0 = unlimited)")
+		fs.Int64Var(&quota.MonthlyTransactions, "monthly-transactions", 0, "transactions analyzed per month (0 = unlimited)")
+		fs.Int64Var(&quota.DailyTokens, "daily-tokens", 0, "Gemini tokens per day (0 = unlimited)")
+		fs.Int64Var(&quota.MonthlyTokens, "monthly-tokens", 0, "Gemini tokens per month (0 = unlimited)")
+		fs.Parse(os.Args[2:])
+		if quota.DailyTransactions < 0 || quota.MonthlyTransactions < 0 || quota.DailyTokens < 0 || quota.MonthlyTokens < 0 {
+			usage()
+		}
+
+		if err := services.SetTenantQuota(*org, quota); err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		fmt.Printf("Set quota of organization %s: %d/%d transactions, %d/%d tokens per day/month\n", *org,
+			quota.DailyTransactions, quota.MonthlyTransactions, quota.DailyTokens, quota.MonthlyTokens)
+
+	case "create":
+		fs := flag.NewFlagSet("create", flag.ExitOnError)
+		org := fs.String("org", models.DefaultOrganizationID, "organization the key belongs to")
+		name := fs.String("name", "", "descriptive name of the client")
+		role := fs.String("role", "", "role granted to the key")
+		fs.Parse(os.Args[2:])
+		if *name == "" || *role == "" {
+			usage()
+		}
+
+		key, err := services.CreateAPIKey(*org, *name, *role)
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		fmt.Printf("Created API key %d (%s, role %s, organization %s)\n", key.ID, key.Name, key.Role, key.OrganizationID)
+		fmt.Printf("Key: %s\n", key.Key)
+		fmt.Println("Store it now, it cannot be shown again.")
+
+	case "list":
+		fs := flag.NewFlagSet("list", flag.ExitOnError)
+		org := fs.String("org", models.DefaultOrganizationID, "organization whose keys to list")
+		fs.Parse(os.Args[2:])
+
+		keys, err := services.GetAPIKeys(*org)
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tNAME\tPREFIX\tROLE\tCREATED\tREVOKED")
+		for _, key := range keys {
+			revoked := "-"
+			if key.RevokedAt != nil {
+				revoked = key.RevokedAt.Format("2006-01-02 15:04")
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", key.ID, key.Name, key.Prefix, key.Role,
+				key.CreatedAt.Format("2006-01-02 15:04"), revoked)
+		}
+		w.Flush()
+
+	case "revoke":
+		fs := flag.NewFlagSet("revoke", flag.ExitOnError)
+		org := fs.String("org", models.DefaultOrganizationID, "organization the key belongs to")
+		id := fs.Int64("id", 0, "ID of the key to revoke")
+		fs.Parse(os.Args[2:])
+		if *id == 0 {
+			usage()
+		}
+
+		if err := services.RevokeAPIKey(*org, *id); err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		fmt.Printf("Revoked API key %d\n", *id)
+
+	default:
+		usage()
+	}
+}
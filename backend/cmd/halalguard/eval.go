@@ -0,0 +1,562 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"halalguard-backend/config"
+	"halalguard-backend/logging"
+	"halalguard-backend/models"
+	"halalguard-backend/services"
+)
+
+// missingVerdict stands for a case the analyzer returned no result for
+const missingVerdict = "-"
+
+// evalDataset is a versioned set of labeled transactions
+type evalDataset struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	// Language is the analysis language of the dataset, defaulting to id
+	Language string     `json:"language,omitempty"`
+	Cases    []evalCase `json:"cases"`
+}
+
+// evalCase is a transaction with its expected verdict
+type evalCase struct {
+	Transaction models.TransactionInput `json:"transaction"`
+	Expected    evalExpectation         `json:"expected"`
+	Note        string                  `json:"note,omitempty"`
+}
+
+// evalExpectation is the verdict a case must receive. Scores maps confidenceScore and
+// the breakdown scores (ribaScore, ghararScore, ...) to their accepted range.
+type evalExpectation struct {
+	Status        string                `json:"status"`
+	ViolationType string                `json:"violationType"`
+	Scores        map[string]scoreRange `json:"scores,omitempty"`
+}
+
+// scoreRange bounds a score; a nil bound is open
+type scoreRange struct {
+	Min *float64 `json:"min,omitempty"`
+	Max *float64 `json:"max,omitempty"`
+}
+
+func (r scoreRange) contains(value float64) bool {
+	return (r.Min == nil || value >= *r.Min) && (r.Max == nil || value <= *r.Max)
+}
+
+// evalReport is the outcome of an evaluation run. A saved report serves as the
+// baseline of later runs.
+type evalReport struct {
+	Dataset        string    `json:"dataset"`
+	DatasetVersion string    `json:"datasetVersion"`
+	Model          string    `json:"model"`
+	PromptVersion  string    `json:"promptVersion,omitempty"`
+	CreatedAt      time.Time `json:"createdAt"`
+	Cases          int       `json:"cases"`
+	Answered       int       `json:"answered"`
+	// StatusAccuracy and ViolationAccuracy are the shares of cases with the expected
+	// status and violation type; ScoreRangePassRate the share within all score ranges
+	StatusAccuracy     float64 `json:"statusAccuracy"`
+	ViolationAccuracy  float64 `json:"violationAccuracy"`
+	ScoreRangePassRate float64 `json:"scoreRangePassRate"`
+	// ViolationTypes holds precision and recall per violation type
+	ViolationTypes map[string]*typeMetrics `json:"violationTypes"`
+	// Confusion counts cases by expected, then predicted violation type ("-" = no result)
+	Confusion map[string]map[string]int `json:"confusion"`
+	Results   []caseResult              `json:"results"`
+	Drift     *evalDrift                `json:"drift,omitempty"`
+}
+
+// typeMetrics are the classification metrics of one violation type
+type typeMetrics struct {
+	// Support is the number of cases expecting the type, Predicted the number of
+	// results reporting it
+	Support       int     `json:"support"`
+	Predicted     int     `json:"predicted"`
+	TruePositives int     `json:"truePositives"`
+	Precision     float64 `json:"precision"`
+	Recall        float64 `json:"recall"`
+	F1            float64 `json:"f1"`
+}
+
+// caseResult is the verdict a case received
+type caseResult struct {
+	TransactionID         string   `json:"transactionId"`
+	ExpectedStatus        string   `json:"expectedStatus"`
+	ExpectedViolationType string   `json:"expectedViolationType"`
+	Status                string   `json:"status"`
+	ViolationType         string   `json:"violationType"`
+	ConfidenceScore       float64  `json:"confidenceScore"`
+	ScoreFailures         []string `json:"scoreFailures,omitempty"`
+}
+
+// evalDrift compares a run with its baseline
+type evalDrift struct {
+	BaselineModel          string    `json:"baselineModel"`
+	BaselinePromptVersion  string    `json:"baselinePromptVersion,omitempty"`
+	BaselineCreatedAt      time.Time `json:"baselineCreatedAt"`
+	ComparedCases          int       `json:"comparedCases"`
+	ChangedVerdicts        []string  `json:"changedVerdicts"`
+	MeanScoreDrift         float64   `json:"meanScoreDrift"`
+	StatusAccuracyDelta    float64   `json:"statusAccuracyDelta"`
+	ViolationAccuracyDelta float64   `json:"violationAccuracyDelta"`
+	// PrecisionDelta and RecallDelta are per violation type, current minus baseline
+	PrecisionDelta map[string]float64 `json:"precisionDelta"`
+	RecallDelta    map[string]float64 `json:"recallDelta"`
+	// Regressions lists the thresholds this run exceeded
+	Regressions []string `json:"regressions"`
+}
+
+// regressionThresholds bound how much worse than the baseline a run may be
+type regressionThresholds struct {
+	maxPrecisionDrop float64
+	maxRecallDrop    float64
+	maxAccuracyDrop  float64
+	maxScoreDrift    float64
+}
+
+func evaluate(args []string) int {
+	fs := flag.NewFlagSet("eval", flag.ExitOnError)
+	out := fs.String("out", "", "write the JSON report to this file")
+	baselinePath := fs.String("baseline", "", "report of an earlier run to compare against")
+	writeBaseline := fs.String("write-baseline", "", "save this run's report as the new baseline")
+	var thresholds regressionThresholds
+	fs.Float64Var(&thresholds.maxPrecisionDrop, "max-precision-drop", 0.05, "largest accepted precision drop of any violation type versus the baseline")
+	fs.Float64Var(&thresholds.maxRecallDrop, "max-recall-drop", 0.05, "largest accepted recall drop of any violation type versus the baseline")
+	fs.Float64Var(&thresholds.maxAccuracyDrop, "max-accuracy-drop", 0.05, "largest accepted status or violation accuracy drop versus the baseline")
+	fs.Float64Var(&thresholds.maxScoreDrift, "max-score-drift", 10, "largest accepted mean confidenceScore change versus the baseline, in points")
+	model := fs.String("model", "", "model name (default GEMINI_MODEL, OPENAI_MODEL or OLLAMA_MODEL for LLM_PROVIDER)")
+	applyGeneration := generationFlags(fs)
+	language := fs.String("language", "", "language of reasoning: id, en or ar (default from the dataset)")
+	rulesOnly := fs.Bool("rules-only", false, "evaluate the keyword rules instead of a model")
+	concurrency := fs.Int("concurrency", 2, "chunks analyzed in parallel")
+	chunkSize := fs.Int("chunk-size", 20, "transactions per model call")
+	promptDir := fs.String("prompt-dir", "", "directory of *.tmpl files overriding the prompt templates (default PROMPT_TEMPLATE_DIR)")
+	datasetPath := parseInterspersed(fs, args)
+	if datasetPath == "" || *concurrency < 1 || *chunkSize < 1 {
+		usage()
+	}
+	if *language != "" && !services.IsSupportedLanguage(*language) {
+		usage()
+	}
+
+	cfg := config.Load()
+	slog.SetDefault(slog.New(logging.NewHandler(os.Stderr, cfg.Log)))
+	applyGeneration(cfg)
+
+	dataset, err := loadDataset(datasetPath)
+	if err != nil {
+		return fail(err)
+	}
+	var baseline *evalReport
+	if *baselinePath != "" {
+		if baseline, err = loadReport(*baselinePath); err != nil {
+			return fail(err)
+		}
+		if baseline.Dataset != dataset.Name || baseline.DatasetVersion != dataset.Version {
+			fmt.Fprintf(os.Stderr, "⚠️  Baseline was made with dataset %s@%s; comparing cases with matching IDs only\n",
+				baseline.Dataset, baseline.DatasetVersion)
+		}
+	}
+
+	transactions := make([]models.TransactionInput, len(dataset.Cases))
+	for i, c := range dataset.Cases {
+		transactions[i] = c.Transaction
+	}
+	if *language == "" {
+		*language = dataset.Language
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var results []models.AnalysisResult
+	modelName := "rules"
+	if *rulesOnly {
+		results = services.AnalyzeWithRules(transactions)
+	} else {
+		analyzer, err := newAnalyzer(cfg, *promptDir)
+		if err != nil {
+			return fail(err)
+		}
+		defer analyzer.Close()
+
+		settings := models.TenantSettings{Model: *model, Language: services.AnalysisLanguage(*language)}
+		var usage models.TokenUsage
+		results, usage, err = analyzeChunks(ctx, analyzer, transactions, settings, *chunkSize, *concurrency)
+		if err != nil {
+			return fail(err)
+		}
+		usage = services.PriceTokenUsage(usage, cfg.LLMPrices)
+		for _, call := range usage.Calls {
+			fmt.Fprintf(os.Stderr, "  %s: %d tokens, %.6f %s\n", call.Model, call.TotalTokens, call.Cost, services.UsageCurrency)
+		}
+		fmt.Fprintf(os.Stderr, "Model %s: %d tokens, %.6f %s\n", usage.Model, usage.TotalTokens, usage.Cost, services.UsageCurrency)
+		modelName = usage.Model
+	}
+
+	report := scoreDataset(dataset, results)
+	report.Model = modelName
+	if baseline != nil {
+		report.Drift = compareReports(report, baseline, thresholds)
+	}
+
+	printReport(os.Stdout, report)
+	if *out != "" {
+		if err := saveReport(*out, report); err != nil {
+			return fail(err)
+		}
+	}
+	if *writeBaseline != "" {
+		baselineReport := *report
+		baselineReport.Drift = nil
+		if err := saveReport(*writeBaseline, &baselineReport); err != nil {
+			return fail(err)
+		}
+		fmt.Fprintf(os.Stderr, "Baseline written to %s\n", *writeBaseline)
+	}
+
+	if report.Drift != nil && len(report.Drift.Regressions) > 0 {
+		for _, regression := range report.Drift.Regressions {
+			fmt.Fprintf(os.Stderr, "❌ Regression: %s\n", regression)
+		}
+		return exitThreshold
+	}
+	return 0
+}
+
+// loadDataset reads and validates a labeled dataset
+func loadDataset(path string) (*evalDataset, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var dataset evalDataset
+	if err := json.Unmarshal(data, &dataset); err != nil {
+		return nil, fmt.Errorf("invalid dataset %s: %w", path, err)
+	}
+	if dataset.Name == "" || dataset.Version == "" {
+		return nil, fmt.Errorf("dataset %s needs a name and version", path)
+	}
+	if dataset.Language != "" && !services.IsSupportedLanguage(dataset.Language) {
+		return nil, fmt.Errorf("dataset %s has unsupported language %q", path, dataset.Language)
+	}
+	if len(dataset.Cases) == 0 {
+		return nil, fmt.Errorf("dataset %s has no cases", path)
+	}
+
+	seen := make(map[string]bool, len(dataset.Cases))
+	for i, c := range dataset.Cases {
+		id := c.Transaction.ID
+		switch {
+		case id == "" || c.Transaction.Description == "":
+			return nil, fmt.Errorf("case %d needs a transaction id and description", i+1)
+		case seen[id]:
+			return nil, fmt.Errorf("duplicate case %q", id)
+		case !models.IsValidStatus(c.Expected.Status):
+			return nil, fmt.Errorf("case %q expects unknown status %q", id, c.Expected.Status)
+		case !models.IsValidViolationType(c.Expected.ViolationType):
+			return nil, fmt.Errorf("case %q expects unknown violation type %q", id, c.Expected.ViolationType)
+		}
+		for name := range c.Expected.Scores {
+			if _, ok := resultScore(models.AnalysisResult{}, name); !ok {
+				return nil, fmt.Errorf("case %q has a range for unknown score %q", id, name)
+			}
+		}
+		seen[id] = true
+	}
+	return &dataset, nil
+}
+
+// resultScore returns the named score of a result
+func resultScore(result models.AnalysisResult, name string) (float64, bool) {
+	switch name {
+	case "confidenceScore":
+		return result.ConfidenceScore, true
+	case "ribaScore":
+		return result.Breakdown.RibaScore, true
+	case "ghararScore":
+		return result.Breakdown.GhararScore, true
+	case "maysirScore":
+		return result.Breakdown.MaysirScore, true
+	case "halalScore":
+		return result.Breakdown.HalalScore, true
+	case "justiceScore":
+		return result.Breakdown.JusticeScore, true
+	}
+	return 0, false
+}
+
+// scoreDataset compares the results with the dataset's expectations
+func scoreDataset(dataset *evalDataset, results []models.AnalysisResult) *evalReport {
+	byID := make(map[string]models.AnalysisResult, len(results))
+	for _, result := range results {
+		byID[result.TransactionID] = result
+	}
+
+	report := &evalReport{
+		Dataset:        dataset.Name,
+		DatasetVersion: dataset.Version,
+		CreatedAt:      time.Now().UTC(),
+		Cases:          len(dataset.Cases),
+		ViolationTypes: make(map[string]*typeMetrics),
+		Confusion:      make(map[string]map[string]int),
+	}
+	metricsOf := func(violationType string) *typeMetrics {
+		if report.ViolationTypes[violationType] == nil {
+			report.ViolationTypes[violationType] = &typeMetrics{}
+		}
+		return report.ViolationTypes[violationType]
+	}
+
+	var statusHits, violationHits, scorePasses int
+	for _, c := range dataset.Cases {
+		expected := c.Expected
+		row := caseResult{
+			TransactionID:         c.Transaction.ID,
+			ExpectedStatus:        expected.Status,
+			ExpectedViolationType: expected.ViolationType,
+			Status:                missingVerdict,
+			ViolationType:         missingVerdict,
+		}
+		metricsOf(expected.ViolationType).Support++
+
+		if result, ok := byID[c.Transaction.ID]; ok {
+			report.Answered++
+			if report.PromptVersion == "" {
+				report.PromptVersion = result.PromptVersion
+			}
+			row.Status = result.Status
+			row.ViolationType = result.ViolationType
+			row.ConfidenceScore = result.ConfidenceScore
+			metricsOf(result.ViolationType).Predicted++
+			for _, name := range sortedKeys(expected.Scores) {
+				value, _ := resultScore(result, name)
+				if !expected.Scores[name].contains(value) {
+					row.ScoreFailures = append(row.ScoreFailures, fmt.Sprintf("%s=%g", name, value))
+				}
+			}
+		} else {
+			row.ScoreFailures = []string{"no result"}
+		}
+
+		if row.Status == expected.Status {
+			statusHits++
+		}
+		if row.ViolationType == expected.ViolationType {
+			violationHits++
+			metricsOf(expected.ViolationType).TruePositives++
+		}
+		if len(row.ScoreFailures) == 0 {
+			scorePasses++
+		}
+		if report.Confusion[expected.ViolationType] == nil {
+			report.Confusion[expected.ViolationType] = make(map[string]int)
+		}
+		report.Confusion[expected.ViolationType][row.ViolationType]++
+		report.Results = append(report.Results, row)
+	}
+
+	n := float64(len(dataset.Cases))
+	report.StatusAccuracy = float64(statusHits) / n
+	report.ViolationAccuracy = float64(violationHits) / n
+	report.ScoreRangePassRate = float64(scorePasses) / n
+	for _, m := range report.ViolationTypes {
+		if m.Predicted > 0 {
+			m.Precision = float64(m.TruePositives) / float64(m.Predicted)
+		}
+		if m.Support > 0 {
+			m.Recall = float64(m.TruePositives) / float64(m.Support)
+		}
+		if m.Precision+m.Recall > 0 {
+			m.F1 = 2 * m.Precision * m.Recall / (m.Precision + m.Recall)
+		}
+	}
+	return report
+}
+
+// compareReports measures the drift of report from baseline over the cases both share
+// and lists the thresholds it exceeds. Precision and recall are only compared for
+// violation types the baseline has cases or predictions for.
+func compareReports(report, baseline *evalReport, thresholds regressionThresholds) *evalDrift {
+	drift := &evalDrift{
+		BaselineModel:          baseline.Model,
+		BaselinePromptVersion:  baseline.PromptVersion,
+		BaselineCreatedAt:      baseline.CreatedAt,
+		ChangedVerdicts:        []string{},
+		StatusAccuracyDelta:    report.StatusAccuracy - baseline.StatusAccuracy,
+		ViolationAccuracyDelta: report.ViolationAccuracy - baseline.ViolationAccuracy,
+		PrecisionDelta:         make(map[string]float64),
+		RecallDelta:            make(map[string]float64),
+		Regressions:            []string{},
+	}
+
+	before := make(map[string]caseResult, len(baseline.Results))
+	for _, row := range baseline.Results {
+		before[row.TransactionID] = row
+	}
+	var scoreDrift float64
+	for _, row := range report.Results {
+		old, ok := before[row.TransactionID]
+		if !ok {
+			continue
+		}
+		drift.ComparedCases++
+		if old.Status != row.Status || old.ViolationType != row.ViolationType {
+			drift.ChangedVerdicts = append(drift.ChangedVerdicts, row.TransactionID)
+		}
+		scoreDrift += math.Abs(row.ConfidenceScore - old.ConfidenceScore)
+	}
+	if drift.ComparedCases > 0 {
+		drift.MeanScoreDrift = scoreDrift / float64(drift.ComparedCases)
+	}
+
+	for _, violationType := range sortedKeys(baseline.ViolationTypes) {
+		old := baseline.ViolationTypes[violationType]
+		current := report.ViolationTypes[violationType]
+		if current == nil {
+			current = &typeMetrics{}
+		}
+		drift.PrecisionDelta[violationType] = current.Precision - old.Precision
+		drift.RecallDelta[violationType] = current.Recall - old.Recall
+		if -drift.PrecisionDelta[violationType] > thresholds.maxPrecisionDrop {
+			drift.Regressions = append(drift.Regressions, fmt.Sprintf("%s precision dropped from %.3f to %.3f", violationType, old.Precision, current.Precision))
+		}
+		if -drift.RecallDelta[violationType] > thresholds.maxRecallDrop {
+			drift.Regressions = append(drift.Regressions, fmt.Sprintf("%s recall dropped from %.3f to %.3f", violationType, old.Recall, current.Recall))
+		}
+	}
+	if -drift.StatusAccuracyDelta > thresholds.maxAccuracyDrop {
+		drift.Regressions = append(drift.Regressions, fmt.Sprintf("status accuracy dropped from %.3f to %.3f", baseline.StatusAccuracy, report.StatusAccuracy))
+	}
+	if -drift.ViolationAccuracyDelta > thresholds.maxAccuracyDrop {
+		drift.Regressions = append(drift.Regressions, fmt.Sprintf("violation accuracy dropped from %.3f to %.3f", baseline.ViolationAccuracy, report.ViolationAccuracy))
+	}
+	if drift.MeanScoreDrift > thresholds.maxScoreDrift {
+		drift.Regressions = append(drift.Regressions, fmt.Sprintf("mean confidenceScore drift of %.1f points exceeds %.1f", drift.MeanScoreDrift, thresholds.maxScoreDrift))
+	}
+	return drift
+}
+
+// printReport writes the metrics, confusion matrix and drift as tables
+func printReport(w io.Writer, report *evalReport) {
+	fmt.Fprintf(w, "Dataset %s@%s, model %s", report.Dataset, report.DatasetVersion, report.Model)
+	if report.PromptVersion != "" {
+		fmt.Fprintf(w, ", prompt %s", report.PromptVersion)
+	}
+	fmt.Fprintf(w, "\n%d cases, %d answered: status accuracy %.1f%%, violation accuracy %.1f%%, score ranges met %.1f%%\n\n",
+		report.Cases, report.Answered, report.StatusAccuracy*100, report.ViolationAccuracy*100, report.ScoreRangePassRate*100)
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "VIOLATION\tSUPPORT\tPREDICTED\tPRECISION\tRECALL\tF1")
+	types := sortedKeys(report.ViolationTypes)
+	for _, violationType := range types {
+		m := report.ViolationTypes[violationType]
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.3f\t%.3f\t%.3f\n", violationType, m.Support, m.Predicted, m.Precision, m.Recall, m.F1)
+	}
+	tw.Flush()
+
+	// Confusion matrix: rows are expected, columns predicted violation types
+	columns := types
+	for _, row := range report.Confusion {
+		if row[missingVerdict] > 0 {
+			columns = append(columns, missingVerdict)
+			break
+		}
+	}
+	fmt.Fprintln(w, "\nEXPECTED \\ PREDICTED")
+	tw = tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprint(tw, "")
+	for _, column := range columns {
+		fmt.Fprintf(tw, "\t%s", column)
+	}
+	fmt.Fprintln(tw)
+	for _, expected := range sortedKeys(report.Confusion) {
+		fmt.Fprint(tw, expected)
+		for _, column := range columns {
+			fmt.Fprintf(tw, "\t%d", report.Confusion[expected][column])
+		}
+		fmt.Fprintln(tw)
+	}
+	tw.Flush()
+
+	var failures []caseResult
+	for _, row := range report.Results {
+		if row.Status != row.ExpectedStatus || row.ViolationType != row.ExpectedViolationType || len(row.ScoreFailures) > 0 {
+			failures = append(failures, row)
+		}
+	}
+	if len(failures) > 0 {
+		fmt.Fprintln(w, "\nMISMATCHES")
+		tw = tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tEXPECTED\tACTUAL\tSCORES OUT OF RANGE")
+		for _, row := range failures {
+			scores := missingVerdict
+			if len(row.ScoreFailures) > 0 {
+				scores = strings.Join(row.ScoreFailures, ", ")
+			}
+			fmt.Fprintf(tw, "%s\t%s/%s\t%s/%s\t%s\n", row.TransactionID, row.ExpectedStatus, row.ExpectedViolationType,
+				row.Status, row.ViolationType, scores)
+		}
+		tw.Flush()
+	}
+
+	if d := report.Drift; d != nil {
+		fmt.Fprintf(w, "\nVersus baseline (model %s, %s): %d cases compared, %d verdicts changed, mean confidenceScore drift %.1f points\n",
+			d.BaselineModel, d.BaselineCreatedAt.Format(time.RFC3339), d.ComparedCases, len(d.ChangedVerdicts), d.MeanScoreDrift)
+		fmt.Fprintf(w, "Status accuracy %+.3f, violation accuracy %+.3f\n", d.StatusAccuracyDelta, d.ViolationAccuracyDelta)
+		tw = tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "VIOLATION\tΔPRECISION\tΔRECALL")
+		for _, violationType := range sortedKeys(d.PrecisionDelta) {
+			fmt.Fprintf(tw, "%s\t%+.3f\t%+.3f\n", violationType, d.PrecisionDelta[violationType], d.RecallDelta[violationType])
+		}
+		tw.Flush()
+	}
+}
+
+func loadReport(path string) (*evalReport, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var report evalReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("invalid baseline %s: %w", path, err)
+	}
+	if len(report.Results) == 0 {
+		return nil, errors.New("baseline " + path + " has no results")
+	}
+	return &report, nil
+}
+
+func saveReport(path string, report *evalReport) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
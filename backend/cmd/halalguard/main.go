@@ -0,0 +1,384 @@
+// Command halalguard analyzes a transaction statement offline, without the HTTP
+// server or a database, and evaluates the analyzer against a labeled dataset.
+//
+//	go run ./cmd/halalguard analyze statement.csv --out results.jsonl
+//	go run ./cmd/halalguard analyze statement.csv -rules-only -format table
+//	go run ./cmd/halalguard analyze statement.csv -model gemini-2.5-pro -concurrency 4 -max-non-compliant 5
+//	go run ./cmd/halalguard analyze statement.csv -temperature 0 -seed 42
+//	go run ./cmd/halalguard eval eval/golden-v1.json -write-baseline eval/baseline.json
+//	go run ./cmd/halalguard eval eval/golden-v1.json -baseline eval/baseline.json
+//
+// The statement is a CSV with the header id,description,amount,date,type. Exit codes:
+// 0 success, 1 error, 2 usage, 3 non-compliant transactions above -max-non-compliant
+// or an evaluation regression beyond its thresholds.
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"halalguard-backend/config"
+	"halalguard-backend/logging"
+	"halalguard-backend/models"
+	"halalguard-backend/prompts"
+	"halalguard-backend/services"
+)
+
+const (
+	exitError     = 1
+	exitUsage     = 2
+	exitThreshold = 3
+)
+
+// Output formats
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+	formatCSV   = "csv"
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: halalguard analyze STATEMENT.csv [-out FILE] [-format table|json|csv] [-model MODEL] [-temperature T] [-top-p P] [-top-k K] [-max-output-tokens N] [-seed N] [-language id|en|ar] [-rules-only] [-prompt-dir DIR] [-concurrency N] [-chunk-size N] [-max-non-compliant PERCENT]")
+	fmt.Fprintln(os.Stderr, "       halalguard eval DATASET.json [-out REPORT.json] [-baseline REPORT.json] [-write-baseline FILE] [-max-precision-drop D] [-max-recall-drop D] [-max-accuracy-drop D] [-max-score-drift POINTS] [-model MODEL] [-temperature T] [-top-p P] [-top-k K] [-max-output-tokens N] [-seed N] [-language id|en|ar] [-rules-only] [-prompt-dir DIR] [-concurrency N] [-chunk-size N]")
+	os.Exit(exitUsage)
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	switch os.Args[1] {
+	case "analyze":
+		os.Exit(analyze(os.Args[2:]))
+	case "eval":
+		os.Exit(evaluate(os.Args[2:]))
+	}
+	usage()
+}
+
+func analyze(args []string) int {
+	fs := flag.NewFlagSet("analyze", flag.ExitOnError)
+	out := fs.String("out", "", "output file (default stdout)")
+	format := fs.String("format", "", "output format: table, json (JSON Lines) or csv (default from -out extension, else table)")
+	model := fs.String("model", "", "model name (default GEMINI_MODEL, OPENAI_MODEL or OLLAMA_MODEL for LLM_PROVIDER)")
+	applyGeneration := generationFlags(fs)
+	language := fs.String("language", models.LanguageIndonesian, "language of reasoning: id, en or ar")
+	rulesOnly := fs.Bool("rules-only", false, "classify by keywords only, without calling a model")
+	concurrency := fs.Int("concurrency", 2, "chunks analyzed in parallel")
+	chunkSize := fs.Int("chunk-size", 20, "transactions per model call")
+	promptDir := fs.String("prompt-dir", "", "directory of *.tmpl files overriding the prompt templates (default PROMPT_TEMPLATE_DIR)")
+	maxNonCompliant := fs.Float64("max-non-compliant", 100, "exit with code 3 when more than this percentage of transactions is non-compliant")
+	statement := parseInterspersed(fs, args)
+	if statement == "" || *concurrency < 1 || *chunkSize < 1 {
+		usage()
+	}
+	if *format == "" {
+		*format = formatFromExtension(*out)
+	}
+	if *format != formatTable && *format != formatJSON && *format != formatCSV {
+		usage()
+	}
+	if !services.IsSupportedLanguage(*language) {
+		usage()
+	}
+
+	cfg := config.Load()
+	// Logs go to stderr so they never mix with results written to stdout
+	slog.SetDefault(slog.New(logging.NewHandler(os.Stderr, cfg.Log)))
+	applyGeneration(cfg)
+
+	file, err := os.Open(statement)
+	if err != nil {
+		return fail(err)
+	}
+	transactions, err := services.ParseTransactionsCSV(file)
+	file.Close()
+	if err != nil {
+		return fail(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var results []models.AnalysisResult
+	if *rulesOnly {
+		results = services.AnalyzeWithRules(transactions)
+	} else {
+		analyzer, err := newAnalyzer(cfg, *promptDir)
+		if err != nil {
+			return fail(err)
+		}
+		defer analyzer.Close()
+
+		settings := models.TenantSettings{Model: *model, Language: *language}
+		var usage models.TokenUsage
+		results, usage, err = analyzeChunks(ctx, analyzer, transactions, settings, *chunkSize, *concurrency)
+		if err != nil {
+			return fail(err)
+		}
+		usage = services.PriceTokenUsage(usage, cfg.LLMPrices)
+		for _, call := range usage.Calls {
+			fmt.Fprintf(os.Stderr, "  %s: %d tokens, %.6f %s\n", call.Model, call.TotalTokens, call.Cost, services.UsageCurrency)
+		}
+		fmt.Fprintf(os.Stderr, "Model %s: %d tokens, %.6f %s\n", usage.Model, usage.TotalTokens, usage.Cost, services.UsageCurrency)
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return fail(err)
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := writeResults(w, *format, transactions, results); err != nil {
+		return fail(err)
+	}
+
+	return summarize(transactions, results, *maxNonCompliant)
+}
+
+// parseInterspersed parses flags placed before or after the single positional argument
+func parseInterspersed(fs *flag.FlagSet, args []string) string {
+	var positional []string
+	for {
+		fs.Parse(args)
+		if fs.NArg() == 0 {
+			break
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+	if len(positional) != 1 {
+		return ""
+	}
+	return positional[0]
+}
+
+func formatFromExtension(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json", ".jsonl", ".ndjson":
+		return formatJSON
+	case ".csv":
+		return formatCSV
+	}
+	return formatTable
+}
+
+// generationFlags registers the sampling flags on fs. The returned function copies the
+// flags that were given onto cfg, so the environment applies to the others.
+func generationFlags(fs *flag.FlagSet) func(cfg *config.Config) {
+	temperature := fs.Float64("temperature", 0, "sampling temperature (default GEMINI_TEMPERATURE)")
+	topP := fs.Float64("top-p", 0, "nucleus sampling probability (default GEMINI_TOP_P)")
+	topK := fs.Int("top-k", 0, "top-k sampling (default GEMINI_TOP_K)")
+	maxOutputTokens := fs.Int("max-output-tokens", 0, "maximum response tokens (default GEMINI_MAX_OUTPUT_TOKENS)")
+	seed := fs.Int("seed", 0, "sampling seed recorded with the results (default GEMINI_SEED)")
+	return func(cfg *config.Config) {
+		fs.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "temperature":
+				cfg.Gemini.Temperature = *temperature
+			case "top-p":
+				cfg.Gemini.TopP = *topP
+			case "top-k":
+				cfg.Gemini.TopK = *topK
+			case "max-output-tokens":
+				cfg.Gemini.MaxOutputTokens = *maxOutputTokens
+			case "seed":
+				cfg.Gemini.Seed = seed
+			}
+		})
+	}
+}
+
+// newAnalyzer validates the configuration and creates the analyzer of LLM_PROVIDER with
+// the prompt templates of promptDir, or PROMPT_TEMPLATE_DIR when empty
+func newAnalyzer(cfg *config.Config, promptDir string) (services.Analyzer, error) {
+	if cfg.LLMProvider == services.ProviderGemini && cfg.GeminiAPIKey == "" {
+		return nil, errors.New("GEMINI_API_KEY is required unless -rules-only is set")
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if promptDir == "" {
+		promptDir = cfg.PromptTemplateDir
+	}
+	templates, err := prompts.Load(promptDir)
+	if err != nil {
+		return nil, err
+	}
+	return services.NewAnalyzer(cfg, templates)
+}
+
+// addUsage adds the token usage of a chunk to total, keeping an ensemble's per-model calls apart
+func addUsage(total *models.TokenUsage, usage models.TokenUsage) {
+	total.Model = usage.Model
+	total.PromptTokens += usage.PromptTokens
+	total.CandidateTokens += usage.CandidateTokens
+	total.TotalTokens += usage.TotalTokens
+	for _, call := range usage.Calls {
+		i := slices.IndexFunc(total.Calls, func(c models.TokenUsage) bool { return c.Model == call.Model })
+		if i < 0 {
+			total.Calls = append(total.Calls, models.TokenUsage{Model: call.Model})
+			i = len(total.Calls) - 1
+		}
+		total.Calls[i].PromptTokens += call.PromptTokens
+		total.Calls[i].CandidateTokens += call.CandidateTokens
+		total.Calls[i].TotalTokens += call.TotalTokens
+	}
+}
+
+// analyzeChunks sends the transactions to the analyzer in chunks, running up to concurrency
+// calls at once, and returns the results in statement order with the summed token usage
+func analyzeChunks(ctx context.Context, analyzer services.Analyzer, transactions []models.TransactionInput, settings models.TenantSettings, chunkSize, concurrency int) ([]models.AnalysisResult, models.TokenUsage, error) {
+	var chunks [][]models.TransactionInput
+	for start := 0; start < len(transactions); start += chunkSize {
+		chunks = append(chunks, transactions[start:min(start+chunkSize, len(transactions))])
+	}
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		byID     = make(map[string]models.AnalysisResult, len(transactions))
+		total    models.TokenUsage
+		firstErr error
+	)
+	sem := make(chan struct{}, concurrency)
+	for i, chunk := range chunks {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, chunk []models.TransactionInput) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			results, usage, err := analyzer.AnalyzeTransactions(ctx, chunk, nil, settings, nil)
+			mu.Lock()
+			defer mu.Unlock()
+			addUsage(&total, usage)
+			if err != nil {
+				if firstErr == nil {
+					firstErr = fmt.Errorf("chunk %d of %d: %w", i+1, len(chunks), err)
+				}
+				return
+			}
+			for _, result := range results {
+				byID[result.TransactionID] = result
+			}
+			fmt.Fprintf(os.Stderr, "Analyzed chunk %d of %d\n", i+1, len(chunks))
+		}(i, chunk)
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, total, firstErr
+	}
+
+	results := make([]models.AnalysisResult, 0, len(transactions))
+	for _, tx := range transactions {
+		if result, ok := byID[tx.ID]; ok {
+			results = append(results, result)
+		}
+	}
+	return results, total, nil
+}
+
+func writeResults(w io.Writer, format string, transactions []models.TransactionInput, results []models.AnalysisResult) error {
+	switch format {
+	case formatJSON:
+		encoder := json.NewEncoder(w)
+		for _, result := range results {
+			if err := encoder.Encode(result); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case formatCSV:
+		writer := csv.NewWriter(w)
+		writer.Write([]string{
+			"transaction_id", "status", "violation_type", "confidence_score",
+			"riba_score", "gharar_score", "maysir_score", "halal_score", "justice_score",
+			"reasoning", "suggested_correction",
+		})
+		for _, r := range results {
+			writer.Write([]string{
+				r.TransactionID, r.Status, r.ViolationType, formatFloat(r.ConfidenceScore),
+				formatFloat(r.Breakdown.RibaScore), formatFloat(r.Breakdown.GhararScore), formatFloat(r.Breakdown.MaysirScore),
+				formatFloat(r.Breakdown.HalalScore), formatFloat(r.Breakdown.JusticeScore),
+				r.Reasoning, r.SuggestedCorrection,
+			})
+		}
+		writer.Flush()
+		return writer.Error()
+	}
+
+	amounts := make(map[string]float64, len(transactions))
+	for _, tx := range transactions {
+		amounts[tx.ID] = tx.Amount
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tAMOUNT\tSTATUS\tVIOLATION\tCONFIDENCE\tREASONING")
+	for _, r := range results {
+		fmt.Fprintf(tw, "%s\t%.2f\t%s\t%s\t%.0f\t%s\n",
+			r.TransactionID, amounts[r.TransactionID], r.Status, r.ViolationType, r.ConfidenceScore, truncate(r.Reasoning, 80))
+	}
+	return tw.Flush()
+}
+
+// summarize prints status counts to stderr and returns the exit code
+func summarize(transactions []models.TransactionInput, results []models.AnalysisResult, maxNonCompliant float64) int {
+	counts := make(map[string]int)
+	for _, result := range results {
+		counts[result.Status]++
+	}
+	missing := len(transactions) - len(results)
+	nonCompliant := 0.0
+	if len(transactions) > 0 {
+		nonCompliant = float64(counts[models.StatusNonCompliant]) / float64(len(transactions)) * 100
+	}
+
+	fmt.Fprintf(os.Stderr, "%d transactions: %d %s, %d %s, %d %s",
+		len(transactions), counts[models.StatusCompliant], models.StatusCompliant,
+		counts[models.StatusNonCompliant], models.StatusNonCompliant, counts[models.StatusNeedsReview], models.StatusNeedsReview)
+	if missing > 0 {
+		fmt.Fprintf(os.Stderr, ", %d without result", missing)
+	}
+	fmt.Fprintf(os.Stderr, " (%.1f%% non-compliant)\n", nonCompliant)
+
+	if nonCompliant > maxNonCompliant {
+		fmt.Fprintf(os.Stderr, "Non-compliant share %.1f%% exceeds the threshold of %.1f%%\n", nonCompliant, maxNonCompliant)
+		return exitThreshold
+	}
+	return 0
+}
+
+func fail(err error) int {
+	fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+	return exitError
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
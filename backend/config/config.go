@@ -1,8 +1,14 @@
 package config
 
 import (
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -10,8 +16,198 @@ import (
 type Config struct {
 	Port         string
 	GeminiAPIKey string
-	Database     DatabaseConfig
-	CORSOrigin   string
+	Gemini       GeminiConfig
+	// LLMProvider selects the model provider: gemini, openai (any OpenAI-compatible
+	// chat completions endpoint) or ollama
+	LLMProvider string
+	OpenAI      OpenAIConfig
+	Ollama      OllamaConfig
+	Ensemble    EnsembleConfig
+	Calibration CalibrationConfig
+	Database    DatabaseConfig
+	CORSOrigin  string
+	// TrustedProxies lists the proxy IPs or CIDRs whose X-Forwarded-For header is
+	// believed when resolving the client IP; empty trusts no proxy
+	TrustedProxies []string
+	Purification   PurificationConfig
+	Auth           AuthConfig
+	// PromptTemplateDir holds *.tmpl files overriding the embedded prompt templates
+	PromptTemplateDir string
+	// ReviewConfidenceThreshold queues analyses below this confidenceScore for human review
+	ReviewConfidenceThreshold float64
+	// ScreeningMethodology selects the Sharia stock screening standard (AAOIFI, OJK, DJIM)
+	ScreeningMethodology string
+	RateLimit            RateLimitConfig
+	Health               HealthConfig
+	Log                  LogConfig
+	Tracing              TracingConfig
+	Jobs                 JobsConfig
+	Shutdown             ShutdownConfig
+	// LLMPrices maps model names to token prices used for cost accounting
+	LLMPrices map[string]ModelPrice
+}
+
+// ModelPrice is the USD price of one million tokens of a model
+type ModelPrice struct {
+	InputPerMillion  float64
+	OutputPerMillion float64
+}
+
+// GeminiConfig controls the model, sampling, retries and result caching of analysis
+// calls. Everything except Model also applies to the openai and ollama providers.
+type GeminiConfig struct {
+	// Model is the Gemini model used when a tenant does not configure its own model
+	Model string
+	// Temperature, TopP and TopK control sampling; a low temperature keeps results stable between runs
+	Temperature float64
+	TopP        float64
+	TopK        int
+	// MaxOutputTokens bounds the length of a response
+	MaxOutputTokens int
+	// Seed makes sampling reproducible on providers that support it; nil leaves it unseeded
+	Seed *int
+	// MaxRetries is the number of retries after a transient GenerateContent error
+	MaxRetries int
+	// RetryBackoff is the delay before the first retry; it doubles on each retry
+	RetryBackoff time.Duration
+	// CacheSize is the number of analysis results kept in memory; 0 disables the cache
+	CacheSize int
+	// CacheTTL is how long a cached analysis result is reused
+	CacheTTL time.Duration
+	// CircuitThreshold is the number of consecutive failed calls that opens the
+	// circuit breaker; 0 disables it
+	CircuitThreshold int
+	// CircuitCooldown is how long the circuit stays open before a trial call
+	CircuitCooldown time.Duration
+}
+
+// OpenAIConfig configures an OpenAI-compatible chat completions endpoint
+type OpenAIConfig struct {
+	// BaseURL is the API root, e.g. https://api.openai.com/v1 or http://localhost:8000/v1 for vLLM
+	BaseURL string
+	// APIKey is sent as a bearer token; self-hosted servers usually need none
+	APIKey string
+	// Model is used when a tenant does not configure its own model
+	Model   string
+	Timeout time.Duration
+}
+
+// OllamaConfig configures an Ollama server
+type OllamaConfig struct {
+	BaseURL string
+	// Model is used when a tenant does not configure its own model
+	Model   string
+	Timeout time.Duration
+}
+
+// EnsembleConfig controls voting by several models, or several samples of one model,
+// on every analysis
+type EnsembleConfig struct {
+	// Models are asked instead of the tenant's model; empty samples the tenant's model
+	Models []string
+	// Samples is the number of answers requested from each model
+	Samples int
+	// MinAgreement is the share of votes the majority verdict needs; contested results
+	// are set to "Butuh Tinjauan"
+	MinAgreement float64
+	// MaxScoreSpread is the largest confidenceScore range among the majority votes
+	// before the result counts as contested
+	MaxScoreSpread float64
+}
+
+// Enabled reports whether analyses are voted on by more than one model or sample
+func (e EnsembleConfig) Enabled() bool {
+	return len(e.Models) > 1 || e.Samples > 1
+}
+
+// CalibrationConfig controls how raw confidence scores are calibrated against labels
+type CalibrationConfig struct {
+	// Method is isotonic or platt
+	Method string
+	// MinSamples is the number of labeled analyses a violation type needs to be calibrated
+	MinSamples int
+}
+
+// LogConfig controls structured logging
+type LogConfig struct {
+	// Level is debug, info, warn or error; amounts, descriptions and AI
+	// responses are only logged unredacted at debug
+	Level string
+	// Format is json or text
+	Format string
+}
+
+// TracingConfig controls OpenTelemetry trace export
+type TracingConfig struct {
+	// Exporter is otlp, stdout or none
+	Exporter string
+	// Endpoint is the OTLP/HTTP collector URL, e.g. http://localhost:4318
+	Endpoint string
+	// ServiceName identifies the backend in traces
+	ServiceName string
+	// SampleRatio is the fraction of new traces that are recorded
+	SampleRatio float64
+}
+
+// JobsConfig controls the background analysis job workers
+type JobsConfig struct {
+	// Workers is the number of concurrent job workers; 0 disables them
+	Workers int
+	// PollInterval is how long an idle worker waits before checking the queue again
+	PollInterval time.Duration
+	// Lease is how long a claimed job may go without its worker renewing the lease
+	// before another worker may reclaim it
+	Lease time.Duration
+	// MaxAttempts is how many times a job is claimed before a job whose worker keeps
+	// dying is marked failed instead of reclaimed
+	MaxAttempts int
+}
+
+// ShutdownConfig controls graceful shutdown
+type ShutdownConfig struct {
+	// DrainTimeout bounds how long shutdown waits for in-flight requests and jobs
+	DrainTimeout time.Duration
+}
+
+// HealthConfig controls the readiness probe
+type HealthConfig struct {
+	// DBTimeout bounds the database ping of the readiness probe
+	DBTimeout time.Duration
+}
+
+// RateLimitConfig controls the per API key / per IP token bucket
+type RateLimitConfig struct {
+	// RequestsPerSecond is the bucket refill rate; 0 disables rate limiting
+	RequestsPerSecond float64
+	// Burst is the bucket capacity
+	Burst int
+	// IPRequestsPerSecond and IPBurst size the per client IP bucket checked before
+	// authentication, so failed credential guesses are throttled too; 0 disables it
+	IPRequestsPerSecond float64
+	IPBurst             int
+}
+
+// PurificationConfig controls how non-compliant income is purified (tathir)
+type PurificationConfig struct {
+	// SyubhatRatio is the proportion of Syubhat income that must be purified
+	SyubhatRatio float64
+	// IncomeTypes lists the transaction types treated as income (case-insensitive)
+	IncomeTypes []string
+}
+
+// AuthConfig controls JWT bearer token verification
+type AuthConfig struct {
+	// JWTAlgorithm is HS256 (shared secret) or RS256 (keys from JWKSFile)
+	JWTAlgorithm string
+	JWTSecret    string
+	JWKSFile     string
+	JWTIssuer    string
+	JWTAudience  string
+	// JWTRoleClaim names the claim carrying the user's role
+	JWTRoleClaim string
+	// JWTOrgClaim names the claim carrying the user's organization; tokens
+	// without it belong to the default organization
+	JWTOrgClaim string
 }
 
 type DatabaseConfig struct {
@@ -21,6 +217,9 @@ type DatabaseConfig struct {
 	Password string
 	DBName   string
 	SSLMode  string
+	// RowLevelSecurity installs Postgres row-level security policies on tenant tables.
+	// They bind other database roles only; the backend owns the tables.
+	RowLevelSecurity bool
 }
 
 func Load() *Config {
@@ -33,16 +232,78 @@ func Load() *Config {
 		envFile = ".env.local"
 	}
 	if err := godotenv.Load(envFile); err != nil {
-		log.Printf("No %s file found, trying .env", envFile)
+		slog.Info("env file not found, trying .env", "file", envFile)
 		if err := godotenv.Load(); err != nil {
-			log.Println("No .env file found, using environment variables")
+			slog.Info("no .env file found, using environment variables")
 		}
 	}
 
 	return &Config{
 		Port:         getEnv("PORT", "8087"),
 		GeminiAPIKey: getEnv("GEMINI_API_KEY", ""),
-		CORSOrigin:   getEnv("CORS_ORIGIN", "http://localhost:5173"),
+		Gemini: GeminiConfig{
+			Model:           getEnv("GEMINI_MODEL", "gemini-2.5-flash"),
+			Temperature:     getEnvFloat("GEMINI_TEMPERATURE", 0),
+			TopP:            getEnvFloat("GEMINI_TOP_P", 0.95),
+			TopK:            getEnvInt("GEMINI_TOP_K", 40),
+			MaxOutputTokens: getEnvInt("GEMINI_MAX_OUTPUT_TOKENS", 8192),
+			Seed:            getEnvOptionalInt("GEMINI_SEED"),
+
+			MaxRetries:   getEnvInt("GEMINI_MAX_RETRIES", 2),
+			RetryBackoff: getEnvDuration("GEMINI_RETRY_BACKOFF", time.Second),
+			CacheSize:    getEnvInt("ANALYSIS_CACHE_SIZE", 1000),
+			CacheTTL:     getEnvDuration("ANALYSIS_CACHE_TTL", 24*time.Hour),
+
+			CircuitThreshold: getEnvInt("GEMINI_CIRCUIT_THRESHOLD", 5),
+			CircuitCooldown:  getEnvDuration("GEMINI_CIRCUIT_COOLDOWN", 30*time.Second),
+		},
+		LLMProvider: getEnv("LLM_PROVIDER", "gemini"),
+		OpenAI: OpenAIConfig{
+			BaseURL: getEnv("OPENAI_BASE_URL", "https://api.openai.com/v1"),
+			APIKey:  getEnv("OPENAI_API_KEY", ""),
+			Model:   getEnv("OPENAI_MODEL", ""),
+			Timeout: getEnvDuration("OPENAI_TIMEOUT", 2*time.Minute),
+		},
+		Ollama: OllamaConfig{
+			BaseURL: getEnv("OLLAMA_BASE_URL", "http://localhost:11434"),
+			Model:   getEnv("OLLAMA_MODEL", ""),
+			Timeout: getEnvDuration("OLLAMA_TIMEOUT", 5*time.Minute),
+		},
+		Ensemble: EnsembleConfig{
+			Models:         getEnvList("ENSEMBLE_MODELS", ""),
+			Samples:        getEnvInt("ENSEMBLE_SAMPLES", 1),
+			MinAgreement:   getEnvFloat("ENSEMBLE_MIN_AGREEMENT", 1),
+			MaxScoreSpread: getEnvFloat("ENSEMBLE_MAX_SCORE_SPREAD", 30),
+		},
+		Calibration: CalibrationConfig{
+			Method:     getEnv("CALIBRATION_METHOD", "isotonic"),
+			MinSamples: getEnvInt("CALIBRATION_MIN_SAMPLES", 30),
+		},
+		PromptTemplateDir: getEnv("PROMPT_TEMPLATE_DIR", ""),
+		Log: LogConfig{
+			Level:  getEnv("LOG_LEVEL", "info"),
+			Format: getEnv("LOG_FORMAT", "json"),
+		},
+		Tracing: TracingConfig{
+			Exporter:    getEnv("TRACING_EXPORTER", "none"),
+			Endpoint:    getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://localhost:4318"),
+			ServiceName: getEnv("OTEL_SERVICE_NAME", "halalguard-backend"),
+			SampleRatio: getEnvFloat("TRACING_SAMPLE_RATIO", 1),
+		},
+		Jobs: JobsConfig{
+			Workers:      getEnvInt("ANALYSIS_WORKERS", 2),
+			PollInterval: getEnvDuration("ANALYSIS_JOB_POLL_INTERVAL", 2*time.Second),
+			Lease:        getEnvDuration("ANALYSIS_JOB_LEASE", 10*time.Minute),
+			MaxAttempts:  getEnvInt("ANALYSIS_JOB_MAX_ATTEMPTS", 3),
+		},
+		Shutdown: ShutdownConfig{
+			DrainTimeout: getEnvDuration("SHUTDOWN_DRAIN_TIMEOUT", 30*time.Second),
+		},
+		Health: HealthConfig{
+			DBTimeout: getEnvDuration("HEALTH_DB_TIMEOUT", 2*time.Second),
+		},
+		CORSOrigin:     getEnv("CORS_ORIGIN", "http://localhost:5173"),
+		TrustedProxies: getEnvList("TRUSTED_PROXIES", ""),
 		Database: DatabaseConfig{
 			Host:     getEnv("DB_HOST", "localhost"),
 			Port:     getEnv("DB_PORT", "5432"),
@@ -50,13 +311,215 @@ func Load() *Config {
 			Password: getEnv("DB_PASSWORD", ""),
 			DBName:   getEnv("DB_NAME", "halalguard_db"),
 			SSLMode:  getEnv("DB_SSLMODE", "disable"),
+
+			RowLevelSecurity: getEnvBool("DB_ROW_LEVEL_SECURITY", false),
+		},
+		ScreeningMethodology:      getEnv("SCREENING_METHODOLOGY", "OJK"),
+		ReviewConfidenceThreshold: getEnvFloat("REVIEW_CONFIDENCE_THRESHOLD", 60),
+		Auth: AuthConfig{
+			JWTAlgorithm: getEnv("JWT_ALGORITHM", "HS256"),
+			JWTSecret:    getEnv("JWT_SECRET", ""),
+			JWKSFile:     getEnv("JWT_JWKS_FILE", ""),
+			JWTIssuer:    getEnv("JWT_ISSUER", ""),
+			JWTAudience:  getEnv("JWT_AUDIENCE", ""),
+			JWTRoleClaim: getEnv("JWT_ROLE_CLAIM", "role"),
+			JWTOrgClaim:  getEnv("JWT_ORG_CLAIM", "org"),
+		},
+		RateLimit: RateLimitConfig{
+			RequestsPerSecond:   getEnvFloat("RATE_LIMIT_RPS", 5),
+			Burst:               getEnvInt("RATE_LIMIT_BURST", 20),
+			IPRequestsPerSecond: getEnvFloat("RATE_LIMIT_IP_RPS", 10),
+			IPBurst:             getEnvInt("RATE_LIMIT_IP_BURST", 50),
+		},
+		LLMPrices: getEnvPrices("LLM_PRICES", "gemini-2.5-flash=0.30/2.50,gemini-2.5-pro=1.25/10.00,gemini-2.0-flash=0.10/0.40"),
+		Purification: PurificationConfig{
+			SyubhatRatio: getEnvFloat("PURIFICATION_SYUBHAT_RATIO", 0.5),
+			IncomeTypes:  getEnvList("PURIFICATION_INCOME_TYPES", "Income,Credit,Dividend,Profit,Return"),
 		},
 	}
 }
 
+// Validate reports configuration values that would make analysis fail or misbehave
+func (c *Config) Validate() error {
+	errs := []error{c.Gemini.Validate(), c.Ensemble.Validate(), c.Calibration.Validate(), c.Jobs.Validate()}
+	switch c.LLMProvider {
+	case "gemini":
+		if c.GeminiAPIKey == "" {
+			errs = append(errs, errors.New("GEMINI_API_KEY is required when LLM_PROVIDER=gemini"))
+		}
+	case "openai":
+		if c.OpenAI.BaseURL == "" || c.OpenAI.Model == "" {
+			errs = append(errs, errors.New("OPENAI_BASE_URL and OPENAI_MODEL are required when LLM_PROVIDER=openai"))
+		}
+	case "ollama":
+		if c.Ollama.BaseURL == "" || c.Ollama.Model == "" {
+			errs = append(errs, errors.New("OLLAMA_BASE_URL and OLLAMA_MODEL are required when LLM_PROVIDER=ollama"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("LLM_PROVIDER must be gemini, openai or ollama, got %q", c.LLMProvider))
+	}
+	return errors.Join(errs...)
+}
+
+// Validate checks the model and sampling parameters
+func (g GeminiConfig) Validate() error {
+	var errs []error
+	if strings.TrimSpace(g.Model) == "" {
+		errs = append(errs, errors.New("GEMINI_MODEL must not be empty"))
+	}
+	if g.Temperature < 0 || g.Temperature > 2 {
+		errs = append(errs, fmt.Errorf("GEMINI_TEMPERATURE must be between 0 and 2, got %g", g.Temperature))
+	}
+	if g.TopP <= 0 || g.TopP > 1 {
+		errs = append(errs, fmt.Errorf("GEMINI_TOP_P must be greater than 0 and at most 1, got %g", g.TopP))
+	}
+	if g.TopK < 1 {
+		errs = append(errs, fmt.Errorf("GEMINI_TOP_K must be at least 1, got %d", g.TopK))
+	}
+	if g.MaxOutputTokens < 1 || g.MaxOutputTokens > math.MaxInt32 {
+		errs = append(errs, fmt.Errorf("GEMINI_MAX_OUTPUT_TOKENS must be a positive 32-bit integer, got %d", g.MaxOutputTokens))
+	}
+	if g.Seed != nil && (*g.Seed < math.MinInt32 || *g.Seed > math.MaxInt32) {
+		errs = append(errs, fmt.Errorf("GEMINI_SEED must be a 32-bit integer, got %d", *g.Seed))
+	}
+	return errors.Join(errs...)
+}
+
+// Validate checks the ensemble size and voting thresholds
+func (e EnsembleConfig) Validate() error {
+	var errs []error
+	if e.Samples < 1 {
+		errs = append(errs, fmt.Errorf("ENSEMBLE_SAMPLES must be at least 1, got %d", e.Samples))
+	}
+	if e.MinAgreement <= 0 || e.MinAgreement > 1 {
+		errs = append(errs, fmt.Errorf("ENSEMBLE_MIN_AGREEMENT must be greater than 0 and at most 1, got %g", e.MinAgreement))
+	}
+	if e.MaxScoreSpread < 0 || e.MaxScoreSpread > 100 {
+		errs = append(errs, fmt.Errorf("ENSEMBLE_MAX_SCORE_SPREAD must be between 0 and 100, got %g", e.MaxScoreSpread))
+	}
+	return errors.Join(errs...)
+}
+
+// Validate checks the calibration method and sample minimum
+func (c CalibrationConfig) Validate() error {
+	var errs []error
+	if c.Method != "isotonic" && c.Method != "platt" {
+		errs = append(errs, fmt.Errorf("CALIBRATION_METHOD must be isotonic or platt, got %q", c.Method))
+	}
+	if c.MinSamples < 2 {
+		errs = append(errs, fmt.Errorf("CALIBRATION_MIN_SAMPLES must be at least 2, got %d", c.MinSamples))
+	}
+	return errors.Join(errs...)
+}
+
+// Validate reports lease and attempt settings that would keep jobs from ever finishing
+func (c JobsConfig) Validate() error {
+	var errs []error
+	if c.Lease <= 0 {
+		errs = append(errs, fmt.Errorf("ANALYSIS_JOB_LEASE must be positive, got %s", c.Lease))
+	}
+	if c.MaxAttempts < 1 {
+		errs = append(errs, fmt.Errorf("ANALYSIS_JOB_MAX_ATTEMPTS must be at least 1, got %d", c.MaxAttempts))
+	}
+	return errors.Join(errs...)
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
 	}
 	return defaultValue
 }
+
+func getEnvFloat(key string, defaultValue float64) float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		slog.Warn("invalid config value, using default", "key", key, "value", value, "default", defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		slog.Warn("invalid config value, using default", "key", key, "value", value, "default", defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+// getEnvOptionalInt returns nil when the variable is unset or invalid
+func getEnvOptionalInt(key string) *int {
+	value := os.Getenv(key)
+	if value == "" {
+		return nil
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		slog.Warn("invalid config value, ignoring", "key", key, "value", value)
+		return nil
+	}
+	return &parsed
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		slog.Warn("invalid config value, using default", "key", key, "value", value, "default", defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+func getEnvBool(key string, defaultValue bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		slog.Warn("invalid config value, using default", "key", key, "value", value, "default", defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+func getEnvList(key, defaultValue string) []string {
+	var values []string
+	for _, item := range strings.Split(getEnv(key, defaultValue), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			values = append(values, item)
+		}
+	}
+	return values
+}
+
+// getEnvPrices parses a comma-separated list of model=input/output prices per million tokens
+func getEnvPrices(key, defaultValue string) map[string]ModelPrice {
+	prices := make(map[string]ModelPrice)
+	for _, item := range getEnvList(key, defaultValue) {
+		model, price, ok := strings.Cut(item, "=")
+		input, output, ok2 := strings.Cut(price, "/")
+		inputPrice, err := strconv.ParseFloat(strings.TrimSpace(input), 64)
+		outputPrice, err2 := strconv.ParseFloat(strings.TrimSpace(output), 64)
+		if !ok || !ok2 || err != nil || err2 != nil {
+			slog.Warn("invalid price, expected model=input/output", "key", key, "value", item)
+			continue
+		}
+		prices[strings.TrimSpace(model)] = ModelPrice{InputPerMillion: inputPrice, OutputPerMillion: outputPrice}
+	}
+	return prices
+}
@@ -3,11 +3,13 @@ package database
 import (
 	"database/sql"
 	"fmt"
-	"log"
+	"log/slog"
 
 	"halalguard-backend/config"
 
+	"github.com/XSAM/otelsql"
 	_ "github.com/lib/pq"
+	semconv "go.opentelemetry.io/otel/semconv/v1.25.0"
 )
 
 var DB *sql.DB
@@ -24,8 +26,18 @@ func Connect(cfg *config.Config) error {
 		cfg.Database.SSLMode,
 	)
 
+	// Every statement is traced as a span; statements run with a request's
+	// context become children of its span. Query arguments are never recorded.
 	var err error
-	DB, err = sql.Open("postgres", connStr)
+	DB, err = otelsql.Open("postgres", connStr,
+		otelsql.WithAttributes(semconv.DBSystemPostgreSQL, semconv.DBName(cfg.Database.DBName)),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{
+			DisableErrSkip:       true,
+			OmitConnResetSession: true,
+			OmitConnectorConnect: true,
+			OmitRows:             true,
+		}),
+	)
 	if err != nil {
 		return fmt.Errorf("error opening database: %w", err)
 	}
@@ -35,31 +47,162 @@ func Connect(cfg *config.Config) error {
 		return fmt.Errorf("error connecting to database: %w", err)
 	}
 
-	log.Println("✅ Connected to PostgreSQL database")
+	slog.Info("connected to PostgreSQL database")
 
 	// Create tables if they don't exist
 	if err = createTables(); err != nil {
 		return fmt.Errorf("error creating tables: %w", err)
 	}
 
+	if cfg.Database.RowLevelSecurity {
+		if err = enableRowLevelSecurity(); err != nil {
+			return fmt.Errorf("error enabling row-level security: %w", err)
+		}
+	}
+
 	return nil
 }
 
 // createTables creates necessary database tables
 func createTables() error {
 	schema := `
+	CREATE TABLE IF NOT EXISTS organizations (
+		id VARCHAR(100) PRIMARY KEY,
+		name TEXT NOT NULL,
+		model VARCHAR(100) NOT NULL DEFAULT '',
+		language VARCHAR(10) NOT NULL DEFAULT 'id',
+		rule_packs TEXT[] NOT NULL DEFAULT '{}',
+		daily_transaction_quota BIGINT NOT NULL DEFAULT 0,
+		monthly_transaction_quota BIGINT NOT NULL DEFAULT 0,
+		daily_token_quota BIGINT NOT NULL DEFAULT 0,
+		monthly_token_quota BIGINT NOT NULL DEFAULT 0,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	ALTER TABLE organizations
+		ADD COLUMN IF NOT EXISTS daily_transaction_quota BIGINT NOT NULL DEFAULT 0,
+		ADD COLUMN IF NOT EXISTS monthly_transaction_quota BIGINT NOT NULL DEFAULT 0,
+		ADD COLUMN IF NOT EXISTS daily_token_quota BIGINT NOT NULL DEFAULT 0,
+		ADD COLUMN IF NOT EXISTS monthly_token_quota BIGINT NOT NULL DEFAULT 0;
+
+	CREATE TABLE IF NOT EXISTS usage_counters (
+		organization_id VARCHAR(100) REFERENCES organizations(id) ON DELETE CASCADE,
+		period VARCHAR(10) NOT NULL,
+		period_start DATE NOT NULL,
+		transactions BIGINT NOT NULL DEFAULT 0,
+		tokens BIGINT NOT NULL DEFAULT 0,
+		PRIMARY KEY (organization_id, period, period_start)
+	);
+
+	INSERT INTO organizations (id, name) VALUES ('default', 'Default Organization')
+	ON CONFLICT (id) DO NOTHING;
+
+	CREATE TABLE IF NOT EXISTS llm_usage (
+		id BIGSERIAL PRIMARY KEY,
+		organization_id VARCHAR(100) NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+		model VARCHAR(100) NOT NULL,
+		prompt_tokens BIGINT NOT NULL DEFAULT 0,
+		candidate_tokens BIGINT NOT NULL DEFAULT 0,
+		total_tokens BIGINT NOT NULL DEFAULT 0,
+		cost DECIMAL(14, 6) NOT NULL DEFAULT 0,
+		transaction_ids TEXT[] NOT NULL DEFAULT '{}',
+		succeeded BOOLEAN NOT NULL DEFAULT TRUE,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS analysis_jobs (
+		id BIGSERIAL PRIMARY KEY,
+		organization_id VARCHAR(100) NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+		state VARCHAR(20) NOT NULL DEFAULT 'queued',
+		transactions JSONB NOT NULL,
+		language VARCHAR(10) NOT NULL DEFAULT '',
+		generation JSONB,
+		result JSONB,
+		error TEXT NOT NULL DEFAULT '',
+		request_id VARCHAR(128) NOT NULL DEFAULT '',
+		attempts INTEGER NOT NULL DEFAULT 0,
+		lease_expires_at TIMESTAMP,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		started_at TIMESTAMP,
+		finished_at TIMESTAMP
+	);
+
 	CREATE TABLE IF NOT EXISTS transactions (
-		id VARCHAR(255) PRIMARY KEY,
+		organization_id VARCHAR(100) NOT NULL DEFAULT 'default' REFERENCES organizations(id),
+		id VARCHAR(255) NOT NULL,
 		description TEXT NOT NULL,
 		amount DECIMAL(15, 2) NOT NULL,
 		date VARCHAR(50) NOT NULL,
 		type VARCHAR(50) NOT NULL,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (organization_id, id)
 	);
 
+	-- try_date parses the YYYY-MM-DD prefix of a stored date, returning NULL instead of
+	-- failing for values such as 2024-13-45 so one bad row cannot break a report
+	CREATE OR REPLACE FUNCTION try_date(value TEXT) RETURNS DATE AS $$
+	BEGIN
+		IF value !~ '^\d{4}-\d{2}-\d{2}' THEN
+			RETURN NULL;
+		END IF;
+		RETURN LEFT(value, 10)::date;
+	EXCEPTION WHEN others THEN
+		RETURN NULL;
+	END;
+	$$ LANGUAGE plpgsql IMMUTABLE;
+
+	-- Databases created before multi-tenancy keyed transactions by id alone;
+	-- move every existing row into the default organization and rekey
+	DO $$
+	BEGIN
+		IF (SELECT COUNT(*) FROM information_schema.key_column_usage
+			WHERE table_name = 'transactions' AND constraint_name = 'transactions_pkey') = 1 THEN
+			ALTER TABLE transactions ADD COLUMN IF NOT EXISTS organization_id VARCHAR(100) NOT NULL DEFAULT 'default' REFERENCES organizations(id);
+			ALTER TABLE IF EXISTS analysis_results ADD COLUMN IF NOT EXISTS organization_id VARCHAR(100) NOT NULL DEFAULT 'default';
+			ALTER TABLE IF EXISTS reviews ADD COLUMN IF NOT EXISTS organization_id VARCHAR(100) NOT NULL DEFAULT 'default';
+			ALTER TABLE IF EXISTS approval_chains ADD COLUMN IF NOT EXISTS organization_id VARCHAR(100) NOT NULL DEFAULT 'default';
+			ALTER TABLE IF EXISTS approvals ADD COLUMN IF NOT EXISTS organization_id VARCHAR(100) NOT NULL DEFAULT 'default';
+			ALTER TABLE IF EXISTS approval_steps ADD COLUMN IF NOT EXISTS organization_id VARCHAR(100) NOT NULL DEFAULT 'default';
+			ALTER TABLE IF EXISTS purification_donations ADD COLUMN IF NOT EXISTS organization_id VARCHAR(100) NOT NULL DEFAULT 'default';
+			ALTER TABLE IF EXISTS api_keys ADD COLUMN IF NOT EXISTS organization_id VARCHAR(100) NOT NULL DEFAULT 'default' REFERENCES organizations(id);
+
+			-- CASCADE drops the single-column foreign keys of the dependent tables
+			ALTER TABLE transactions DROP CONSTRAINT transactions_pkey CASCADE;
+			ALTER TABLE transactions ADD PRIMARY KEY (organization_id, id);
+
+			ALTER TABLE IF EXISTS analysis_results DROP CONSTRAINT IF EXISTS analysis_results_transaction_id_key;
+			ALTER TABLE IF EXISTS analysis_results ADD UNIQUE (organization_id, transaction_id);
+			ALTER TABLE IF EXISTS analysis_results ADD FOREIGN KEY (organization_id, transaction_id)
+				REFERENCES transactions(organization_id, id) ON DELETE CASCADE;
+
+			ALTER TABLE IF EXISTS reviews DROP CONSTRAINT IF EXISTS reviews_transaction_id_key;
+			ALTER TABLE IF EXISTS reviews ADD UNIQUE (organization_id, transaction_id);
+			ALTER TABLE IF EXISTS reviews ADD FOREIGN KEY (organization_id, transaction_id)
+				REFERENCES transactions(organization_id, id) ON DELETE CASCADE;
+
+			ALTER TABLE IF EXISTS approval_chains DROP CONSTRAINT IF EXISTS approval_chains_name_key;
+			ALTER TABLE IF EXISTS approval_chains ADD UNIQUE (organization_id, name);
+
+			ALTER TABLE IF EXISTS approvals DROP CONSTRAINT IF EXISTS approvals_pkey;
+			ALTER TABLE IF EXISTS approvals ADD PRIMARY KEY (organization_id, transaction_id);
+			ALTER TABLE IF EXISTS approvals ADD FOREIGN KEY (organization_id, transaction_id)
+				REFERENCES transactions(organization_id, id) ON DELETE CASCADE;
+
+			ALTER TABLE IF EXISTS approval_steps DROP CONSTRAINT IF EXISTS approval_steps_transaction_id_step_index_key;
+			ALTER TABLE IF EXISTS approval_steps ADD UNIQUE (organization_id, transaction_id, step_index);
+			ALTER TABLE IF EXISTS approval_steps ADD FOREIGN KEY (organization_id, transaction_id)
+				REFERENCES transactions(organization_id, id) ON DELETE CASCADE;
+
+			ALTER TABLE IF EXISTS purification_donations ADD FOREIGN KEY (organization_id, transaction_id)
+				REFERENCES transactions(organization_id, id);
+		END IF;
+	END $$;
+
 	CREATE TABLE IF NOT EXISTS analysis_results (
 		id SERIAL PRIMARY KEY,
-		transaction_id VARCHAR(255) REFERENCES transactions(id) ON DELETE CASCADE,
+		organization_id VARCHAR(100) NOT NULL DEFAULT 'default',
+		transaction_id VARCHAR(255),
 		status VARCHAR(50) NOT NULL,
 		violation_type VARCHAR(50) NOT NULL,
 		confidence_score DECIMAL(5, 2) NOT NULL,
@@ -77,13 +220,345 @@ func createTables() error {
 		maslahah_projection TEXT,
 		reasoning TEXT NOT NULL,
 		suggested_correction TEXT,
+		screening JSONB,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE(organization_id, transaction_id),
+		FOREIGN KEY (organization_id, transaction_id) REFERENCES transactions(organization_id, id) ON DELETE CASCADE
+	);
+
+	ALTER TABLE analysis_results ADD COLUMN IF NOT EXISTS screening JSONB;
+	ALTER TABLE analysis_results ADD COLUMN IF NOT EXISTS prompt_version VARCHAR(200) NOT NULL DEFAULT '';
+	ALTER TABLE analysis_results ADD COLUMN IF NOT EXISTS generation JSONB;
+	ALTER TABLE analysis_results ADD COLUMN IF NOT EXISTS language VARCHAR(10) NOT NULL DEFAULT 'id';
+	-- Votes and individual model opinions of ensemble analyses
+	ALTER TABLE analysis_results ADD COLUMN IF NOT EXISTS ensemble JSONB;
+	-- The model's own confidence when confidence_score holds the calibrated value
+	ALTER TABLE analysis_results ADD COLUMN IF NOT EXISTS raw_confidence_score DECIMAL(5, 2);
+
+	-- Confirmed verdicts imported to calibrate confidence scores
+	CREATE TABLE IF NOT EXISTS calibration_labels (
+		organization_id VARCHAR(100) NOT NULL DEFAULT 'default',
+		transaction_id VARCHAR(255) NOT NULL,
+		status VARCHAR(50) NOT NULL,
+		violation_type VARCHAR(50) NOT NULL,
+		imported_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (organization_id, transaction_id)
+	);
+
+	-- Fitted confidence calibration per violation type
+	CREATE TABLE IF NOT EXISTS calibration_models (
+		organization_id VARCHAR(100) NOT NULL DEFAULT 'default',
+		violation_type VARCHAR(50) NOT NULL,
+		model JSONB NOT NULL,
+		fitted_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (organization_id, violation_type)
+	);
+
+	-- Reasoning per language; analysis_results holds the latest verdict
+	CREATE TABLE IF NOT EXISTS analysis_texts (
+		organization_id VARCHAR(100) NOT NULL DEFAULT 'default',
+		transaction_id VARCHAR(255) NOT NULL,
+		language VARCHAR(10) NOT NULL,
+		reasoning TEXT NOT NULL,
+		suggested_correction TEXT,
+		maslahah_projection TEXT,
+		prompt_version VARCHAR(200) NOT NULL DEFAULT '',
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (organization_id, transaction_id, language),
+		FOREIGN KEY (organization_id, transaction_id) REFERENCES transactions(organization_id, id) ON DELETE CASCADE
+	);
+
+	-- Results stored before analysis_texts existed are copied once, while the table is
+	-- still empty; afterwards every save writes both tables
+	DO $$
+	BEGIN
+		IF NOT EXISTS (SELECT 1 FROM analysis_texts) THEN
+			INSERT INTO analysis_texts (organization_id, transaction_id, language, reasoning, suggested_correction, maslahah_projection, prompt_version, created_at)
+			SELECT organization_id, transaction_id, language, reasoning, suggested_correction, maslahah_projection, prompt_version, created_at
+			FROM analysis_results
+			ON CONFLICT DO NOTHING;
+		END IF;
+	END $$;
+
+	CREATE TABLE IF NOT EXISTS reviews (
+		id SERIAL PRIMARY KEY,
+		organization_id VARCHAR(100) NOT NULL DEFAULT 'default',
+		transaction_id VARCHAR(255),
+		reviewer VARCHAR(255) NOT NULL,
+		state VARCHAR(20) NOT NULL,
+		assigned_at TIMESTAMP,
+		final_status VARCHAR(50),
+		final_violation_type VARCHAR(50),
+		justification TEXT,
+		decided_at TIMESTAMP,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE(organization_id, transaction_id),
+		FOREIGN KEY (organization_id, transaction_id) REFERENCES transactions(organization_id, id) ON DELETE CASCADE
+	);
+
+	CREATE TABLE IF NOT EXISTS approval_chains (
+		id SERIAL PRIMARY KEY,
+		organization_id VARCHAR(100) NOT NULL DEFAULT 'default',
+		name VARCHAR(100) NOT NULL,
+		transaction_type VARCHAR(50),
+		min_amount DECIMAL(15, 2) NOT NULL DEFAULT 0,
+		steps TEXT[] NOT NULL,
 		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		UNIQUE(transaction_id)
+		UNIQUE(organization_id, name)
 	);
 
+	CREATE TABLE IF NOT EXISTS approvals (
+		organization_id VARCHAR(100) NOT NULL DEFAULT 'default',
+		transaction_id VARCHAR(255) NOT NULL,
+		chain_id INTEGER REFERENCES approval_chains(id),
+		state VARCHAR(20) NOT NULL,
+		locked_at TIMESTAMP,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (organization_id, transaction_id),
+		FOREIGN KEY (organization_id, transaction_id) REFERENCES transactions(organization_id, id) ON DELETE CASCADE
+	);
+
+	CREATE TABLE IF NOT EXISTS approval_steps (
+		id SERIAL PRIMARY KEY,
+		organization_id VARCHAR(100) NOT NULL DEFAULT 'default',
+		transaction_id VARCHAR(255),
+		chain_id INTEGER REFERENCES approval_chains(id),
+		step_index INTEGER NOT NULL,
+		role VARCHAR(20) NOT NULL,
+		approver VARCHAR(255) NOT NULL,
+		decision VARCHAR(20) NOT NULL,
+		comment TEXT,
+		decided_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE(organization_id, transaction_id, step_index),
+		FOREIGN KEY (organization_id, transaction_id) REFERENCES transactions(organization_id, id) ON DELETE CASCADE
+	);
+
+	CREATE TABLE IF NOT EXISTS api_keys (
+		id SERIAL PRIMARY KEY,
+		organization_id VARCHAR(100) NOT NULL DEFAULT 'default' REFERENCES organizations(id),
+		name VARCHAR(255) NOT NULL,
+		prefix VARCHAR(20) NOT NULL,
+		key_hash VARCHAR(64) NOT NULL UNIQUE,
+		role VARCHAR(50) NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		last_used_at TIMESTAMP,
+		revoked_at TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS roles (
+		name VARCHAR(50) PRIMARY KEY,
+		description TEXT NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS permissions (
+		name VARCHAR(100) PRIMARY KEY,
+		description TEXT NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS role_permissions (
+		role VARCHAR(50) REFERENCES roles(name) ON DELETE CASCADE,
+		permission VARCHAR(100) REFERENCES permissions(name) ON DELETE CASCADE,
+		PRIMARY KEY (role, permission)
+	);
+
+	INSERT INTO permissions (name, description) VALUES
+		('transactions:analyze', 'Submit transactions for analysis'),
+		('transactions:read', 'Read transactions and analysis results'),
+		('reports:export', 'Export compliance audit reports'),
+		('stats:read', 'Read aggregate statistics'),
+		('purification:read', 'Read income purification summaries and donations'),
+		('purification:record', 'Record purification donations'),
+		('zakat:calculate', 'Calculate zakat maal'),
+		('zakat:configure', 'Set the gold price used for nisab'),
+		('screening:read', 'Read issuer ratios and screening results'),
+		('screening:manage', 'Import issuer ratios and edit screening methodologies'),
+		('reviews:read', 'Read the human review queue'),
+		('reviews:decide', 'Assign reviews and override AI verdicts'),
+		('approvals:read', 'Read approval chains and approval status'),
+		('approvals:decide', 'Approve or reject approval chain steps'),
+		('approvals:configure', 'Edit approval chains'),
+		('apikeys:manage', 'Issue and revoke API keys'),
+		('roles:manage', 'Edit role permissions'),
+		('organization:manage', 'Edit the organization''s model, language and rule packs'),
+		('usage:read', 'Read LLM token usage and cost'),
+		('usage:read_all', 'Read the LLM usage and cost of other organizations'),
+		('prompts:manage', 'List prompt templates and preview rendered prompts'),
+		('analysis:override', 'Override the model and sampling parameters of an analysis request'),
+		('calibration:read', 'Read confidence calibration curves and Brier scores'),
+		('calibration:manage', 'Import calibration labels and refit confidence calibration')
+	ON CONFLICT (name) DO NOTHING;
+
+	-- Default grants are only applied when a role is first created so that
+	-- permissions edited by an admin survive restarts
+	WITH new_roles AS (
+		INSERT INTO roles (name, description) VALUES
+			('submitter', 'Submits transactions for analysis'),
+			('reviewer', 'Reviews and overrides AI verdicts'),
+			('auditor', 'Reads and exports compliance data'),
+			('dps', 'Dewan Pengawas Syariah, signs off approval chains'),
+			('admin', 'Manages rules, roles and API keys')
+		ON CONFLICT (name) DO NOTHING
+		RETURNING name
+	)
+	INSERT INTO role_permissions (role, permission)
+	SELECT grants.role, grants.permission
+	FROM (VALUES
+		('submitter', 'transactions:analyze'),
+		('submitter', 'transactions:read'),
+		('submitter', 'stats:read'),
+		('submitter', 'purification:read'),
+		('submitter', 'purification:record'),
+		('submitter', 'zakat:calculate'),
+		('submitter', 'screening:read'),
+		('submitter', 'approvals:read'),
+		('reviewer', 'transactions:read'),
+		('reviewer', 'stats:read'),
+		('reviewer', 'purification:read'),
+		('reviewer', 'screening:read'),
+		('reviewer', 'reviews:read'),
+		('reviewer', 'reviews:decide'),
+		('reviewer', 'approvals:read'),
+		('reviewer', 'approvals:decide'),
+		('auditor', 'transactions:read'),
+		('auditor', 'reports:export'),
+		('auditor', 'stats:read'),
+		('auditor', 'purification:read'),
+		('auditor', 'zakat:calculate'),
+		('auditor', 'screening:read'),
+		('auditor', 'reviews:read'),
+		('auditor', 'approvals:read'),
+		('auditor', 'usage:read'),
+		('auditor', 'calibration:read'),
+		('dps', 'transactions:read'),
+		('dps', 'reports:export'),
+		('dps', 'stats:read'),
+		('dps', 'screening:read'),
+		('dps', 'reviews:read'),
+		('dps', 'reviews:decide'),
+		('dps', 'approvals:read'),
+		('dps', 'approvals:decide'),
+		('dps', 'calibration:read')
+	) AS grants (role, permission)
+	JOIN new_roles ON new_roles.name = grants.role
+	ON CONFLICT DO NOTHING;
+
+	-- Grants an organization's admin edited for its own members; a row replaces the
+	-- role's default grants in role_permissions for that organization only
+	CREATE TABLE IF NOT EXISTS organization_role_permissions (
+		organization_id VARCHAR(100) NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+		role VARCHAR(50) NOT NULL REFERENCES roles(name) ON DELETE CASCADE,
+		permissions TEXT[] NOT NULL,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (organization_id, role)
+	);
+
+	-- The admin role always holds every permission so it cannot be locked out
+	INSERT INTO role_permissions (role, permission)
+	SELECT 'admin', name FROM permissions
+	ON CONFLICT DO NOTHING;
+
+	CREATE TABLE IF NOT EXISTS issuer_ratios (
+		organization_id VARCHAR(100) NOT NULL DEFAULT 'default' REFERENCES organizations(id) ON DELETE CASCADE,
+		ticker VARCHAR(20) NOT NULL,
+		name TEXT NOT NULL,
+		period VARCHAR(50) NOT NULL,
+		total_assets DECIMAL(20, 2) NOT NULL,
+		interest_bearing_debt DECIMAL(20, 2) NOT NULL,
+		total_revenue DECIMAL(20, 2) NOT NULL,
+		non_halal_income DECIMAL(20, 2) NOT NULL,
+		cash_and_interest_securities DECIMAL(20, 2) NOT NULL,
+		market_cap DECIMAL(20, 2) NOT NULL,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (organization_id, ticker)
+	);
+
+	-- Issuer ratios were global before they were scoped by organization; existing
+	-- ratios move to the default organization
+	DO $$
+	BEGIN
+		IF (SELECT COUNT(*) FROM information_schema.key_column_usage
+			WHERE table_name = 'issuer_ratios' AND constraint_name = 'issuer_ratios_pkey') = 1 THEN
+			ALTER TABLE issuer_ratios ADD COLUMN organization_id VARCHAR(100) NOT NULL DEFAULT 'default' REFERENCES organizations(id) ON DELETE CASCADE;
+			ALTER TABLE issuer_ratios DROP CONSTRAINT issuer_ratios_pkey;
+			ALTER TABLE issuer_ratios ADD PRIMARY KEY (organization_id, ticker);
+		END IF;
+	END $$;
+
+	CREATE TABLE IF NOT EXISTS screening_methodologies (
+		organization_id VARCHAR(100) NOT NULL DEFAULT 'default' REFERENCES organizations(id) ON DELETE CASCADE,
+		name VARCHAR(50) NOT NULL,
+		debt_denominator VARCHAR(20) NOT NULL,
+		max_debt_ratio DECIMAL(5, 4) NOT NULL,
+		max_non_halal_income_ratio DECIMAL(5, 4) NOT NULL,
+		max_cash_securities_ratio DECIMAL(5, 4) NOT NULL,
+		PRIMARY KEY (organization_id, name)
+	);
+
+	-- Methodologies were global before they were scoped by organization; existing
+	-- thresholds move to the default organization
+	DO $$
+	BEGIN
+		IF (SELECT COUNT(*) FROM information_schema.key_column_usage
+			WHERE table_name = 'screening_methodologies' AND constraint_name = 'screening_methodologies_pkey') = 1 THEN
+			ALTER TABLE screening_methodologies ADD COLUMN organization_id VARCHAR(100) NOT NULL DEFAULT 'default' REFERENCES organizations(id) ON DELETE CASCADE;
+			ALTER TABLE screening_methodologies DROP CONSTRAINT screening_methodologies_pkey;
+			ALTER TABLE screening_methodologies ADD PRIMARY KEY (organization_id, name);
+		END IF;
+	END $$;
+
+	-- Every organization starts with the standard methodologies; organizations
+	-- created later copy the default organization's (see SaveOrganization)
+	INSERT INTO screening_methodologies (organization_id, name, debt_denominator, max_debt_ratio, max_non_halal_income_ratio, max_cash_securities_ratio)
+	SELECT o.id, m.name, m.debt_denominator, m.max_debt_ratio, m.max_non_halal_income_ratio, m.max_cash_securities_ratio
+	FROM organizations o CROSS JOIN (VALUES
+		('AAOIFI', 'marketCap', 0.30, 0.05, 0.30),
+		('OJK', 'totalAssets', 0.45, 0.10, 0),
+		('DJIM', 'marketCap', 0.33, 0.05, 0.33)
+	) AS m (name, debt_denominator, max_debt_ratio, max_non_halal_income_ratio, max_cash_securities_ratio)
+	ON CONFLICT (organization_id, name) DO NOTHING;
+
+	-- Donations keep their transaction reference for the audit trail, so the
+	-- transaction cannot be deleted while donations point at it
+	CREATE TABLE IF NOT EXISTS purification_donations (
+		id SERIAL PRIMARY KEY,
+		organization_id VARCHAR(100) NOT NULL DEFAULT 'default',
+		transaction_id VARCHAR(255),
+		amount DECIMAL(15, 2) NOT NULL,
+		date VARCHAR(50) NOT NULL,
+		recipient TEXT NOT NULL,
+		note TEXT,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (organization_id, transaction_id) REFERENCES transactions(organization_id, id)
+	);
+
+	CREATE TABLE IF NOT EXISTS zakat_gold_prices (
+		id SERIAL PRIMARY KEY,
+		organization_id VARCHAR(100) NOT NULL DEFAULT 'default' REFERENCES organizations(id) ON DELETE CASCADE,
+		price_per_gram DECIMAL(15, 2) NOT NULL,
+		effective_date VARCHAR(50) NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	-- Gold prices entered before they were scoped by organization belong to the default organization
+	ALTER TABLE zakat_gold_prices ADD COLUMN IF NOT EXISTS organization_id VARCHAR(100) NOT NULL DEFAULT 'default' REFERENCES organizations(id) ON DELETE CASCADE;
+
+
+	INSERT INTO approval_chains (organization_id, name, min_amount, steps)
+	VALUES ('default', 'high-value', 100000000, ARRAY['reviewer', 'reviewer', 'dps'])
+	ON CONFLICT (organization_id, name) DO NOTHING;
+
 	CREATE INDEX IF NOT EXISTS idx_transactions_date ON transactions(date);
+	CREATE INDEX IF NOT EXISTS idx_transactions_type ON transactions(type);
 	CREATE INDEX IF NOT EXISTS idx_analysis_status ON analysis_results(status);
 	CREATE INDEX IF NOT EXISTS idx_analysis_violation ON analysis_results(violation_type);
+	CREATE INDEX IF NOT EXISTS idx_reviews_state ON reviews(state);
+	CREATE INDEX IF NOT EXISTS idx_purification_donations_tx ON purification_donations(organization_id, transaction_id);
+	CREATE INDEX IF NOT EXISTS idx_api_keys_organization ON api_keys(organization_id);
+	DROP INDEX IF EXISTS idx_zakat_gold_prices_date;
+	CREATE INDEX IF NOT EXISTS idx_zakat_gold_prices_organization_date ON zakat_gold_prices(organization_id, effective_date);
+	CREATE INDEX IF NOT EXISTS idx_llm_usage_organization_created ON llm_usage(organization_id, created_at);
+	CREATE INDEX IF NOT EXISTS idx_llm_usage_transactions ON llm_usage USING GIN (transaction_ids);
+	CREATE INDEX IF NOT EXISTS idx_analysis_jobs_state_created ON analysis_jobs(state, created_at);
 	`
 
 	_, err := DB.Exec(schema)
@@ -91,7 +566,38 @@ func createTables() error {
 		return err
 	}
 
-	log.Println("✅ Database tables created/verified")
+	slog.Info("database tables created/verified")
+	return nil
+}
+
+// tenantTables lists the tables holding per-organization rows
+var tenantTables = []string{
+	"transactions", "analysis_results", "reviews", "approval_chains",
+	"approvals", "approval_steps", "purification_donations", "usage_counters", "llm_usage",
+	"analysis_jobs", "analysis_texts", "calibration_labels", "calibration_models",
+	"organization_role_permissions", "issuer_ratios", "screening_methodologies", "zakat_gold_prices",
+}
+
+// enableRowLevelSecurity restricts tenant tables to the organization named in the
+// app.organization_id setting. Policies bind every role except the table owner, so
+// reporting or support roles only see the tenant they SET app.organization_id to.
+// They are deliberately not forced: the backend owns the tables and never sets
+// app.organization_id, so its tenant isolation rests on scoping its own queries.
+func enableRowLevelSecurity() error {
+	for _, table := range tenantTables {
+		statements := fmt.Sprintf(`
+			ALTER TABLE %[1]s ENABLE ROW LEVEL SECURITY;
+			DROP POLICY IF EXISTS tenant_isolation ON %[1]s;
+			CREATE POLICY tenant_isolation ON %[1]s
+				USING (organization_id = current_setting('app.organization_id', true))
+				WITH CHECK (organization_id = current_setting('app.organization_id', true));
+		`, table)
+		if _, err := DB.Exec(statements); err != nil {
+			return fmt.Errorf("failed to enable row-level security on %s: %w", table, err)
+		}
+	}
+
+	slog.Info("row-level security policies enabled")
 	return nil
 }
 
@@ -99,6 +605,6 @@ func createTables() error {
 func Close() {
 	if DB != nil {
 		DB.Close()
-		log.Println("Database connection closed")
+		slog.Info("database connection closed")
 	}
 }
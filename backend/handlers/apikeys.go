@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"halalguard-backend/middleware"
+	"halalguard-backend/models"
+	"halalguard-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetAPIKeys lists issued API keys without their secrets
+func (h *Handler) GetAPIKeys(c *gin.Context) {
+	keys, err := services.GetAPIKeys(middleware.TenantFrom(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to retrieve API keys",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, keys)
+}
+
+// CreateAPIKey issues a new API key; the plaintext key is only returned in this response
+func (h *Handler) CreateAPIKey(c *gin.Context) {
+	var req models.CreateAPIKeyRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	key, err := services.CreateAPIKey(middleware.TenantFrom(c), req.Name, req.Role)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrRoleNotFound) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, models.ErrorResponse{
+			Error:   "Failed to create API key",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, key)
+}
+
+// RevokeAPIKey revokes an API key
+func (h *Handler) RevokeAPIKey(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid request",
+			Message: "id must be numeric",
+		})
+		return
+	}
+
+	if err := services.RevokeAPIKey(middleware.TenantFrom(c), id); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrAPIKeyNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, models.ErrorResponse{
+			Error:   "Failed to revoke API key",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
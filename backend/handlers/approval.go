@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"halalguard-backend/middleware"
+	"halalguard-backend/models"
+	"halalguard-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetApprovalChains lists the configured approval chains
+func (h *Handler) GetApprovalChains(c *gin.Context) {
+	chains, err := services.GetApprovalChains(middleware.TenantFrom(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to retrieve approval chains",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, chains)
+}
+
+// SaveApprovalChain creates or updates an approval chain
+func (h *Handler) SaveApprovalChain(c *gin.Context) {
+	var chain models.ApprovalChain
+
+	if err := c.ShouldBindJSON(&chain); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	saved, err := services.SaveApprovalChain(middleware.TenantFrom(c), chain)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to save approval chain",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, saved)
+}
+
+// GetApprovals lists transactions in or requiring an approval chain
+func (h *Handler) GetApprovals(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 1 || limit > 500 {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid request",
+			Message: "limit must be between 1 and 500",
+		})
+		return
+	}
+
+	statuses, err := services.GetApprovalStatuses(middleware.TenantFrom(c), c.Query("state"), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to retrieve approvals",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, statuses)
+}
+
+// GetApprovalStatus shows where a transaction sits in its approval chain
+func (h *Handler) GetApprovalStatus(c *gin.Context) {
+	status, err := services.GetApprovalStatus(middleware.TenantFrom(c), c.Param("id"))
+	if err != nil {
+		c.JSON(approvalErrorStatus(err), models.ErrorResponse{
+			Error:   "Failed to retrieve approval status",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// DecideApproval approves or rejects the current step of a transaction's approval chain
+func (h *Handler) DecideApproval(c *gin.Context) {
+	var req models.ApprovalRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	// Signing off a step requires holding that step's role; admins may act for any role
+	principal := middleware.PrincipalFrom(c)
+	if principal == nil || (principal.Role != req.Role && principal.Role != models.RoleAdmin) {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{
+			Error:   "Forbidden",
+			Message: "approval role must match the caller's role",
+		})
+		return
+	}
+
+	status, err := services.DecideApproval(principal.OrganizationID, c.Param("id"), principal.Subject, req)
+	if err != nil {
+		c.JSON(approvalErrorStatus(err), models.ErrorResponse{
+			Error:   "Failed to record approval",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+func approvalErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrTransactionNotFound), errors.Is(err, services.ErrAnalysisNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrApprovalWrongRole), errors.Is(err, services.ErrDuplicateApprover):
+		return http.StatusForbidden
+	case errors.Is(err, services.ErrApprovalNotRequired), errors.Is(err, services.ErrApprovalClosed),
+		errors.Is(err, services.ErrApprovalStepDecided):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
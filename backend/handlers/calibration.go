@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"strings"
+
+	"halalguard-backend/middleware"
+	"halalguard-backend/models"
+	"halalguard-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ImportCalibrationLabels stores confirmed verdicts from an uploaded CSV file
+// (multipart field "file") or a raw text/csv request body
+func (h *Handler) ImportCalibrationLabels(c *gin.Context) {
+	reader, err := uploadedCSV(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid request",
+			Message: err.Error(),
+		})
+		return
+	}
+	defer reader.Close()
+
+	labels, err := services.ParseCalibrationLabelsCSV(reader)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Failed to import labels",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	count, err := services.ImportCalibrationLabels(c.Request.Context(), middleware.TenantFrom(c), labels)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to import labels",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"imported": count})
+}
+
+// FitCalibration refits the organization's confidence calibration from its labels
+func (h *Handler) FitCalibration(c *gin.Context) {
+	report, err := services.FitCalibration(c.Request.Context(), middleware.TenantFrom(c), h.cfg.Calibration)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to fit calibration",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// GetCalibration reports the calibration curves and Brier scores per violation type
+func (h *Handler) GetCalibration(c *gin.Context) {
+	report, err := services.GetCalibrationReport(c.Request.Context(), middleware.TenantFrom(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to retrieve calibration",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// uploadedCSV opens the CSV file of multipart field "file", or the request body
+// when the request is not multipart
+func uploadedCSV(c *gin.Context) (io.ReadCloser, error) {
+	if !strings.HasPrefix(c.ContentType(), "multipart/") {
+		return c.Request.Body, nil
+	}
+	file, err := c.FormFile("file")
+	if err != nil {
+		return nil, err
+	}
+	return file.Open()
+}
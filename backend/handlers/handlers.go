@@ -1,9 +1,15 @@
 package handlers
 
 import (
-	"log"
+	"errors"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
 
+	"halalguard-backend/config"
+	"halalguard-backend/middleware"
 	"halalguard-backend/models"
 	"halalguard-backend/services"
 
@@ -11,16 +17,29 @@ import (
 )
 
 type Handler struct {
-	geminiService *services.GeminiService
+	cfg       *config.Config
+	analyzer  services.Analyzer
+	pipeline  *services.AnalysisPipeline
+	startedAt time.Time
+	// draining is set on shutdown so the readiness probe takes the instance out of rotation
+	draining atomic.Bool
 }
 
 // NewHandler creates a new handler
-func NewHandler(geminiService *services.GeminiService) *Handler {
+func NewHandler(cfg *config.Config, analyzer services.Analyzer, pipeline *services.AnalysisPipeline) *Handler {
 	return &Handler{
-		geminiService: geminiService,
+		cfg:       cfg,
+		analyzer:  analyzer,
+		pipeline:  pipeline,
+		startedAt: time.Now(),
 	}
 }
 
+// SetDraining makes the readiness probe fail while the server shuts down
+func (h *Handler) SetDraining() {
+	h.draining.Store(true)
+}
+
 // AnalyzeTransactions handles transaction analysis requests
 func (h *Handler) AnalyzeTransactions(c *gin.Context) {
 	var req models.AnalyzeRequest
@@ -33,39 +52,110 @@ func (h *Handler) AnalyzeTransactions(c *gin.Context) {
 		return
 	}
 
-	// Save transactions to database
-	for _, tx := range req.Transactions {
-		if err := services.SaveTransaction(tx); err != nil {
-			log.Printf("Warning: Failed to save transaction %s: %v", tx.ID, err)
-		}
+	if !authorizeGenerationOverride(c, req) {
+		return
+	}
+	if req.Language == "" {
+		req.Language = acceptedLanguage(c.GetHeader("Accept-Language"))
 	}
 
-	// Analyze transactions using Gemini AI
-	results, err := h.geminiService.AnalyzeTransactions(req.Transactions)
+	response, err := h.pipeline.Run(c.Request.Context(), middleware.TenantFrom(c), req)
 	if err != nil {
-		log.Printf("Analysis failed: %v", err)
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error:   "Analysis failed",
+		var quotaErr *services.QuotaExceededError
+		if errors.As(err, &quotaErr) {
+			setQuotaHeaders(c, quotaErr)
+		}
+		status, title := analysisErrorStatus(err)
+		c.JSON(status, models.ErrorResponse{
+			Error:   title,
 			Message: err.Error(),
 		})
 		return
 	}
 
-	// Save analysis results to database
-	for _, result := range results {
-		if err := services.SaveAnalysisResult(result); err != nil {
-			log.Printf("Warning: Failed to save analysis result for %s: %v", result.TransactionID, err)
+	c.JSON(http.StatusOK, response)
+}
+
+// acceptedLanguage returns the supported analysis language preferred by an
+// Accept-Language header, or "" so the organization's language applies
+func acceptedLanguage(header string) string {
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		primary, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+		if !services.IsSupportedLanguage(primary) {
+			continue
+		}
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q > bestQ {
+			best, bestQ = primary, q
 		}
 	}
+	return best
+}
 
-	c.JSON(http.StatusOK, models.AnalyzeResponse{
-		Results: results,
-	})
+// authorizeGenerationOverride responds with 403 and returns false when the request
+// overrides generation settings without the analysis:override permission
+func authorizeGenerationOverride(c *gin.Context, req models.AnalyzeRequest) bool {
+	if req.Generation == nil {
+		return true
+	}
+
+	return authorize(c, models.PermAnalysisOverride, "overriding generation settings")
+}
+
+// authorize checks a permission beyond the one required by the route. It responds
+// with 403 (or 500 when the lookup fails) and returns false when the caller's role
+// lacks the permission.
+func authorize(c *gin.Context, permission, action string) bool {
+	principal := middleware.PrincipalFrom(c)
+	granted := false
+	if principal != nil && principal.Role != "" {
+		var err error
+		if granted, err = services.HasPermission(principal.OrganizationID, principal.Role, permission); err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "Failed to check permissions",
+				Message: err.Error(),
+			})
+			return false
+		}
+	}
+	if !granted {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{
+			Error:   "Forbidden",
+			Message: action + " requires permission " + permission,
+		})
+		return false
+	}
+	return true
+}
+
+// analysisErrorStatus maps an analysis pipeline error to an HTTP status and error title
+func analysisErrorStatus(err error) (int, string) {
+	var quotaErr *services.QuotaExceededError
+	switch {
+	case errors.Is(err, services.ErrOrganizationNotFound):
+		return http.StatusForbidden, "Failed to load organization"
+	case errors.Is(err, services.ErrTransactionLocked):
+		return http.StatusConflict, "Transactions locked"
+	case errors.As(err, &quotaErr):
+		return http.StatusTooManyRequests, "Quota exceeded"
+	case errors.Is(err, services.ErrCircuitOpen):
+		return http.StatusServiceUnavailable, "Analysis failed"
+	}
+	return http.StatusInternalServerError, "Analysis failed"
 }
 
 // GetAllTransactions retrieves all transactions with analysis
 func (h *Handler) GetAllTransactions(c *gin.Context) {
-	results, err := services.GetAllTransactions()
+	results, err := services.GetAllTransactions(middleware.TenantFrom(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
 			Error:   "Failed to retrieve transactions",
@@ -81,7 +171,7 @@ func (h *Handler) GetAllTransactions(c *gin.Context) {
 func (h *Handler) GetTransactionByID(c *gin.Context) {
 	id := c.Param("id")
 
-	result, err := services.GetTransactionByID(id)
+	result, err := services.GetTransactionByID(middleware.TenantFrom(c), id)
 	if err != nil {
 		c.JSON(http.StatusNotFound, models.ErrorResponse{
 			Error:   "Transaction not found",
@@ -92,12 +182,3 @@ func (h *Handler) GetTransactionByID(c *gin.Context) {
 
 	c.JSON(http.StatusOK, result)
 }
-
-// HealthCheck handles health check requests
-func (h *Handler) HealthCheck(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
-		"status":  "healthy",
-		"service": "HalalGuard AI Backend",
-		"version": "1.0.0",
-	})
-}
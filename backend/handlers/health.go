@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"halalguard-backend/database"
+	"halalguard-backend/metrics"
+	"halalguard-backend/middleware"
+	"halalguard-backend/models"
+	"halalguard-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Version is the backend version reported by the health endpoints,
+// set at build time with -ldflags "-X halalguard-backend/handlers.Version=..."
+var Version = "1.0.0"
+
+const serviceName = "HalalGuard AI Backend"
+
+// seriesInterval is the bucket width of the latency series in /system/status
+const seriesInterval = time.Minute
+
+// HealthCheck handles health check requests; it reports readiness for older clients
+func (h *Handler) HealthCheck(c *gin.Context) {
+	h.Ready(c)
+}
+
+// Live reports that the process is running and able to serve requests
+func (h *Handler) Live(c *gin.Context) {
+	c.JSON(http.StatusOK, models.HealthResponse{
+		Status:  "alive",
+		Service: serviceName,
+		Version: Version,
+	})
+}
+
+// Ready reports whether the backend can serve analyses: it is not shutting down,
+// the database answers a ping within the timeout and the model provider's circuit breaker is not open
+func (h *Handler) Ready(c *gin.Context) {
+	checks, ready := h.checkDependencies(c.Request.Context())
+
+	response := models.HealthResponse{
+		Status:  "ready",
+		Service: serviceName,
+		Version: Version,
+		Checks:  checks,
+	}
+	status := http.StatusOK
+	if !ready {
+		response.Status = "not_ready"
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, response)
+}
+
+// GetSystemStatus returns recent latency percentiles, error rates, the model provider's
+// circuit state and the caller's last analysis timestamps
+func (h *Handler) GetSystemStatus(c *gin.Context) {
+	checks, ready := h.checkDependencies(c.Request.Context())
+
+	activity, err := services.GetAnalysisActivity(middleware.TenantFrom(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to retrieve system status",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	status := models.SystemStatus{
+		Status:        "ready",
+		Version:       Version,
+		StartedAt:     h.startedAt,
+		UptimeSeconds: int64(time.Since(h.startedAt).Seconds()),
+		WindowMinutes: int(metrics.RecentHTTP.Span().Minutes()),
+		Checks:        checks,
+		HTTP:          metrics.RecentHTTP.Summary(),
+		LLM:           h.analyzer.Status(),
+		Analysis:      *activity,
+		Series:        metrics.RecentHTTP.Series(seriesInterval),
+	}
+	if !ready {
+		status.Status = "not_ready"
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// checkDependencies pings the database and inspects the model provider's circuit
+// breaker, reported under the provider's name (e.g. "gemini").
+// A draining instance is never ready.
+func (h *Handler) checkDependencies(ctx context.Context) (map[string]models.HealthCheck, bool) {
+	ready := true
+	checks := make(map[string]models.HealthCheck)
+
+	if h.draining.Load() {
+		checks["shutdown"] = models.HealthCheck{Status: models.HealthDown, Error: "draining"}
+		ready = false
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, h.cfg.Health.DBTimeout)
+	defer cancel()
+	start := time.Now()
+	dbCheck := models.HealthCheck{Status: models.HealthOK}
+	if err := database.DB.PingContext(ctx); err != nil {
+		dbCheck.Status = models.HealthDown
+		dbCheck.Error = err.Error()
+		ready = false
+	}
+	dbCheck.LatencyMs = float64(time.Since(start).Microseconds()) / 1000
+	checks["database"] = dbCheck
+
+	llm := h.analyzer.Status()
+	llmCheck := models.HealthCheck{Status: models.HealthOK}
+	switch llm.Circuit {
+	case services.CircuitOpen:
+		llmCheck.Status = models.HealthDown
+		llmCheck.Error = llm.LastError
+		ready = false
+	case services.CircuitHalfOpen:
+		llmCheck.Status = models.HealthDegraded
+		llmCheck.Error = llm.LastError
+	}
+	checks[llm.Provider] = llmCheck
+
+	return checks, ready
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"halalguard-backend/logging"
+	"halalguard-backend/middleware"
+	"halalguard-backend/models"
+	"halalguard-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// EnqueueAnalysis queues transactions for background analysis and returns the job
+func (h *Handler) EnqueueAnalysis(c *gin.Context) {
+	var req models.AnalyzeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if !authorizeGenerationOverride(c, req) {
+		return
+	}
+	if req.Language == "" {
+		req.Language = acceptedLanguage(c.GetHeader("Accept-Language"))
+	}
+
+	ctx := c.Request.Context()
+	job, err := services.EnqueueAnalysisJob(ctx, middleware.TenantFrom(c), logging.RequestIDFrom(ctx), req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to queue analysis",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.Header("Location", fmt.Sprintf("/api/analyze/jobs/%d", job.ID))
+	c.JSON(http.StatusAccepted, job)
+}
+
+// GetAnalysisJob returns the state of a queued analysis and its results once finished
+func (h *Handler) GetAnalysisJob(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid request",
+			Message: "id must be numeric",
+		})
+		return
+	}
+
+	job, err := services.GetAnalysisJob(middleware.TenantFrom(c), id)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrJobNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, models.ErrorResponse{
+			Error:   "Failed to retrieve analysis job",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"halalguard-backend/middleware"
+	"halalguard-backend/models"
+	"halalguard-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetOrganization returns the caller's organization and its analysis settings
+func (h *Handler) GetOrganization(c *gin.Context) {
+	org, err := services.GetOrganization(middleware.TenantFrom(c))
+	if err != nil {
+		c.JSON(organizationErrorStatus(err), models.ErrorResponse{
+			Error:   "Failed to retrieve organization",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, org)
+}
+
+// UpdateOrganizationSettings changes the model, language and rule packs of the caller's organization
+func (h *Handler) UpdateOrganizationSettings(c *gin.Context) {
+	var settings models.TenantSettings
+
+	if err := c.ShouldBindJSON(&settings); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	org, err := services.UpdateTenantSettings(middleware.TenantFrom(c), settings)
+	if err != nil {
+		c.JSON(organizationErrorStatus(err), models.ErrorResponse{
+			Error:   "Failed to update organization",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, org)
+}
+
+func organizationErrorStatus(err error) int {
+	if errors.Is(err, services.ErrOrganizationNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
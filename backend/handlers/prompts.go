@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"net/http"
+
+	"halalguard-backend/middleware"
+	"halalguard-backend/models"
+	"halalguard-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetPromptTemplates lists the loaded prompt templates with their versions
+func (h *Handler) GetPromptTemplates(c *gin.Context) {
+	c.JSON(http.StatusOK, h.analyzer.Templates())
+}
+
+// PreviewPrompt renders the prompt for sample transactions without calling the model.
+// Language defaults to the Accept-Language header, then the organization's language;
+// rule packs default to the organization's.
+func (h *Handler) PreviewPrompt(c *gin.Context) {
+	var req models.PromptPreviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	org, err := services.GetOrganization(middleware.TenantFrom(c))
+	if err != nil {
+		c.JSON(organizationErrorStatus(err), models.ErrorResponse{
+			Error:   "Failed to load organization",
+			Message: err.Error(),
+		})
+		return
+	}
+	settings := org.TenantSettings
+	if req.Language == "" {
+		req.Language = acceptedLanguage(c.GetHeader("Accept-Language"))
+	}
+	if req.Language != "" {
+		settings.Language = req.Language
+	}
+	if req.RulePacks != nil {
+		settings.RulePacks = req.RulePacks
+	}
+
+	preview, err := h.analyzer.PreviewPrompt(req.Transactions, settings)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to render prompt",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, preview)
+}
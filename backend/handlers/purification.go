@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"halalguard-backend/middleware"
+	"halalguard-backend/models"
+	"halalguard-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetPurification returns purification obligations and outstanding balances
+func (h *Handler) GetPurification(c *gin.Context) {
+	from, to, err := parseOptionalDateRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	summary, err := services.GetPurificationSummary(middleware.TenantFrom(c), h.cfg.Purification, from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to compute purification",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
+
+// GetDonations lists recorded purification donations
+func (h *Handler) GetDonations(c *gin.Context) {
+	donations, err := services.GetDonations(middleware.TenantFrom(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to retrieve donations",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, donations)
+}
+
+// RecordDonation records a charity donation against purification obligations
+func (h *Handler) RecordDonation(c *gin.Context) {
+	var input models.DonationInput
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if _, err := time.Parse(dateLayout, input.Date); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid request",
+			Message: "date must use the YYYY-MM-DD format",
+		})
+		return
+	}
+
+	if input.TransactionID != "" {
+		if _, err := services.GetTransactionByID(middleware.TenantFrom(c), input.TransactionID); err != nil {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{
+				Error:   "Transaction not found",
+				Message: err.Error(),
+			})
+			return
+		}
+	}
+
+	donation, err := services.SaveDonation(middleware.TenantFrom(c), input)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to record donation",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, donation)
+}
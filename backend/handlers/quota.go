@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"halalguard-backend/middleware"
+	"halalguard-backend/models"
+	"halalguard-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetQuota returns the caller's organization quota limits and current usage
+func (h *Handler) GetQuota(c *gin.Context) {
+	status, err := services.GetQuotaStatus(middleware.TenantFrom(c))
+	if err != nil {
+		c.JSON(organizationErrorStatus(err), models.ErrorResponse{
+			Error:   "Failed to retrieve quota",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// setQuotaHeaders describes an exhausted tenant quota on a 429 response
+func setQuotaHeaders(c *gin.Context, quotaErr *services.QuotaExceededError) {
+	retryAfter := int(math.Ceil(time.Until(quotaErr.ResetAt).Seconds()))
+	c.Header("X-Quota-Scope", quotaErr.Scope)
+	c.Header("X-Quota-Limit", strconv.FormatInt(quotaErr.Limit, 10))
+	c.Header("X-Quota-Remaining", strconv.FormatInt(max(0, quotaErr.Limit-quotaErr.Used), 10))
+	c.Header("X-Quota-Reset", strconv.FormatInt(quotaErr.ResetAt.Unix(), 10))
+	c.Header("Retry-After", strconv.Itoa(max(1, retryAfter)))
+}
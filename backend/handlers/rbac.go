@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"halalguard-backend/middleware"
+	"halalguard-backend/models"
+	"halalguard-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetRoles lists the roles with the permissions each grants in the caller's organization
+func (h *Handler) GetRoles(c *gin.Context) {
+	roles, err := services.GetRoles(middleware.TenantFrom(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to retrieve roles",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, roles)
+}
+
+// GetPermissions lists every permission that can be granted to a role
+func (h *Handler) GetPermissions(c *gin.Context) {
+	permissions, err := services.GetPermissions()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to retrieve permissions",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, permissions)
+}
+
+// SetRolePermissions replaces the permissions a role grants in the caller's organization
+func (h *Handler) SetRolePermissions(c *gin.Context) {
+	var req models.RolePermissionsRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	role, err := services.SetRolePermissions(middleware.TenantFrom(c), c.Param("name"), req.Permissions)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, services.ErrRoleNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, services.ErrUnknownPermission):
+			status = http.StatusBadRequest
+		case errors.Is(err, services.ErrAdminRoleImmutable):
+			status = http.StatusConflict
+		}
+		c.JSON(status, models.ErrorResponse{
+			Error:   "Failed to update role",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, role)
+}
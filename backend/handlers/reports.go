@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"halalguard-backend/middleware"
+	"halalguard-backend/models"
+	"halalguard-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+const dateLayout = "2006-01-02"
+
+// GetComplianceReportPDF generates the Sharia compliance audit report for a date range
+func (h *Handler) GetComplianceReportPDF(c *gin.Context) {
+	from, to, err := parseDateRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	report, err := services.GetComplianceReport(middleware.TenantFrom(c), from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to build report",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	pdf, err := services.RenderComplianceReportPDF(report)
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "report rendering failed", "error", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to render report",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	filename := fmt.Sprintf("laporan-kepatuhan-syariah_%s_%s.pdf", from, to)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Data(http.StatusOK, "application/pdf", pdf)
+}
+
+// parseDateRange reads the from/to query parameters (YYYY-MM-DD).
+// Defaults to the first day of the current month up to today.
+func parseDateRange(c *gin.Context) (string, string, error) {
+	now := time.Now()
+	from := c.DefaultQuery("from", time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location()).Format(dateLayout))
+	to := c.DefaultQuery("to", now.Format(dateLayout))
+
+	fromDate, err := time.Parse(dateLayout, from)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid from date %q, expected YYYY-MM-DD", from)
+	}
+	toDate, err := time.Parse(dateLayout, to)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid to date %q, expected YYYY-MM-DD", to)
+	}
+	if toDate.Before(fromDate) {
+		return "", "", fmt.Errorf("to date must not be before from date")
+	}
+
+	return from, to, nil
+}
+
+// parseOptionalDateRange reads the optional from/to query parameters (YYYY-MM-DD).
+// Empty values leave that side of the range open.
+func parseOptionalDateRange(c *gin.Context) (string, string, error) {
+	from, to := c.Query("from"), c.Query("to")
+	for name, value := range map[string]string{"from": from, "to": to} {
+		if value == "" {
+			continue
+		}
+		if _, err := time.Parse(dateLayout, value); err != nil {
+			return "", "", fmt.Errorf("invalid %s date %q, expected YYYY-MM-DD", name, value)
+		}
+	}
+	return from, to, nil
+}
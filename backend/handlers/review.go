@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"halalguard-backend/middleware"
+	"halalguard-backend/models"
+	"halalguard-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetReviewQueue lists analyses pending human review ordered by priority
+func (h *Handler) GetReviewQueue(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 500 {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid request",
+			Message: "limit must be between 1 and 500",
+		})
+		return
+	}
+
+	queue, err := services.GetReviewQueue(middleware.TenantFrom(c), h.cfg.ReviewConfidenceThreshold, c.Query("reviewer"), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to retrieve review queue",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, queue)
+}
+
+// AssignReview assigns a queued analysis to the calling reviewer
+func (h *Handler) AssignReview(c *gin.Context) {
+	principal := middleware.PrincipalFrom(c)
+	result, err := services.AssignReview(principal.OrganizationID, c.Param("id"), principal.Subject)
+	if err != nil {
+		c.JSON(reviewErrorStatus(err), models.ErrorResponse{
+			Error:   "Failed to assign review",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// DecideReview records the Sharia reviewer's final status and violation type
+func (h *Handler) DecideReview(c *gin.Context) {
+	var req models.ReviewDecisionRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if !models.IsValidStatus(req.Status) || !models.IsValidViolationType(req.ViolationType) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid request",
+			Message: "unknown status or violationType",
+		})
+		return
+	}
+
+	principal := middleware.PrincipalFrom(c)
+	result, err := services.DecideReview(principal.OrganizationID, c.Param("id"), principal.Subject, req)
+	if err != nil {
+		c.JSON(reviewErrorStatus(err), models.ErrorResponse{
+			Error:   "Failed to record decision",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+func reviewErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrTransactionNotFound), errors.Is(err, services.ErrAnalysisNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrReviewDecided), errors.Is(err, services.ErrReviewAssignedElsewhere):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
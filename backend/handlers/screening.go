@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"halalguard-backend/middleware"
+	"halalguard-backend/models"
+	"halalguard-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ImportIssuers loads issuer financial ratios from an uploaded CSV file
+// (multipart field "file") or from a raw text/csv request body
+func (h *Handler) ImportIssuers(c *gin.Context) {
+	var reader io.Reader = c.Request.Body
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		file, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "Invalid request",
+				Message: err.Error(),
+			})
+			return
+		}
+		f, err := file.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "Invalid request",
+				Message: err.Error(),
+			})
+			return
+		}
+		defer f.Close()
+		reader = f
+	}
+
+	count, err := services.ImportIssuerRatiosCSV(middleware.TenantFrom(c), reader)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Failed to import issuers",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"imported": count})
+}
+
+// GetIssuers lists the organization's issuers with stored financial ratios
+func (h *Handler) GetIssuers(c *gin.Context) {
+	issuers, err := services.GetAllIssuerRatios(middleware.TenantFrom(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to retrieve issuers",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, issuers)
+}
+
+// ScreenIssuer screens one issuer against the requested or configured methodology
+func (h *Handler) ScreenIssuer(c *gin.Context) {
+	issuer, err := services.GetIssuerRatios(middleware.TenantFrom(c), c.Param("ticker"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error:   "Issuer not found",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	methodology, err := services.GetScreeningMethodology(middleware.TenantFrom(c), c.DefaultQuery("methodology", h.cfg.ScreeningMethodology))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrMethodologyNotFound) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, models.ErrorResponse{
+			Error:   "Invalid methodology",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, services.ScreenIssuer(*issuer, *methodology))
+}
+
+// GetScreeningMethodologies lists the screening methodologies and their thresholds
+func (h *Handler) GetScreeningMethodologies(c *gin.Context) {
+	methodologies, err := services.GetScreeningMethodologies(middleware.TenantFrom(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to retrieve methodologies",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, methodologies)
+}
+
+// UpdateScreeningMethodology creates or updates the thresholds of a methodology
+func (h *Handler) UpdateScreeningMethodology(c *gin.Context) {
+	var methodology models.ScreeningMethodology
+
+	if err := c.ShouldBindJSON(&methodology); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid request",
+			Message: err.Error(),
+		})
+		return
+	}
+	methodology.Name = strings.ToUpper(c.Param("name"))
+
+	if err := services.SaveScreeningMethodology(middleware.TenantFrom(c), methodology); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to save methodology",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, methodology)
+}
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"halalguard-backend/middleware"
+	"halalguard-backend/models"
+	"halalguard-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+var statsBuckets = map[string]bool{"day": true, "week": true, "month": true}
+
+// GetStats returns server-computed aggregates for the analysis dashboard
+func (h *Handler) GetStats(c *gin.Context) {
+	bucket := c.DefaultQuery("bucket", "day")
+	from, to, err := parseOptionalDateRange(c)
+	if err == nil && !statsBuckets[bucket] {
+		err = fmt.Errorf("invalid bucket %q, expected day, week or month", bucket)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	filter := models.StatsFilter{From: from, To: to, Bucket: bucket}
+
+	stats, err := services.GetStats(middleware.TenantFrom(c), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to compute stats",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
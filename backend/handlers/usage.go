@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"net/http"
+
+	"halalguard-backend/middleware"
+	"halalguard-backend/models"
+	"halalguard-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// allOrganizations is the organization query value selecting every tenant
+const allOrganizations = "all"
+
+// GetUsage returns LLM token usage and cost aggregated by day, organization and model.
+// Callers holding usage:read_all may report on another tenant or all tenants.
+func (h *Handler) GetUsage(c *gin.Context) {
+	from, to, err := parseOptionalDateRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	orgID := middleware.TenantFrom(c)
+	if requested := c.Query("organization"); requested != "" && requested != orgID {
+		if !authorize(c, models.PermUsageReadAll, "reading other organizations' usage") {
+			return
+		}
+		orgID = requested
+		if requested == allOrganizations {
+			orgID = ""
+		}
+	}
+
+	report, err := services.GetUsageReport(models.UsageFilter{OrganizationID: orgID, From: from, To: to})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to retrieve usage",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// GetTransactionUsage lists the LLM calls that analyzed a transaction
+func (h *Handler) GetTransactionUsage(c *gin.Context) {
+	calls, err := services.GetTransactionUsage(middleware.TenantFrom(c), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to retrieve usage",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, calls)
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"halalguard-backend/middleware"
+	"halalguard-backend/models"
+	"halalguard-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CalculateZakat computes zakat maal over the submitted holdings
+func (h *Handler) CalculateZakat(c *gin.Context) {
+	var req models.ZakatRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	result, err := services.CalculateZakat(middleware.TenantFrom(c), req, h.cfg.Purification.SyubhatRatio)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrGoldPriceNotConfigured) {
+			status = http.StatusConflict
+		} else if errors.Is(err, services.ErrInvalidZakatInput) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, models.ErrorResponse{
+			Error:   "Zakat calculation failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// GetGoldPrice returns the gold price currently used for nisab
+func (h *Handler) GetGoldPrice(c *gin.Context) {
+	price, err := services.GetGoldPrice(middleware.TenantFrom(c), c.DefaultQuery("asOf", time.Now().Format(dateLayout)))
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error:   "Gold price not found",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"goldPrice":  price,
+		"nisabGrams": services.NisabGoldGrams,
+		"nisabValue": price.PricePerGram * services.NisabGoldGrams,
+	})
+}
+
+// SetGoldPrice records the gold price per gram used for nisab
+func (h *Handler) SetGoldPrice(c *gin.Context) {
+	var input models.GoldPriceInput
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if _, err := time.Parse(dateLayout, input.EffectiveDate); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid request",
+			Message: "effectiveDate must use the YYYY-MM-DD format",
+		})
+		return
+	}
+
+	price, err := services.SaveGoldPrice(middleware.TenantFrom(c), input)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to save gold price",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, price)
+}
@@ -0,0 +1,101 @@
+// Package logging configures the slog default logger and carries request IDs
+// through contexts so every log line of a request can be correlated.
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"halalguard-backend/config"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Redacted replaces sensitive attribute values unless debug logging is enabled
+const Redacted = "[REDACTED]"
+
+// sensitiveKeys are attribute keys holding financial data or model output
+var sensitiveKeys = map[string]bool{
+	"amount":      true,
+	"description": true,
+	"ai_response": true,
+	"prompt":      true,
+}
+
+type requestIDKey struct{}
+
+// WithRequestID returns a context carrying the request ID
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestIDFrom returns the request ID carried by the context, if any
+func RequestIDFrom(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// Setup installs a JSON (or text) slog logger at the configured level as the
+// default for both slog and the standard log package
+func Setup(cfg config.LogConfig) {
+	slog.SetDefault(slog.New(NewHandler(os.Stdout, cfg)))
+}
+
+// NewHandler creates the backend's log handler writing to w
+func NewHandler(w io.Writer, cfg config.LogConfig) slog.Handler {
+	level := ParseLevel(cfg.Level)
+	options := &slog.HandlerOptions{Level: level}
+	if level > slog.LevelDebug {
+		options.ReplaceAttr = redact
+	}
+
+	var handler slog.Handler
+	if strings.EqualFold(cfg.Format, "text") {
+		handler = slog.NewTextHandler(w, options)
+	} else {
+		handler = slog.NewJSONHandler(w, options)
+	}
+	return contextHandler{handler}
+}
+
+// ParseLevel maps debug, info, warn and error to slog levels, defaulting to info
+func ParseLevel(level string) slog.Level {
+	var parsed slog.Level
+	if err := parsed.UnmarshalText([]byte(level)); err != nil {
+		return slog.LevelInfo
+	}
+	return parsed
+}
+
+func redact(_ []string, attr slog.Attr) slog.Attr {
+	if sensitiveKeys[attr.Key] {
+		return slog.String(attr.Key, Redacted)
+	}
+	return attr
+}
+
+// contextHandler adds the request ID and trace ID of the context to every record
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if requestID := RequestIDFrom(ctx); requestID != "" {
+		record.AddAttrs(slog.String("request_id", requestID))
+	}
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		record.AddAttrs(slog.String("trace_id", span.TraceID().String()))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
@@ -1,83 +1,232 @@
 package main
 
 import (
-	"log"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"halalguard-backend/config"
 	"halalguard-backend/database"
 	"halalguard-backend/handlers"
+	"halalguard-backend/logging"
+	"halalguard-backend/metrics"
+	"halalguard-backend/middleware"
+	"halalguard-backend/models"
+	"halalguard-backend/prompts"
 	"halalguard-backend/services"
+	"halalguard-backend/tracing"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 func main() {
 	// Load configuration
 	cfg := config.Load()
+	logging.Setup(cfg.Log)
+	if err := cfg.Validate(); err != nil {
+		fatal("invalid configuration", "error", err)
+	}
 
-	// Validate Gemini API Key
-	if cfg.GeminiAPIKey == "" {
-		log.Fatal("❌ GEMINI_API_KEY is required. Please set it in .env file")
+	// Export traces of requests, model calls and SQL statements
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing, handlers.Version)
+	if err != nil {
+		fatal("failed to initialize tracing", "error", err)
 	}
 
 	// Connect to database
 	if err := database.Connect(cfg); err != nil {
-		log.Fatalf("❌ Failed to connect to database: %v", err)
+		fatal("failed to connect to database", "error", err)
 	}
 	defer database.Close()
 
-	// Initialize Gemini service
-	geminiService, err := services.NewGeminiService(cfg.GeminiAPIKey)
+	// Initialize the analyzer of LLM_PROVIDER with the embedded or overridden prompt templates
+	templates, err := prompts.Load(cfg.PromptTemplateDir)
+	if err != nil {
+		fatal("failed to load prompt templates", "error", err)
+	}
+	analyzer, err := services.NewAnalyzer(cfg, templates)
+	if err != nil {
+		fatal("failed to initialize analyzer", "provider", cfg.LLMProvider, "error", err)
+	}
+	defer analyzer.Close()
+
+	// Initialize authentication
+	authenticator, err := middleware.NewAuthenticator(cfg.Auth)
 	if err != nil {
-		log.Fatalf("❌ Failed to initialize Gemini service: %v", err)
+		fatal("failed to initialize authentication", "error", err)
 	}
-	defer geminiService.Close()
 
-	// Initialize handlers
-	handler := handlers.NewHandler(geminiService)
+	// Initialize handlers and background analysis workers
+	pipeline := services.NewAnalysisPipeline(cfg, analyzer)
+	handler := handlers.NewHandler(cfg, analyzer, pipeline)
+	workers := services.NewAnalysisWorkers(pipeline, cfg.Jobs)
 
 	// Setup Gin router
-	router := gin.Default()
+	router := gin.New()
+	// Without trusted proxies the client IP is the connection's remote address, so
+	// a forged X-Forwarded-For cannot dodge the per IP rate limit
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		fatal("invalid TRUSTED_PROXIES", "error", err)
+	}
+	router.Use(gin.Recovery(), middleware.Tracing(cfg.Tracing.ServiceName, probeRoutes...), middleware.RequestID(), middleware.RequestLogger())
+	router.Use(middleware.Metrics(probeRoutes...))
 
 	// CORS configuration
 	corsConfig := cors.Config{
 		AllowOrigins:     []string{cfg.CORSOrigin, "http://localhost:5173", "http://localhost:3000"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-API-Key", middleware.RequestIDHeader},
+		ExposeHeaders:    exposedHeaders,
 		AllowCredentials: true,
 	}
 	router.Use(cors.New(corsConfig))
 
+	// Prometheus metrics, scraped without authentication
+	metrics.RegisterDB(database.DB, "halalguard")
+	router.GET("/metrics", gin.WrapH(promhttp.HandlerFor(metrics.Registry, promhttp.HandlerOpts{})))
+
 	// API routes
 	api := router.Group("/api")
-	{
-		api.GET("/health", handler.HealthCheck)
-		api.POST("/analyze", handler.AnalyzeTransactions)
-		api.GET("/transactions", handler.GetAllTransactions)
-		api.GET("/transactions/:id", handler.GetTransactionByID)
+	api.GET("/health", handler.HealthCheck)
+	api.GET("/health/live", handler.Live)
+	api.GET("/health/ready", handler.Ready)
+
+	// Every other route requires an API key or JWT bearer token and the
+	// permission listed in the route table, granted through the caller's role.
+	// Client IPs are rate limited before authentication to slow credential guessing.
+	protected := api.Group("",
+		middleware.NewIPRateLimiter(cfg.RateLimit).Middleware(),
+		authenticator.Middleware(),
+		middleware.NewRateLimiter(cfg.RateLimit).Middleware())
+	for _, route := range routes(handler) {
+		protected.Handle(route.method, route.path, middleware.RequirePermission(route.permission), route.handler)
+	}
+
+	server := &http.Server{
+		Addr:              ":" + cfg.Port,
+		Handler:           router,
+		ReadHeaderTimeout: 10 * time.Second,
 	}
 
-	// Graceful shutdown
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Start server
+	slog.Info("server starting",
+		"port", cfg.Port,
+		"cors_origin", cfg.CORSOrigin,
+		"database", fmt.Sprintf("%s@%s:%s/%s", cfg.Database.User, cfg.Database.Host, cfg.Database.Port, cfg.Database.DBName),
+		"log_level", cfg.Log.Level,
+		"trace_exporter", cfg.Tracing.Exporter)
+	serverErr := make(chan error, 1)
 	go func() {
-		sigChan := make(chan os.Signal, 1)
-		signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
-		<-sigChan
-		log.Println("\n🛑 Shutting down server...")
-		os.Exit(0)
+		serverErr <- server.ListenAndServe()
 	}()
+	workers.Start()
 
-	// Start server
-	port := cfg.Port
-	log.Printf("🚀 Server starting on port %s", port)
-	log.Printf("📡 CORS enabled for: %s", cfg.CORSOrigin)
-	log.Printf("🗄️  Database: %s@%s:%s/%s", cfg.Database.User, cfg.Database.Host, cfg.Database.Port, cfg.Database.DBName)
+	select {
+	case err := <-serverErr:
+		fatal("failed to start server", "error", err)
+	case <-ctx.Done():
+	}
+
+	// Graceful shutdown: fail readiness, stop accepting connections, then let
+	// in-flight requests and jobs finish within the drain timeout. Jobs still
+	// running at the deadline are requeued. Deferred closes run on return.
+	stop()
+	slog.Info("shutting down server", "drain_timeout", cfg.Shutdown.DrainTimeout)
+	handler.SetDraining()
+	drainCtx, cancel := context.WithTimeout(context.Background(), cfg.Shutdown.DrainTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(drainCtx); err != nil {
+		slog.Error("in-flight requests did not finish before the drain timeout", "error", err)
+	}
+	if err := workers.Stop(drainCtx); err != nil {
+		slog.Error("analysis jobs interrupted and requeued", "error", err)
+	}
+	if err := shutdownTracing(context.Background()); err != nil {
+		slog.Error("failed to flush traces", "error", err)
+	}
+	slog.Info("server stopped")
+}
+
+// fatal logs an error and exits, like log.Fatal
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+// probeRoutes are scraped by monitoring and kept out of traces and the recent latency window
+var probeRoutes = []string{"/metrics", "/api/health", "/api/health/live", "/api/health/ready"}
+
+// exposedHeaders are response headers the frontend may read, including rate limit and quota details
+var exposedHeaders = []string{
+	"Content-Length", "Content-Disposition", "Location", "Retry-After", "X-Request-ID",
+	"X-RateLimit-Limit", "X-RateLimit-Remaining",
+	"X-Quota-Scope", "X-Quota-Limit", "X-Quota-Remaining", "X-Quota-Reset",
+}
+
+// route binds an endpoint to the permission required to call it
+type route struct {
+	method     string
+	path       string
+	permission string
+	handler    gin.HandlerFunc
+}
 
-	if err := router.Run(":" + port); err != nil {
-		log.Fatalf("❌ Failed to start server: %v", err)
+// routes is the single table of protected endpoints and their permissions
+func routes(h *handlers.Handler) []route {
+	return []route{
+		{http.MethodPost, "/analyze", models.PermTransactionsAnalyze, h.AnalyzeTransactions},
+		{http.MethodPost, "/analyze/jobs", models.PermTransactionsAnalyze, h.EnqueueAnalysis},
+		{http.MethodGet, "/analyze/jobs/:id", models.PermTransactionsRead, h.GetAnalysisJob},
+		{http.MethodGet, "/transactions", models.PermTransactionsRead, h.GetAllTransactions},
+		{http.MethodGet, "/transactions/:id", models.PermTransactionsRead, h.GetTransactionByID},
+		{http.MethodGet, "/reports/compliance.pdf", models.PermReportsExport, h.GetComplianceReportPDF},
+		{http.MethodGet, "/stats", models.PermStatsRead, h.GetStats},
+		{http.MethodGet, "/quota", models.PermStatsRead, h.GetQuota},
+		{http.MethodGet, "/system/status", models.PermStatsRead, h.GetSystemStatus},
+		{http.MethodGet, "/usage", models.PermUsageRead, h.GetUsage},
+		{http.MethodGet, "/usage/transactions/:id", models.PermUsageRead, h.GetTransactionUsage},
+		{http.MethodGet, "/purification", models.PermPurificationRead, h.GetPurification},
+		{http.MethodGet, "/purification/donations", models.PermPurificationRead, h.GetDonations},
+		{http.MethodPost, "/purification/donations", models.PermPurificationRecord, h.RecordDonation},
+		{http.MethodPost, "/zakat/calculate", models.PermZakatCalculate, h.CalculateZakat},
+		{http.MethodGet, "/zakat/nisab", models.PermZakatCalculate, h.GetGoldPrice},
+		{http.MethodPut, "/zakat/nisab", models.PermZakatConfigure, h.SetGoldPrice},
+		{http.MethodPost, "/screening/issuers", models.PermScreeningManage, h.ImportIssuers},
+		{http.MethodGet, "/screening/issuers", models.PermScreeningRead, h.GetIssuers},
+		{http.MethodGet, "/screening/issuers/:ticker", models.PermScreeningRead, h.ScreenIssuer},
+		{http.MethodGet, "/screening/methodologies", models.PermScreeningRead, h.GetScreeningMethodologies},
+		{http.MethodPut, "/screening/methodologies/:name", models.PermScreeningManage, h.UpdateScreeningMethodology},
+		{http.MethodGet, "/reviews/queue", models.PermReviewsRead, h.GetReviewQueue},
+		{http.MethodPost, "/reviews/:id/assign", models.PermReviewsDecide, h.AssignReview},
+		{http.MethodPost, "/reviews/:id/decision", models.PermReviewsDecide, h.DecideReview},
+		{http.MethodGet, "/calibration", models.PermCalibrationRead, h.GetCalibration},
+		{http.MethodPost, "/calibration/labels", models.PermCalibrationManage, h.ImportCalibrationLabels},
+		{http.MethodPost, "/calibration/fit", models.PermCalibrationManage, h.FitCalibration},
+		{http.MethodGet, "/approvals/chains", models.PermApprovalsRead, h.GetApprovalChains},
+		{http.MethodPut, "/approvals/chains", models.PermApprovalsConfigure, h.SaveApprovalChain},
+		{http.MethodGet, "/approvals", models.PermApprovalsRead, h.GetApprovals},
+		{http.MethodGet, "/approvals/:id", models.PermApprovalsRead, h.GetApprovalStatus},
+		{http.MethodPost, "/approvals/:id", models.PermApprovalsDecide, h.DecideApproval},
+		{http.MethodGet, "/admin/api-keys", models.PermAPIKeysManage, h.GetAPIKeys},
+		{http.MethodPost, "/admin/api-keys", models.PermAPIKeysManage, h.CreateAPIKey},
+		{http.MethodDelete, "/admin/api-keys/:id", models.PermAPIKeysManage, h.RevokeAPIKey},
+		{http.MethodGet, "/admin/roles", models.PermRolesManage, h.GetRoles},
+		{http.MethodGet, "/admin/permissions", models.PermRolesManage, h.GetPermissions},
+		{http.MethodPut, "/admin/roles/:name/permissions", models.PermRolesManage, h.SetRolePermissions},
+		{http.MethodGet, "/admin/prompts", models.PermPromptsManage, h.GetPromptTemplates},
+		{http.MethodPost, "/admin/prompts/preview", models.PermPromptsManage, h.PreviewPrompt},
+		{http.MethodGet, "/admin/organization", models.PermOrganizationManage, h.GetOrganization},
+		{http.MethodPut, "/admin/organization", models.PermOrganizationManage, h.UpdateOrganizationSettings},
 	}
 }
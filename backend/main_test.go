@@ -0,0 +1,199 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"halalguard-backend/config"
+	"halalguard-backend/handlers"
+	"halalguard-backend/middleware"
+	"halalguard-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// defaultGrants mirrors the role_permissions seeded in database.createTables;
+// admin holds every permission
+var defaultGrants = map[string][]string{
+	"submitter": {
+		models.PermTransactionsAnalyze, models.PermTransactionsRead, models.PermStatsRead,
+		models.PermPurificationRead, models.PermPurificationRecord, models.PermZakatCalculate,
+		models.PermScreeningRead, models.PermApprovalsRead,
+	},
+	"reviewer": {
+		models.PermTransactionsRead, models.PermStatsRead, models.PermPurificationRead,
+		models.PermScreeningRead, models.PermReviewsRead, models.PermReviewsDecide,
+		models.PermApprovalsRead, models.PermApprovalsDecide,
+	},
+	"auditor": {
+		models.PermTransactionsRead, models.PermReportsExport, models.PermStatsRead,
+		models.PermPurificationRead, models.PermZakatCalculate, models.PermScreeningRead,
+		models.PermReviewsRead, models.PermApprovalsRead, models.PermUsageRead,
+		models.PermCalibrationRead,
+	},
+	"dps": {
+		models.PermTransactionsRead, models.PermReportsExport, models.PermStatsRead,
+		models.PermScreeningRead, models.PermReviewsRead, models.PermReviewsDecide,
+		models.PermApprovalsRead, models.PermApprovalsDecide, models.PermCalibrationRead,
+	},
+}
+
+func granted(role, permission string) bool {
+	if role == models.RoleAdmin {
+		return true
+	}
+	for _, p := range defaultGrants[role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// Roles allowed by the default grants, written out per endpoint rather than derived
+// from the routes table so that a route wired to the wrong permission fails
+var (
+	everyone    = []string{"submitter", "reviewer", "auditor", "dps", models.RoleAdmin}
+	adminOnly   = []string{models.RoleAdmin}
+	deciders    = []string{"reviewer", "dps", models.RoleAdmin}
+	auditors    = []string{"auditor", "dps", models.RoleAdmin}
+	submitters  = []string{"submitter", models.RoleAdmin}
+	calculators = []string{"submitter", "auditor", models.RoleAdmin}
+)
+
+var endpointRoles = []struct {
+	method string
+	path   string
+	roles  []string
+}{
+	{http.MethodPost, "/api/analyze", submitters},
+	{http.MethodPost, "/api/analyze/jobs", submitters},
+	{http.MethodGet, "/api/analyze/jobs/JOB1", everyone},
+	{http.MethodGet, "/api/transactions", everyone},
+	{http.MethodGet, "/api/transactions/TXN001", everyone},
+	{http.MethodGet, "/api/reports/compliance.pdf", auditors},
+	{http.MethodGet, "/api/stats", everyone},
+	{http.MethodGet, "/api/quota", everyone},
+	{http.MethodGet, "/api/system/status", everyone},
+	{http.MethodGet, "/api/usage", []string{"auditor", models.RoleAdmin}},
+	{http.MethodGet, "/api/usage/transactions/TXN001", []string{"auditor", models.RoleAdmin}},
+	{http.MethodGet, "/api/purification", []string{"submitter", "reviewer", "auditor", models.RoleAdmin}},
+	{http.MethodGet, "/api/purification/donations", []string{"submitter", "reviewer", "auditor", models.RoleAdmin}},
+	{http.MethodPost, "/api/purification/donations", submitters},
+	{http.MethodPost, "/api/zakat/calculate", calculators},
+	{http.MethodGet, "/api/zakat/nisab", calculators},
+	{http.MethodPut, "/api/zakat/nisab", adminOnly},
+	{http.MethodPost, "/api/screening/issuers", adminOnly},
+	{http.MethodGet, "/api/screening/issuers", everyone},
+	{http.MethodGet, "/api/screening/issuers/BBRI", everyone},
+	{http.MethodGet, "/api/screening/methodologies", everyone},
+	{http.MethodPut, "/api/screening/methodologies/AAOIFI", adminOnly},
+	{http.MethodGet, "/api/reviews/queue", []string{"reviewer", "auditor", "dps", models.RoleAdmin}},
+	{http.MethodPost, "/api/reviews/TXN001/assign", deciders},
+	{http.MethodPost, "/api/reviews/TXN001/decision", deciders},
+	{http.MethodGet, "/api/calibration", auditors},
+	{http.MethodPost, "/api/calibration/labels", adminOnly},
+	{http.MethodPost, "/api/calibration/fit", adminOnly},
+	{http.MethodGet, "/api/approvals/chains", everyone},
+	{http.MethodPut, "/api/approvals/chains", adminOnly},
+	{http.MethodGet, "/api/approvals", everyone},
+	{http.MethodGet, "/api/approvals/TXN001", everyone},
+	{http.MethodPost, "/api/approvals/TXN001", deciders},
+	{http.MethodGet, "/api/admin/api-keys", adminOnly},
+	{http.MethodPost, "/api/admin/api-keys", adminOnly},
+	{http.MethodDelete, "/api/admin/api-keys/7", adminOnly},
+	{http.MethodGet, "/api/admin/roles", adminOnly},
+	{http.MethodGet, "/api/admin/permissions", adminOnly},
+	{http.MethodPut, "/api/admin/roles/auditor/permissions", adminOnly},
+	{http.MethodGet, "/api/admin/prompts", adminOnly},
+	{http.MethodPost, "/api/admin/prompts/preview", adminOnly},
+	{http.MethodGet, "/api/admin/organization", adminOnly},
+	{http.MethodPut, "/api/admin/organization", adminOnly},
+}
+
+func TestRoutePermissions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	const secret = "test-secret"
+	authenticator, err := middleware.NewAuthenticator(config.AuthConfig{
+		JWTAlgorithm: "HS256",
+		JWTSecret:    secret,
+		JWTRoleClaim: "role",
+		JWTOrgClaim:  "org",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	original := middleware.PermissionLookup
+	middleware.PermissionLookup = func(orgID, role, permission string) (bool, error) {
+		return granted(role, permission), nil
+	}
+	t.Cleanup(func() { middleware.PermissionLookup = original })
+
+	// Handlers are replaced by a stub so only authentication and authorization run.
+	// The stub records which routes were reached so unlisted routes are reported.
+	router := gin.New()
+	protected := router.Group("/api", authenticator.Middleware())
+	reached := make(map[string]bool)
+	for _, route := range routes(new(handlers.Handler)) {
+		key := route.method + " /api" + route.path
+		reached[key] = false
+		protected.Handle(route.method, route.path, middleware.RequirePermission(route.permission), func(c *gin.Context) {
+			reached[c.Request.Method+" "+c.FullPath()] = true
+			c.Status(http.StatusNoContent)
+		})
+	}
+
+	tokens := make(map[string]string)
+	for _, role := range everyone {
+		tokens[role], err = jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+			"sub":  role + "@koperasi-a",
+			"role": role,
+			"org":  "koperasi-a",
+			"exp":  time.Now().Add(time.Hour).Unix(),
+		}).SignedString([]byte(secret))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	serve := func(method, path, token string) int {
+		req := httptest.NewRequest(method, path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	for _, endpoint := range endpointRoles {
+		t.Run(endpoint.method+" "+endpoint.path, func(t *testing.T) {
+			if code := serve(endpoint.method, endpoint.path, ""); code != http.StatusUnauthorized {
+				t.Errorf("without credentials: status = %d, want 401", code)
+			}
+			allowed := make(map[string]bool)
+			for _, role := range endpoint.roles {
+				allowed[role] = true
+			}
+			for _, role := range everyone {
+				want := http.StatusForbidden
+				if allowed[role] {
+					want = http.StatusNoContent
+				}
+				if code := serve(endpoint.method, endpoint.path, tokens[role]); code != want {
+					t.Errorf("role %s: status = %d, want %d", role, code, want)
+				}
+			}
+		})
+	}
+
+	for route, ok := range reached {
+		if !ok {
+			t.Errorf("route %s has no entry in endpointRoles", route)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+// Package metrics defines the Prometheus collectors exposed on /metrics.
+package metrics
+
+import (
+	"database/sql"
+
+	"halalguard-backend/models"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	dto "github.com/prometheus/client_model/go"
+)
+
+const namespace = "halalguard"
+
+var (
+	// HTTPRequestDuration observes request latency per route template
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method, route and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// LLMRequestDuration observes each model call attempt
+	LLMRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "llm_request_duration_seconds",
+		Help:      "Model call latency per attempt by provider, model and outcome.",
+		Buckets:   []float64{0.5, 1, 2, 5, 10, 20, 30, 60, 120},
+	}, []string{"provider", "model", "outcome"})
+
+	// LLMErrors counts failed model analyses by reason
+	LLMErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "llm_errors_total",
+		Help:      "Model analysis failures by provider, model and reason.",
+	}, []string{"provider", "model", "reason"})
+
+	// LLMRetries counts model call attempts retried after a transient error
+	LLMRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "llm_retries_total",
+		Help:      "Model call retries by provider and model.",
+	}, []string{"provider", "model"})
+
+	// LLMTokens counts tokens consumed by analysis calls
+	LLMTokens = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "llm_tokens_total",
+		Help:      "LLM tokens consumed by model and type (prompt, candidate, total).",
+	}, []string{"model", "type"})
+
+	// AnalysisResults counts analysis verdicts
+	AnalysisResults = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "analysis_results_total",
+		Help:      "Transaction analysis outcomes by status and violation type.",
+	}, []string{"status", "violation_type"})
+
+	// AnalysisCacheRequests counts analysis cache lookups by result (hit, miss)
+	AnalysisCacheRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "analysis_cache_requests_total",
+		Help:      "Analysis cache lookups by result (hit or miss).",
+	}, []string{"result"})
+
+	// EnsembleVotes counts ensemble verdicts by outcome (agreed, contested)
+	EnsembleVotes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ensemble_votes_total",
+		Help:      "Ensemble analysis verdicts by outcome (agreed or contested).",
+	}, []string{"outcome"})
+)
+
+// Registry holds every HalalGuard collector plus the Go runtime and process collectors
+var Registry = prometheus.NewRegistry()
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequestDuration,
+		LLMRequestDuration,
+		LLMErrors,
+		LLMRetries,
+		LLMTokens,
+		AnalysisResults,
+		AnalysisCacheRequests,
+		EnsembleVotes,
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "analysis_cache_hit_ratio",
+			Help:      "Share of analysis cache lookups served from the cache since startup.",
+		}, cacheHitRatio),
+	)
+}
+
+// RegisterDB exposes the connection pool statistics of db (sql.DB.Stats)
+func RegisterDB(db *sql.DB, name string) {
+	Registry.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+// ObserveTokens adds the tokens of one LLM call
+func ObserveTokens(usage models.TokenUsage) {
+	LLMTokens.WithLabelValues(usage.Model, "prompt").Add(float64(usage.PromptTokens))
+	LLMTokens.WithLabelValues(usage.Model, "candidate").Add(float64(usage.CandidateTokens))
+	LLMTokens.WithLabelValues(usage.Model, "total").Add(float64(usage.TotalTokens))
+}
+
+// ObserveAnalysisResults counts the verdicts returned for a batch
+func ObserveAnalysisResults(results []models.AnalysisResult) {
+	for _, result := range results {
+		AnalysisResults.WithLabelValues(result.Status, result.ViolationType).Inc()
+	}
+}
+
+func cacheHitRatio() float64 {
+	hits := counterValue(AnalysisCacheRequests.WithLabelValues("hit"))
+	misses := counterValue(AnalysisCacheRequests.WithLabelValues("miss"))
+	if hits+misses == 0 {
+		return 0
+	}
+	return hits / (hits + misses)
+}
+
+// counterValue reads the current value of a counter
+func counterValue(counter prometheus.Counter) float64 {
+	var m dto.Metric
+	if err := counter.Write(&m); err != nil {
+		return 0
+	}
+	return m.GetCounter().GetValue()
+}
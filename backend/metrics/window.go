@@ -0,0 +1,129 @@
+package metrics
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"halalguard-backend/models"
+)
+
+// Recent latency windows feeding /api/system/status, which needs percentiles
+// over the last minutes rather than the cumulative Prometheus histograms
+var (
+	RecentHTTP = NewWindow(5000, 30*time.Minute)
+	RecentLLM  = NewWindow(1000, 30*time.Minute)
+)
+
+type sample struct {
+	at       time.Time
+	duration time.Duration
+	failed   bool
+}
+
+// Window keeps the most recent samples, up to a capacity and a maximum age
+type Window struct {
+	span time.Duration
+
+	mu      sync.Mutex
+	samples []sample
+	next    int
+	full    bool
+}
+
+// NewWindow creates a window holding at most capacity samples younger than span
+func NewWindow(capacity int, span time.Duration) *Window {
+	return &Window{span: span, samples: make([]sample, capacity)}
+}
+
+// Span is the maximum age of samples in the window
+func (w *Window) Span() time.Duration {
+	return w.span
+}
+
+// Observe records one request or call
+func (w *Window) Observe(duration time.Duration, failed bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.samples[w.next] = sample{at: time.Now(), duration: duration, failed: failed}
+	w.next = (w.next + 1) % len(w.samples)
+	if w.next == 0 {
+		w.full = true
+	}
+}
+
+// recent returns the samples younger than the span, oldest first
+func (w *Window) recent(now time.Time) []sample {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var ordered []sample
+	if w.full {
+		ordered = append(ordered, w.samples[w.next:]...)
+	}
+	ordered = append(ordered, w.samples[:w.next]...)
+
+	cutoff := now.Add(-w.span)
+	start := sort.Search(len(ordered), func(i int) bool { return ordered[i].at.After(cutoff) })
+	return ordered[start:]
+}
+
+// Summary returns latency percentiles and the error rate over the window
+func (w *Window) Summary() models.LatencySummary {
+	return summarize(w.recent(time.Now()))
+}
+
+// Series buckets the window into fixed intervals for charting
+func (w *Window) Series(interval time.Duration) []models.LatencyPoint {
+	now := time.Now()
+	samples := w.recent(now)
+
+	buckets := int(w.span / interval)
+	start := now.Truncate(interval).Add(-time.Duration(buckets-1) * interval)
+	grouped := make([][]sample, buckets)
+	for _, s := range samples {
+		if i := int(s.at.Sub(start) / interval); i >= 0 && i < buckets {
+			grouped[i] = append(grouped[i], s)
+		}
+	}
+
+	series := make([]models.LatencyPoint, buckets)
+	for i, group := range grouped {
+		series[i] = models.LatencyPoint{
+			Time:           start.Add(time.Duration(i) * interval),
+			LatencySummary: summarize(group),
+		}
+	}
+	return series
+}
+
+func summarize(samples []sample) models.LatencySummary {
+	summary := models.LatencySummary{Count: len(samples)}
+	if len(samples) == 0 {
+		return summary
+	}
+
+	durations := make([]float64, len(samples))
+	var failed int
+	for i, s := range samples {
+		durations[i] = float64(s.duration) / float64(time.Millisecond)
+		if s.failed {
+			failed++
+		}
+	}
+	sort.Float64s(durations)
+
+	summary.ErrorRate = float64(failed) / float64(len(samples))
+	summary.P50Ms = percentile(durations, 0.50)
+	summary.P90Ms = percentile(durations, 0.90)
+	summary.P99Ms = percentile(durations, 0.99)
+	return summary
+}
+
+// percentile uses the nearest-rank method on sorted values
+func percentile(sorted []float64, p float64) float64 {
+	rank := int(p*float64(len(sorted))+0.5) - 1
+	rank = max(0, min(rank, len(sorted)-1))
+	return sorted[rank]
+}
@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"halalguard-backend/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Metrics records the latency of every request under its route template, so
+// /api/transactions/:id is one series regardless of the ID requested. Requests
+// outside the probe routes also feed the recent window behind /api/system/status.
+func Metrics(probeRoutes ...string) gin.HandlerFunc {
+	probes := make(map[string]bool, len(probeRoutes))
+	for _, route := range probeRoutes {
+		probes[route] = true
+	}
+
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		elapsed := time.Since(start)
+		status := c.Writer.Status()
+		metrics.HTTPRequestDuration.
+			WithLabelValues(c.Request.Method, route, strconv.Itoa(status)).
+			Observe(elapsed.Seconds())
+		if !probes[route] {
+			metrics.RecentHTTP.Observe(elapsed, status >= http.StatusInternalServerError)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"halalguard-backend/config"
+	"halalguard-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// bucketIdleTTL is how long an unused bucket is kept before being swept
+const bucketIdleTTL = 10 * time.Minute
+
+type bucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+// RateLimiter is an in-memory token bucket per API key or client IP
+type RateLimiter struct {
+	rate  float64
+	burst float64
+	key   func(c *gin.Context) string
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// NewRateLimiter creates a rate limiter per API key or client IP; a zero rate disables limiting
+func NewRateLimiter(cfg config.RateLimitConfig) *RateLimiter {
+	return newRateLimiter(cfg.RequestsPerSecond, cfg.Burst, rateLimitKey)
+}
+
+// NewIPRateLimiter creates a rate limiter per client IP that runs before
+// authentication, so requests with invalid credentials are limited as well
+func NewIPRateLimiter(cfg config.RateLimitConfig) *RateLimiter {
+	return newRateLimiter(cfg.IPRequestsPerSecond, cfg.IPBurst, func(c *gin.Context) string {
+		return "ip:" + c.ClientIP()
+	})
+}
+
+func newRateLimiter(rate float64, burst int, key func(c *gin.Context) string) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{
+		rate:      rate,
+		burst:     float64(burst),
+		key:       key,
+		buckets:   make(map[string]*bucket),
+		lastSweep: time.Now(),
+	}
+}
+
+// Middleware aborts with 429 once the caller's bucket is empty. The limiter from
+// NewRateLimiter must run after Authenticator.Middleware so API key callers are
+// limited per key.
+func (l *RateLimiter) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if l.rate <= 0 {
+			c.Next()
+			return
+		}
+
+		allowed, remaining, retryAfter := l.take(l.key(c), time.Now())
+		c.Header("X-RateLimit-Limit", strconv.Itoa(int(l.burst)))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(remaining))
+		if !allowed {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, models.ErrorResponse{
+				Error:   "Too many requests",
+				Message: "rate limit exceeded, retry after " + retryAfter.Round(time.Second).String(),
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// take removes one token from the key's bucket, reporting whether the request
+// is allowed, the whole tokens left and how long until the next token
+func (l *RateLimiter) take(key string, now time.Time) (bool, int, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, lastSeen: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.lastSeen).Seconds()*l.rate)
+	b.lastSeen = now
+
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+		return false, 0, wait
+	}
+	b.tokens--
+	return true, int(b.tokens), 0
+}
+
+// sweep drops buckets idle long enough to have refilled completely
+func (l *RateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < bucketIdleTTL {
+		return
+	}
+	for key, b := range l.buckets {
+		if now.Sub(b.lastSeen) > bucketIdleTTL {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastSweep = now
+}
+
+// rateLimitKey identifies API key callers by key and everyone else by client IP. The
+// client IP only honours X-Forwarded-For from TRUSTED_PROXIES.
+func rateLimitKey(c *gin.Context) string {
+	if principal := PrincipalFrom(c); principal != nil && principal.Method == models.AuthMethodAPIKey {
+		return "key:" + principal.OrganizationID + "/" + principal.Subject
+	}
+	return "ip:" + c.ClientIP()
+}
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"net/http"
+
+	"halalguard-backend/models"
+	"halalguard-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PermissionLookup reports whether a role grants a permission. Tests replace it to
+// check routes without a database.
+var PermissionLookup = services.HasPermission
+
+// RequirePermission aborts with 403 unless the principal's role grants the permission
+// in the principal's organization.
+// It must run after Authenticator.Middleware.
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal := PrincipalFrom(c)
+		if principal == nil || principal.Role == "" {
+			c.AbortWithStatusJSON(http.StatusForbidden, models.ErrorResponse{
+				Error:   "Forbidden",
+				Message: "no role assigned to this principal",
+			})
+			return
+		}
+
+		granted, err := PermissionLookup(principal.OrganizationID, principal.Role, permission)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "Failed to check permissions",
+				Message: err.Error(),
+			})
+			return
+		}
+		if !granted {
+			c.AbortWithStatusJSON(http.StatusForbidden, models.ErrorResponse{
+				Error:   "Forbidden",
+				Message: "role " + principal.Role + " lacks permission " + permission,
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"halalguard-backend/config"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testSecret = "test-secret"
+
+func testToken(t *testing.T, role string) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub":  "ustadz.ahmad",
+		"role": role,
+		"org":  "koperasi-a",
+		"exp":  time.Now().Add(time.Hour).Unix(),
+	}).SignedString([]byte(testSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func stubPermissions(t *testing.T, grants map[string][]string, err error) {
+	t.Helper()
+	original := PermissionLookup
+	PermissionLookup = func(orgID, role, permission string) (bool, error) {
+		if err != nil {
+			return false, err
+		}
+		for _, granted := range grants[role] {
+			if granted == permission {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	t.Cleanup(func() { PermissionLookup = original })
+}
+
+func TestRequirePermission(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	authenticator, err := NewAuthenticator(config.AuthConfig{
+		JWTAlgorithm: "HS256",
+		JWTSecret:    testSecret,
+		JWTRoleClaim: "role",
+		JWTOrgClaim:  "org",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	grants := map[string][]string{
+		"reviewer": {"reviews:decide", "reviews:read"},
+		"auditor":  {"reviews:read"},
+	}
+
+	tests := []struct {
+		name       string
+		role       string
+		noAuth     bool
+		lookupErr  error
+		permission string
+		want       int
+	}{
+		{name: "no credentials", noAuth: true, permission: "reviews:read", want: http.StatusUnauthorized},
+		{name: "no role", role: "", permission: "reviews:read", want: http.StatusForbidden},
+		{name: "unknown role", role: "guest", permission: "reviews:read", want: http.StatusForbidden},
+		{name: "role lacks permission", role: "auditor", permission: "reviews:decide", want: http.StatusForbidden},
+		{name: "role grants permission", role: "reviewer", permission: "reviews:decide", want: http.StatusOK},
+		{name: "lookup fails", role: "reviewer", lookupErr: errors.New("db down"), permission: "reviews:read", want: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stubPermissions(t, grants, tt.lookupErr)
+			router := gin.New()
+			router.GET("/protected", authenticator.Middleware(), RequirePermission(tt.permission), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/protected", nil)
+			if !tt.noAuth {
+				req.Header.Set("Authorization", "Bearer "+testToken(t, tt.role))
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body.String())
+			}
+		})
+	}
+}
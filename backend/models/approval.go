@@ -0,0 +1,58 @@
+package models
+
+import "time"
+
+// Approval roles used in approval chain steps
+const (
+	ApprovalRoleReviewer = "reviewer"
+	ApprovalRoleDPS      = "dps"
+)
+
+// Approval states of a transaction
+const (
+	ApprovalNotRequired = "not_required"
+	ApprovalPending     = "pending"
+	ApprovalApproved    = "approved"
+	ApprovalRejected    = "rejected"
+)
+
+// ApprovalChain represents the approval steps required above an amount threshold
+type ApprovalChain struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name" binding:"required"`
+	// TransactionType restricts the chain to one transaction type; empty matches any type
+	TransactionType string   `json:"transactionType,omitempty"`
+	MinAmount       float64  `json:"minAmount" binding:"gte=0"`
+	Steps           []string `json:"steps" binding:"required,min=1,dive,oneof=reviewer dps"`
+}
+
+// ApprovalStep represents a recorded approval decision
+type ApprovalStep struct {
+	StepIndex int       `json:"stepIndex"`
+	Role      string    `json:"role"`
+	Approver  string    `json:"approver"`
+	Decision  string    `json:"decision"`
+	Comment   string    `json:"comment,omitempty"`
+	DecidedAt time.Time `json:"decidedAt"`
+}
+
+// ApprovalStatus shows where a transaction sits in its approval chain
+type ApprovalStatus struct {
+	TransactionID string         `json:"transactionId"`
+	Amount        float64        `json:"amount"`
+	Type          string         `json:"type"`
+	State         string         `json:"state"`
+	Locked        bool           `json:"locked"`
+	Chain         *ApprovalChain `json:"chain,omitempty"`
+	CurrentStep   int            `json:"currentStep"`
+	NextRole      string         `json:"nextRole,omitempty"`
+	Steps         []ApprovalStep `json:"steps"`
+}
+
+// ApprovalRequest represents the API request to approve or reject the current step.
+// The approver is the authenticated caller.
+type ApprovalRequest struct {
+	Role     string `json:"role" binding:"required,oneof=reviewer dps"`
+	Decision string `json:"decision" binding:"required,oneof=approved rejected"`
+	Comment  string `json:"comment"`
+}
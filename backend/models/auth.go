@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// Authentication methods of a principal
+const (
+	AuthMethodAPIKey = "api_key"
+	AuthMethodJWT    = "jwt"
+)
+
+// Principal represents the authenticated caller of a request
+type Principal struct {
+	Subject        string `json:"subject"`
+	Role           string `json:"role"`
+	OrganizationID string `json:"organizationId"`
+	Method         string `json:"method"`
+}
+
+// APIKey represents a stored (hashed) API key for machine clients
+type APIKey struct {
+	ID             int64      `json:"id"`
+	OrganizationID string     `json:"organizationId"`
+	Name           string     `json:"name"`
+	Prefix         string     `json:"prefix"`
+	Role           string     `json:"role"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	LastUsedAt     *time.Time `json:"lastUsedAt,omitempty"`
+	RevokedAt      *time.Time `json:"revokedAt,omitempty"`
+}
+
+// CreateAPIKeyRequest represents the API request to issue an API key
+type CreateAPIKeyRequest struct {
+	Name string `json:"name" binding:"required"`
+	Role string `json:"role" binding:"required"`
+}
+
+// CreateAPIKeyResponse returns the plaintext key, which is shown only once
+type CreateAPIKeyResponse struct {
+	APIKey
+	Key string `json:"key"`
+}
@@ -0,0 +1,69 @@
+package models
+
+import "time"
+
+// Calibration methods selectable with CALIBRATION_METHOD
+const (
+	CalibrationIsotonic = "isotonic"
+	CalibrationPlatt    = "platt"
+	// CalibrationNone marks a violation type with too few labels to fit
+	CalibrationNone = "none"
+)
+
+// CalibrationLabel is a confirmed verdict of a transaction, used to measure how often
+// the model is right at a given confidence
+type CalibrationLabel struct {
+	TransactionID string `json:"transactionId"`
+	Status        string `json:"status"`
+	ViolationType string `json:"violationType"`
+}
+
+// CalibrationPoint maps a raw confidenceScore to the calibrated one (both 0-100)
+type CalibrationPoint struct {
+	Confidence float64 `json:"confidence"`
+	Calibrated float64 `json:"calibrated"`
+}
+
+// CalibrationBin is one bucket of a reliability curve
+type CalibrationBin struct {
+	// Lower and Upper bound the raw confidenceScore of the bin
+	Lower float64 `json:"lower"`
+	Upper float64 `json:"upper"`
+	Count int     `json:"count"`
+	// MeanConfidence and MeanCalibrated average the raw and calibrated scores (0-100)
+	MeanConfidence float64 `json:"meanConfidence"`
+	MeanCalibrated float64 `json:"meanCalibrated"`
+	// Accuracy is the share of verdicts in the bin matching their label (0-1)
+	Accuracy float64 `json:"accuracy"`
+}
+
+// CalibrationModel maps raw confidence to observed accuracy for analyses of one
+// violation type
+type CalibrationModel struct {
+	ViolationType string `json:"violationType"`
+	// Method is isotonic, platt, or none when there were too few labeled samples
+	Method string `json:"method"`
+	// Points is the isotonic mapping, interpolated linearly between points
+	Points []CalibrationPoint `json:"points,omitempty"`
+	// PlattA and PlattB map raw confidence c to 1 / (1 + exp(-(PlattA*c/100 + PlattB)))
+	PlattA float64 `json:"plattA,omitempty"`
+	PlattB float64 `json:"plattB,omitempty"`
+	// Samples is the number of labeled analyses the model was fitted on
+	Samples int `json:"samples"`
+	// Accuracy is the share of those analyses matching their label (0-1)
+	Accuracy float64 `json:"accuracy"`
+	// BrierRaw and BrierCalibrated are the mean squared error of the raw and calibrated
+	// confidence against the labels. The calibrated score of each sample comes from a
+	// model fitted on the other cross-validation folds.
+	BrierRaw        float64          `json:"brierRaw"`
+	BrierCalibrated float64          `json:"brierCalibrated"`
+	Curve           []CalibrationBin `json:"curve"`
+	FittedAt        time.Time        `json:"fittedAt"`
+}
+
+// CalibrationReport lists an organization's calibration per violation type
+type CalibrationReport struct {
+	// Labels is the number of imported labels
+	Labels int                `json:"labels"`
+	Models []CalibrationModel `json:"models"`
+}
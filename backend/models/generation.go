@@ -0,0 +1,50 @@
+package models
+
+// GenerationSettings are the model and sampling parameters an analysis ran with,
+// recorded with each result so it can be reproduced
+type GenerationSettings struct {
+	Model           string  `json:"model"`
+	Temperature     float32 `json:"temperature"`
+	TopP            float32 `json:"topP"`
+	TopK            int32   `json:"topK"`
+	MaxOutputTokens int32   `json:"maxOutputTokens"`
+	// Seed is nil when sampling is not seeded
+	Seed *int32 `json:"seed,omitempty"`
+}
+
+// GenerationOverride replaces some of the server's generation settings for a single
+// request. Only roles holding the analysis:override permission may send one.
+type GenerationOverride struct {
+	Model           string   `json:"model,omitempty"`
+	Temperature     *float32 `json:"temperature,omitempty" binding:"omitempty,min=0,max=2"`
+	TopP            *float32 `json:"topP,omitempty" binding:"omitempty,gt=0,max=1"`
+	TopK            *int32   `json:"topK,omitempty" binding:"omitempty,min=1"`
+	MaxOutputTokens *int32   `json:"maxOutputTokens,omitempty" binding:"omitempty,min=1"`
+	Seed            *int32   `json:"seed,omitempty"`
+}
+
+// Apply returns settings with the override's non-empty fields replaced
+func (o *GenerationOverride) Apply(settings GenerationSettings) GenerationSettings {
+	if o == nil {
+		return settings
+	}
+	if o.Model != "" {
+		settings.Model = o.Model
+	}
+	if o.Temperature != nil {
+		settings.Temperature = *o.Temperature
+	}
+	if o.TopP != nil {
+		settings.TopP = *o.TopP
+	}
+	if o.TopK != nil {
+		settings.TopK = *o.TopK
+	}
+	if o.MaxOutputTokens != nil {
+		settings.MaxOutputTokens = *o.MaxOutputTokens
+	}
+	if o.Seed != nil {
+		settings.Seed = o.Seed
+	}
+	return settings
+}
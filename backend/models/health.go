@@ -0,0 +1,72 @@
+package models
+
+import "time"
+
+// Health check states
+const (
+	HealthOK       = "ok"
+	HealthDegraded = "degraded"
+	HealthDown     = "down"
+)
+
+// HealthCheck is the result of checking one dependency
+type HealthCheck struct {
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latencyMs,omitempty"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// HealthResponse is returned by the liveness and readiness probes
+type HealthResponse struct {
+	Status  string                 `json:"status"`
+	Service string                 `json:"service"`
+	Version string                 `json:"version"`
+	Checks  map[string]HealthCheck `json:"checks,omitempty"`
+}
+
+// LatencySummary describes requests in a recent window
+type LatencySummary struct {
+	Count     int     `json:"count"`
+	ErrorRate float64 `json:"errorRate"`
+	P50Ms     float64 `json:"p50Ms"`
+	P90Ms     float64 `json:"p90Ms"`
+	P99Ms     float64 `json:"p99Ms"`
+}
+
+// LatencyPoint is one interval of a latency series
+type LatencyPoint struct {
+	Time time.Time `json:"time"`
+	LatencySummary
+}
+
+// LLMStatus describes recent calls to the model provider and the circuit breaker
+type LLMStatus struct {
+	// Provider is gemini, openai or ollama
+	Provider string `json:"provider"`
+	LatencySummary
+	Circuit       string     `json:"circuit"`
+	LastSuccessAt *time.Time `json:"lastSuccessAt,omitempty"`
+	LastFailureAt *time.Time `json:"lastFailureAt,omitempty"`
+	LastError     string     `json:"lastError,omitempty"`
+}
+
+// AnalysisActivity holds an organization's most recent analysis calls
+type AnalysisActivity struct {
+	LastAnalysisAt       *time.Time `json:"lastAnalysisAt,omitempty"`
+	LastFailedAnalysisAt *time.Time `json:"lastFailedAnalysisAt,omitempty"`
+	AnalysesLast24h      int64      `json:"analysesLast24h"`
+}
+
+// SystemStatus is the response of GET /system/status
+type SystemStatus struct {
+	Status        string                 `json:"status"`
+	Version       string                 `json:"version"`
+	StartedAt     time.Time              `json:"startedAt"`
+	UptimeSeconds int64                  `json:"uptimeSeconds"`
+	WindowMinutes int                    `json:"windowMinutes"`
+	Checks        map[string]HealthCheck `json:"checks"`
+	HTTP          LatencySummary         `json:"http"`
+	LLM           LLMStatus              `json:"llm"`
+	Analysis      AnalysisActivity       `json:"analysis"`
+	Series        []LatencyPoint         `json:"series"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// Analysis job states
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+)
+
+// AnalysisJob is a batch of transactions queued for background analysis
+type AnalysisJob struct {
+	ID             int64               `json:"id"`
+	OrganizationID string              `json:"organizationId"`
+	State          string              `json:"state"`
+	Transactions   []TransactionInput  `json:"transactions"`
+	Language       string              `json:"language,omitempty"`
+	Generation     *GenerationOverride `json:"generation,omitempty"`
+	Result         *AnalyzeResponse    `json:"result,omitempty"`
+	Error          string              `json:"error,omitempty"`
+	RequestID      string              `json:"requestId,omitempty"`
+	Attempts       int                 `json:"attempts"`
+	CreatedAt      time.Time           `json:"createdAt"`
+	StartedAt      *time.Time          `json:"startedAt,omitempty"`
+	FinishedAt     *time.Time          `json:"finishedAt,omitempty"`
+}
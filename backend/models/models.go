@@ -56,22 +56,81 @@ type AnalysisResult struct {
 	MaslahahAnalysis    *MaslahahAnalysis   `json:"maslahahAnalysis,omitempty"`
 	Reasoning           string              `json:"reasoning"`
 	SuggestedCorrection string              `json:"suggestedCorrection,omitempty"`
+	Screening           *ScreeningResult    `json:"screening,omitempty"`
+	// RawConfidenceScore is the model's own confidenceScore when ConfidenceScore was calibrated
+	RawConfidenceScore *float64 `json:"rawConfidenceScore,omitempty"`
+	// Language is the code of the language reasoning is written in (id, en, ar)
+	Language string `json:"language,omitempty"`
+	// PromptVersion identifies the prompt templates the result was generated with
+	PromptVersion string `json:"promptVersion,omitempty"`
+	// Generation records the model and sampling parameters the result was generated with
+	Generation *GenerationSettings `json:"generation,omitempty"`
+	// Ensemble records how the models voted when the result came from an ensemble
+	Ensemble *EnsembleVerdict `json:"ensemble,omitempty"`
+}
+
+// EnsembleVerdict records the votes behind an ensemble analysis result
+type EnsembleVerdict struct {
+	// Votes is the number of models and samples that answered the batch
+	Votes int `json:"votes"`
+	// Agreement is the share of votes for the majority status and violation type (0-1)
+	Agreement float64 `json:"agreement"`
+	// MajorityStatus and MajorityViolationType are the majority verdict, kept when a
+	// contested result is routed to review
+	MajorityStatus        string `json:"majorityStatus"`
+	MajorityViolationType string `json:"majorityViolationType"`
+	// ScoreSpread is the range of confidenceScore among the majority votes
+	ScoreSpread float64 `json:"scoreSpread"`
+	// Contested is set when agreement or score spread missed the configured thresholds
+	// and the result was set to "Butuh Tinjauan"
+	Contested bool           `json:"contested"`
+	Opinions  []ModelOpinion `json:"opinions"`
+}
+
+// ModelOpinion is the verdict of one ensemble member for a transaction
+type ModelOpinion struct {
+	Model string `json:"model"`
+	// Sample numbers repeated answers of the same model from 0
+	Sample          int                 `json:"sample"`
+	Status          string              `json:"status"`
+	ViolationType   string              `json:"violationType"`
+	ConfidenceScore float64             `json:"confidenceScore"`
+	Breakdown       ComplianceBreakdown `json:"breakdown"`
+	Reasoning       string              `json:"reasoning"`
 }
 
 // CombinedResult represents transaction with analysis
 type CombinedResult struct {
 	TransactionInput
 	Analysis *AnalysisResult `json:"analysis,omitempty"`
+	Review   *ReviewDecision `json:"review,omitempty"`
+	// Texts holds the reasoning of every language the transaction was analyzed in
+	Texts []AnalysisText `json:"texts,omitempty"`
+}
+
+// AnalysisText is the reasoning of an analysis in one language
+type AnalysisText struct {
+	Language            string    `json:"language"`
+	Reasoning           string    `json:"reasoning"`
+	SuggestedCorrection string    `json:"suggestedCorrection,omitempty"`
+	LongTermProjection  string    `json:"longTermProjection,omitempty"`
+	PromptVersion       string    `json:"promptVersion,omitempty"`
+	CreatedAt           time.Time `json:"createdAt"`
 }
 
 // AnalyzeRequest represents the API request for analysis
 type AnalyzeRequest struct {
 	Transactions []TransactionInput `json:"transactions" binding:"required,min=1"`
+	// Language of reasoning; defaults to the Accept-Language header, then the organization's language
+	Language string `json:"language,omitempty" binding:"omitempty,oneof=id en ar"`
+	// Generation overrides the model and sampling parameters; requires analysis:override
+	Generation *GenerationOverride `json:"generation,omitempty"`
 }
 
 // AnalyzeResponse represents the API response
 type AnalyzeResponse struct {
 	Results []AnalysisResult `json:"results"`
+	Usage   TokenUsage       `json:"usage"`
 }
 
 // ErrorResponse represents error response
@@ -79,3 +138,37 @@ type ErrorResponse struct {
 	Error   string `json:"error"`
 	Message string `json:"message,omitempty"`
 }
+
+// Compliance statuses returned by the analysis
+const (
+	StatusCompliant    = "Patuh"
+	StatusNonCompliant = "Tidak Patuh"
+	StatusNeedsReview  = "Butuh Tinjauan"
+)
+
+// Violation types returned by the analysis
+const (
+	ViolationRiba    = "Riba"
+	ViolationGharar  = "Gharar"
+	ViolationMaysir  = "Maysir"
+	ViolationHalal   = "Halal"
+	ViolationSyubhat = "Syubhat"
+)
+
+// IsValidStatus reports whether status is one of the known compliance statuses
+func IsValidStatus(status string) bool {
+	switch status {
+	case StatusCompliant, StatusNonCompliant, StatusNeedsReview:
+		return true
+	}
+	return false
+}
+
+// IsValidViolationType reports whether violationType is one of the known violation types
+func IsValidViolationType(violationType string) bool {
+	switch violationType {
+	case ViolationRiba, ViolationGharar, ViolationMaysir, ViolationHalal, ViolationSyubhat:
+		return true
+	}
+	return false
+}
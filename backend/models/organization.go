@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// DefaultOrganizationID is the tenant that owns data created before multi-tenancy
+const DefaultOrganizationID = "default"
+
+// Supported analysis languages
+const (
+	LanguageIndonesian = "id"
+	LanguageEnglish    = "en"
+	LanguageArabic     = "ar"
+)
+
+// TenantSettings controls how a tenant's transactions are analyzed
+type TenantSettings struct {
+	// Model overrides the default Gemini model; empty uses the server default
+	Model    string `json:"model"`
+	Language string `json:"language" binding:"omitempty,oneof=id en ar"`
+	// RulePacks names additional fatwa/standard references applied during analysis
+	RulePacks []string `json:"rulePacks"`
+}
+
+// Organization represents a tenant such as a BMT or koperasi syariah
+type Organization struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	TenantSettings
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
@@ -0,0 +1,26 @@
+package models
+
+// PromptTemplate is a prompt template loaded by the analyzer
+type PromptTemplate struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	// Source is "embedded" or the path of the override file
+	Source string `json:"source"`
+	Text   string `json:"text"`
+}
+
+// PromptPreviewRequest asks for the prompt that would be sent for sample transactions.
+// Language defaults to the Accept-Language header, then the caller's organization;
+// rule packs default to the organization's.
+type PromptPreviewRequest struct {
+	Transactions []TransactionInput `json:"transactions" binding:"required,min=1"`
+	Language     string             `json:"language" binding:"omitempty,oneof=id en ar"`
+	RulePacks    []string           `json:"rulePacks"`
+}
+
+// PromptPreview is a rendered system instruction and prompt
+type PromptPreview struct {
+	Version           string `json:"version"`
+	SystemInstruction string `json:"systemInstruction"`
+	Prompt            string `json:"prompt"`
+}
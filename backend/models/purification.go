@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+// DonationInput represents a charity donation recorded against purification obligations
+type DonationInput struct {
+	TransactionID string  `json:"transactionId,omitempty"`
+	Amount        float64 `json:"amount" binding:"required,gt=0"`
+	Date          string  `json:"date" binding:"required"`
+	Recipient     string  `json:"recipient" binding:"required"`
+	Note          string  `json:"note,omitempty"`
+}
+
+// Donation represents a stored purification donation
+type Donation struct {
+	ID int64 `json:"id"`
+	DonationInput
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// PurificationItem represents the purification obligation for a single transaction
+type PurificationItem struct {
+	TransactionID string  `json:"transactionId"`
+	Description   string  `json:"description"`
+	Date          string  `json:"date"`
+	Type          string  `json:"type"`
+	Amount        float64 `json:"amount"`
+	ViolationType string  `json:"violationType"`
+	Ratio         float64 `json:"ratio"`
+	Obligation    float64 `json:"obligation"`
+	Donated       float64 `json:"donated"`
+	Outstanding   float64 `json:"outstanding"`
+}
+
+// PurificationPeriod represents purification totals for one month (YYYY-MM)
+type PurificationPeriod struct {
+	Period      string  `json:"period"`
+	Obligation  float64 `json:"obligation"`
+	Donated     float64 `json:"donated"`
+	Outstanding float64 `json:"outstanding"`
+}
+
+// PurificationSummary represents outstanding purification balances
+type PurificationSummary struct {
+	SyubhatRatio         float64              `json:"syubhatRatio"`
+	TotalObligation      float64              `json:"totalObligation"`
+	TotalDonated         float64              `json:"totalDonated"`
+	UnallocatedDonations float64              `json:"unallocatedDonations"`
+	Outstanding          float64              `json:"outstanding"`
+	Periods              []PurificationPeriod `json:"periods"`
+	Items                []PurificationItem   `json:"items"`
+}
@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// Quota periods
+const (
+	QuotaPeriodDay   = "day"
+	QuotaPeriodMonth = "month"
+)
+
+// TenantQuota limits a tenant's Gemini consumption; zero means unlimited.
+// Quotas are set by operators with the apikey command, not by tenant admins.
+type TenantQuota struct {
+	DailyTransactions   int64 `json:"dailyTransactions"`
+	MonthlyTransactions int64 `json:"monthlyTransactions"`
+	DailyTokens         int64 `json:"dailyTokens"`
+	MonthlyTokens       int64 `json:"monthlyTokens"`
+}
+
+// QuotaUsage is a tenant's consumption within one quota period
+type QuotaUsage struct {
+	Period       string    `json:"period"`
+	Start        string    `json:"start"`
+	ResetAt      time.Time `json:"resetAt"`
+	Transactions int64     `json:"transactions"`
+	Tokens       int64     `json:"tokens"`
+}
+
+// QuotaStatus shows a tenant's quota limits alongside current usage
+type QuotaStatus struct {
+	OrganizationID string      `json:"organizationId"`
+	Limits         TenantQuota `json:"limits"`
+	Daily          QuotaUsage  `json:"daily"`
+	Monthly        QuotaUsage  `json:"monthly"`
+}
@@ -0,0 +1,56 @@
+package models
+
+// Built-in roles seeded on startup
+const (
+	RoleSubmitter = "submitter"
+	RoleReviewer  = "reviewer"
+	RoleAuditor   = "auditor"
+	RoleDPS       = "dps"
+	RoleAdmin     = "admin"
+)
+
+// Permissions checked by the router; the role grants are stored in Postgres
+const (
+	PermTransactionsAnalyze = "transactions:analyze"
+	PermTransactionsRead    = "transactions:read"
+	PermReportsExport       = "reports:export"
+	PermStatsRead           = "stats:read"
+	PermPurificationRead    = "purification:read"
+	PermPurificationRecord  = "purification:record"
+	PermZakatCalculate      = "zakat:calculate"
+	PermZakatConfigure      = "zakat:configure"
+	PermScreeningRead       = "screening:read"
+	PermScreeningManage     = "screening:manage"
+	PermReviewsRead         = "reviews:read"
+	PermReviewsDecide       = "reviews:decide"
+	PermApprovalsRead       = "approvals:read"
+	PermApprovalsDecide     = "approvals:decide"
+	PermApprovalsConfigure  = "approvals:configure"
+	PermAPIKeysManage       = "apikeys:manage"
+	PermRolesManage         = "roles:manage"
+	PermOrganizationManage  = "organization:manage"
+	PermUsageRead           = "usage:read"
+	PermUsageReadAll        = "usage:read_all"
+	PermPromptsManage       = "prompts:manage"
+	PermAnalysisOverride    = "analysis:override"
+	PermCalibrationRead     = "calibration:read"
+	PermCalibrationManage   = "calibration:manage"
+)
+
+// Role represents an RBAC role and the permissions it grants
+type Role struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+// Permission represents a permission that can be granted to roles
+type Permission struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// RolePermissionsRequest represents the API request to replace a role's permissions
+type RolePermissionsRequest struct {
+	Permissions []string `json:"permissions" binding:"required"`
+}
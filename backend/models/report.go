@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// CountByKey represents a count and total amount for a grouping key
+type CountByKey struct {
+	Key    string  `json:"key"`
+	Count  int     `json:"count"`
+	Amount float64 `json:"amount"`
+}
+
+// ComplianceReport represents the periodic Sharia compliance audit report
+type ComplianceReport struct {
+	From                    string           `json:"from"`
+	To                      string           `json:"to"`
+	GeneratedAt             time.Time        `json:"generatedAt"`
+	TotalTransactions       int              `json:"totalTransactions"`
+	AnalyzedTransactions    int              `json:"analyzedTransactions"`
+	TotalAmount             float64          `json:"totalAmount"`
+	ByStatus                []CountByKey     `json:"byStatus"`
+	ByViolationType         []CountByKey     `json:"byViolationType"`
+	WeightedComplianceScore float64          `json:"weightedComplianceScore"`
+	WeightedMaslahahScore   float64          `json:"weightedMaslahahScore"`
+	NonCompliant            []CombinedResult `json:"nonCompliant"`
+}
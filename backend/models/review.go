@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// Review states of a queued analysis
+const (
+	ReviewPending  = "pending"
+	ReviewAssigned = "assigned"
+	ReviewDecided  = "decided"
+)
+
+// ReviewDecision represents a Sharia reviewer's verdict, kept apart from the AI verdict
+type ReviewDecision struct {
+	Reviewer      string     `json:"reviewer"`
+	State         string     `json:"state"`
+	AssignedAt    *time.Time `json:"assignedAt,omitempty"`
+	Status        string     `json:"status,omitempty"`
+	ViolationType string     `json:"violationType,omitempty"`
+	Justification string     `json:"justification,omitempty"`
+	DecidedAt     *time.Time `json:"decidedAt,omitempty"`
+}
+
+// ReviewQueueItem represents an analysis awaiting human review
+type ReviewQueueItem struct {
+	CombinedResult
+	Priority    float64 `json:"priority"`
+	Uncertainty float64 `json:"uncertainty"`
+}
+
+// ReviewDecisionRequest represents the API request for a reviewer's decision. The
+// reviewer is the authenticated caller.
+type ReviewDecisionRequest struct {
+	Status        string `json:"status" binding:"required"`
+	ViolationType string `json:"violationType" binding:"required"`
+	Justification string `json:"justification" binding:"required,min=10"`
+}
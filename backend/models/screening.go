@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// IssuerRatios represents the financial ratios inputs of a listed issuer
+type IssuerRatios struct {
+	Ticker                    string    `json:"ticker"`
+	Name                      string    `json:"name"`
+	Period                    string    `json:"period"`
+	TotalAssets               float64   `json:"totalAssets"`
+	InterestBearingDebt       float64   `json:"interestBearingDebt"`
+	TotalRevenue              float64   `json:"totalRevenue"`
+	NonHalalIncome            float64   `json:"nonHalalIncome"`
+	CashAndInterestSecurities float64   `json:"cashAndInterestSecurities"`
+	MarketCap                 float64   `json:"marketCap"`
+	UpdatedAt                 time.Time `json:"updatedAt"`
+}
+
+// ScreeningMethodology represents the thresholds of a Sharia stock screening standard
+type ScreeningMethodology struct {
+	Name string `json:"name"`
+	// DebtDenominator is either "totalAssets" or "marketCap"
+	DebtDenominator        string  `json:"debtDenominator" binding:"required,oneof=totalAssets marketCap"`
+	MaxDebtRatio           float64 `json:"maxDebtRatio" binding:"gte=0,lte=1"`
+	MaxNonHalalIncomeRatio float64 `json:"maxNonHalalIncomeRatio" binding:"gte=0,lte=1"`
+	MaxCashSecuritiesRatio float64 `json:"maxCashSecuritiesRatio" binding:"gte=0,lte=1"`
+}
+
+// ScreeningRatio represents one evaluated financial ratio
+type ScreeningRatio struct {
+	Name      string  `json:"name"`
+	Value     float64 `json:"value"`
+	Threshold float64 `json:"threshold"`
+	Passed    bool    `json:"passed"`
+}
+
+// ScreeningResult represents the outcome of screening an issuer against a methodology
+type ScreeningResult struct {
+	Ticker      string           `json:"ticker"`
+	Name        string           `json:"name"`
+	Period      string           `json:"period"`
+	Methodology string           `json:"methodology"`
+	Compliant   bool             `json:"compliant"`
+	Ratios      []ScreeningRatio `json:"ratios"`
+}
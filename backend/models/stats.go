@@ -0,0 +1,40 @@
+package models
+
+// StatsFilter narrows the transactions included in the aggregate statistics
+type StatsFilter struct {
+	From   string
+	To     string
+	Bucket string
+}
+
+// StatsTotals represents overall transaction counts and amounts
+type StatsTotals struct {
+	Transactions int     `json:"transactions"`
+	Analyzed     int     `json:"analyzed"`
+	Amount       float64 `json:"amount"`
+}
+
+// StatsBucket represents aggregates for one time bucket
+type StatsBucket struct {
+	Bucket                 string  `json:"bucket"`
+	Transactions           int     `json:"transactions"`
+	Amount                 float64 `json:"amount"`
+	Compliant              int     `json:"compliant"`
+	NonCompliant           int     `json:"nonCompliant"`
+	NeedsReview            int     `json:"needsReview"`
+	AverageConfidenceScore float64 `json:"averageConfidenceScore"`
+	AverageMaslahahScore   float64 `json:"averageMaslahahScore"`
+}
+
+// StatsResponse represents the server-computed aggregates for the analysis dashboard
+type StatsResponse struct {
+	Totals                 StatsTotals         `json:"totals"`
+	ByStatus               []CountByKey        `json:"byStatus"`
+	ByViolationType        []CountByKey        `json:"byViolationType"`
+	ByType                 []CountByKey        `json:"byType"`
+	AverageConfidenceScore float64             `json:"averageConfidenceScore"`
+	AverageBreakdown       ComplianceBreakdown `json:"averageBreakdown"`
+	AverageMaslahah        MaslahahAnalysis    `json:"averageMaslahah"`
+	Bucket                 string              `json:"bucket"`
+	Series                 []StatsBucket       `json:"series"`
+}
@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+// TokenUsage reports the tokens consumed by one LLM call and their priced cost in USD
+type TokenUsage struct {
+	Model           string  `json:"model"`
+	PromptTokens    int64   `json:"promptTokens"`
+	CandidateTokens int64   `json:"candidateTokens"`
+	TotalTokens     int64   `json:"totalTokens"`
+	Cost            float64 `json:"cost"`
+	// Calls breaks down the usage of an ensemble analysis by model
+	Calls []TokenUsage `json:"calls,omitempty"`
+}
+
+// LLMCall is one recorded analysis call with the transactions it covered
+type LLMCall struct {
+	ID             int64  `json:"id"`
+	OrganizationID string `json:"organizationId"`
+	TokenUsage
+	TransactionIDs []string  `json:"transactionIds"`
+	Succeeded      bool      `json:"succeeded"`
+	CreatedAt      time.Time `json:"createdAt"`
+}
+
+// UsageFilter narrows the usage report; an empty OrganizationID covers every tenant
+type UsageFilter struct {
+	OrganizationID string
+	From           string
+	To             string
+}
+
+// UsageSummary aggregates LLM calls for one day, tenant and model
+type UsageSummary struct {
+	Date            string  `json:"date"`
+	OrganizationID  string  `json:"organizationId"`
+	Model           string  `json:"model"`
+	Calls           int64   `json:"calls"`
+	Transactions    int64   `json:"transactions"`
+	PromptTokens    int64   `json:"promptTokens"`
+	CandidateTokens int64   `json:"candidateTokens"`
+	TotalTokens     int64   `json:"totalTokens"`
+	Cost            float64 `json:"cost"`
+}
+
+// UsageReport is the response of GET /usage
+type UsageReport struct {
+	Currency string         `json:"currency"`
+	Rows     []UsageSummary `json:"rows"`
+	Totals   UsageSummary   `json:"totals"`
+}
@@ -0,0 +1,79 @@
+package models
+
+import "time"
+
+// Holding categories accepted by the zakat calculator
+const (
+	HoldingCash        = "cash"
+	HoldingGold        = "gold"
+	HoldingInventory   = "inventory"
+	HoldingReceivables = "receivables"
+	HoldingInvestments = "investments"
+)
+
+// HoldingInput represents an account balance or asset submitted for zakat
+type HoldingInput struct {
+	ID          string  `json:"id" binding:"required"`
+	Category    string  `json:"category" binding:"required,oneof=cash gold inventory receivables investments"`
+	Description string  `json:"description,omitempty"`
+	Amount      float64 `json:"amount" binding:"gte=0"`
+	// Grams is used instead of Amount for gold holdings
+	Grams float64 `json:"grams,omitempty" binding:"gte=0"`
+	// AcquiredDate marks the start of haul when no transaction history is referenced
+	AcquiredDate string `json:"acquiredDate,omitempty"`
+	// TransactionIDs links the holding to analyzed transactions for haul and non-halal tracking
+	TransactionIDs []string `json:"transactionIds,omitempty"`
+	// Collectible marks receivables that are expected to be repaid (default true)
+	Collectible *bool `json:"collectible,omitempty"`
+}
+
+// ZakatRequest represents the API request for zakat calculation
+type ZakatRequest struct {
+	AsOf        string         `json:"asOf"`
+	Holdings    []HoldingInput `json:"holdings" binding:"required,min=1,dive"`
+	Liabilities float64        `json:"liabilities" binding:"gte=0"`
+}
+
+// ZakatHoldingResult represents the zakat assessment of a single holding
+type ZakatHoldingResult struct {
+	ID             string  `json:"id"`
+	Category       string  `json:"category"`
+	Description    string  `json:"description,omitempty"`
+	Value          float64 `json:"value"`
+	NonHalalAmount float64 `json:"nonHalalAmount"`
+	ZakatableValue float64 `json:"zakatableValue"`
+	HaulStart      string  `json:"haulStart,omitempty"`
+	HaulDays       int     `json:"haulDays"`
+	HaulComplete   bool    `json:"haulComplete"`
+	Zakat          float64 `json:"zakat"`
+	Note           string  `json:"note,omitempty"`
+}
+
+// ZakatCalculation represents the zakat maal breakdown
+type ZakatCalculation struct {
+	AsOf             string               `json:"asOf"`
+	GoldPricePerGram float64              `json:"goldPricePerGram"`
+	NisabGrams       float64              `json:"nisabGrams"`
+	NisabValue       float64              `json:"nisabValue"`
+	Rate             float64              `json:"rate"`
+	TotalValue       float64              `json:"totalValue"`
+	TotalNonHalal    float64              `json:"totalNonHalal"`
+	Liabilities      float64              `json:"liabilities"`
+	NetZakatable     float64              `json:"netZakatable"`
+	MeetsNisab       bool                 `json:"meetsNisab"`
+	ZakatDue         float64              `json:"zakatDue"`
+	Holdings         []ZakatHoldingResult `json:"holdings"`
+}
+
+// GoldPriceInput represents the gold price per gram entered by an admin
+type GoldPriceInput struct {
+	PricePerGram  float64 `json:"pricePerGram" binding:"required,gt=0"`
+	EffectiveDate string  `json:"effectiveDate" binding:"required"`
+}
+
+// GoldPrice represents a stored gold price used for nisab
+type GoldPrice struct {
+	ID int64 `json:"id"`
+	GoldPriceInput
+	CreatedAt time.Time `json:"createdAt"`
+}
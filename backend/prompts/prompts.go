@@ -0,0 +1,179 @@
+// Package prompts loads the text/template prompt templates sent to the analyzer.
+// Defaults are embedded in the binary; files in an override directory replace
+// templates of the same name. Every template is versioned by a hash of its text.
+//
+// A template named "<name>.<language>", e.g. system.en.tmpl, is used instead of
+// "<name>" for analyses in that language.
+package prompts
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"halalguard-backend/models"
+)
+
+// Template names used by the analyzer
+const (
+	SystemTemplate   = "system"
+	AnalysisTemplate = "analysis"
+)
+
+// SourceEmbedded marks templates compiled into the binary
+const SourceEmbedded = "embedded"
+
+//go:embed templates/*.tmpl
+var embedded embed.FS
+
+var funcs = template.FuncMap{"join": strings.Join}
+
+// Data is the input of the prompt templates
+type Data struct {
+	// Language is the name of the language reasoning is written in
+	Language string
+	// LanguageCode is the code of that language (id, en, ar); it selects language-specific templates
+	LanguageCode string
+	// RulePacks are the fatwa/standard references configured for the tenant
+	RulePacks []string
+	// Transactions is the JSON array of transactions to analyze
+	Transactions string
+}
+
+// Rendered is a system instruction and prompt ready to send to a model
+type Rendered struct {
+	System string
+	Prompt string
+	// Version identifies the templates used, e.g. "system@1a2b3c4d+analysis@5e6f7a8b"
+	Version string
+}
+
+type promptTemplate struct {
+	name    string
+	version string
+	source  string
+	text    string
+	tmpl    *template.Template
+}
+
+// Set is a loaded set of prompt templates
+type Set struct {
+	templates map[string]*promptTemplate
+}
+
+// Load parses the embedded templates and, if dir is not empty, the *.tmpl files in
+// dir, which replace embedded templates with the same name
+func Load(dir string) (*Set, error) {
+	set := &Set{templates: make(map[string]*promptTemplate)}
+
+	files, err := fs.Glob(embedded, "templates/*.tmpl")
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		text, err := embedded.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		if err := set.add(file, SourceEmbedded, string(text)); err != nil {
+			return nil, err
+		}
+	}
+
+	if dir == "" {
+		return set, nil
+	}
+	files, err = filepath.Glob(filepath.Join(dir, "*.tmpl"))
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		text, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read prompt template: %w", err)
+		}
+		if err := set.add(file, file, string(text)); err != nil {
+			return nil, err
+		}
+	}
+	return set, nil
+}
+
+func (s *Set) add(file, source, text string) error {
+	name := strings.TrimSuffix(filepath.Base(file), ".tmpl")
+	tmpl, err := template.New(name).Funcs(funcs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return fmt.Errorf("failed to parse prompt template %s: %w", file, err)
+	}
+	sum := sha256.Sum256([]byte(text))
+	s.templates[name] = &promptTemplate{
+		name:    name,
+		version: name + "@" + hex.EncodeToString(sum[:4]),
+		source:  source,
+		text:    text,
+		tmpl:    tmpl,
+	}
+	return nil
+}
+
+// List returns the loaded templates sorted by name
+func (s *Set) List() []models.PromptTemplate {
+	list := make([]models.PromptTemplate, 0, len(s.templates))
+	for _, t := range s.templates {
+		list = append(list, models.PromptTemplate{Name: t.name, Version: t.version, Source: t.source, Text: t.text})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// lookup returns the variant of a template for the language, falling back to the template itself
+func (s *Set) lookup(name, language string) (*promptTemplate, bool) {
+	if t, ok := s.templates[name+"."+language]; ok && language != "" {
+		return t, true
+	}
+	t, ok := s.templates[name]
+	return t, ok
+}
+
+// Version identifies the templates Render uses for the language
+func (s *Set) Version(language string) string {
+	var versions []string
+	for _, name := range []string{SystemTemplate, AnalysisTemplate} {
+		if t, ok := s.lookup(name, language); ok {
+			versions = append(versions, t.version)
+		}
+	}
+	return strings.Join(versions, "+")
+}
+
+// Render executes the system and analysis templates for data.LanguageCode
+func (s *Set) Render(data Data) (*Rendered, error) {
+	var rendered Rendered
+	for _, part := range []struct {
+		name string
+		out  *string
+	}{
+		{SystemTemplate, &rendered.System},
+		{AnalysisTemplate, &rendered.Prompt},
+	} {
+		t, ok := s.lookup(part.name, data.LanguageCode)
+		if !ok {
+			return nil, fmt.Errorf("prompt template %q not found", part.name)
+		}
+		var buf bytes.Buffer
+		if err := t.tmpl.Execute(&buf, data); err != nil {
+			return nil, fmt.Errorf("failed to render prompt template %s: %w", t.version, err)
+		}
+		*part.out = strings.TrimSpace(buf.String())
+	}
+	rendered.Version = s.Version(data.LanguageCode)
+	return &rendered, nil
+}
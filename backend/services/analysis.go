@@ -0,0 +1,126 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"halalguard-backend/config"
+	"halalguard-backend/metrics"
+	"halalguard-backend/models"
+)
+
+// AnalysisPipeline checks approval locks and quotas, screens, analyzes and stores a
+// batch of transactions. It is shared by POST /analyze and the analysis job workers.
+type AnalysisPipeline struct {
+	cfg      *config.Config
+	analyzer Analyzer
+}
+
+// NewAnalysisPipeline creates the analysis pipeline
+func NewAnalysisPipeline(cfg *config.Config, analyzer Analyzer) *AnalysisPipeline {
+	return &AnalysisPipeline{cfg: cfg, analyzer: analyzer}
+}
+
+// Run analyzes the transactions of an organization's request. It fails with ErrOrganizationNotFound,
+// ErrTransactionLocked, a *QuotaExceededError or the analyzer's error; quota reserved
+// for a failed analysis is released even if ctx was cancelled.
+func (p *AnalysisPipeline) Run(ctx context.Context, orgID string, req models.AnalyzeRequest) (*models.AnalyzeResponse, error) {
+	transactions := req.Transactions
+	org, err := GetOrganization(orgID)
+	if err != nil {
+		return nil, err
+	}
+	settings := org.TenantSettings
+	if req.Language != "" {
+		settings.Language = req.Language
+	}
+
+	// Approved transactions are locked against re-analysis
+	ids := make([]string, len(transactions))
+	for i, tx := range transactions {
+		ids[i] = tx.ID
+	}
+	locked, err := GetLockedTransactionIDs(ctx, orgID, ids)
+	if err != nil {
+		return nil, err
+	}
+	if len(locked) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrTransactionLocked, strings.Join(locked, ", "))
+	}
+
+	// Count the batch against the tenant's Gemini quotas
+	if err := ReserveTransactions(ctx, orgID, len(transactions)); err != nil {
+		return nil, err
+	}
+
+	// Save transactions to database. An approval completing since the check above
+	// locks its transaction, so the transaction keeps the data that was approved.
+	for _, tx := range transactions {
+		if err := SaveTransaction(ctx, orgID, tx); err != nil {
+			slog.WarnContext(ctx, "failed to save transaction", "transaction_id", tx.ID, "error", err)
+		}
+	}
+
+	// Screen investment transactions referencing known issuers
+	screenings, err := ScreenTransactions(orgID, transactions, p.cfg.ScreeningMethodology)
+	if err != nil {
+		slog.WarnContext(ctx, "stock screening failed", "error", err)
+	}
+
+	// Analyze transactions with the configured model provider. Usage bookkeeping must outlive a
+	// cancelled request or a worker stopped mid-call.
+	results, usage, err := p.analyzer.AnalyzeTransactions(ctx, transactions, screenings, settings, req.Generation)
+	bookkeeping := context.WithoutCancel(ctx)
+	if usage.TotalTokens > 0 {
+		usage = PriceTokenUsage(usage, p.cfg.LLMPrices)
+		// An ensemble is recorded as one call per model so costs stay per model
+		calls := usage.Calls
+		if len(calls) == 0 {
+			calls = []models.TokenUsage{usage}
+		}
+		for _, call := range calls {
+			if call.TotalTokens == 0 {
+				continue
+			}
+			if err := RecordLLMCall(bookkeeping, orgID, call, ids, err == nil); err != nil {
+				slog.WarnContext(ctx, "failed to record LLM usage", "model", call.Model, "error", err)
+			}
+		}
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "analysis failed", "organization_id", orgID, "transactions", len(transactions), "error", err)
+		if err := ReleaseTransactions(bookkeeping, orgID, len(transactions)); err != nil {
+			slog.WarnContext(ctx, "failed to release quota", "error", err)
+		}
+		return nil, err
+	}
+
+	ApplyScreening(results, screenings)
+	if err := ApplyCalibration(bookkeeping, orgID, results); err != nil {
+		slog.WarnContext(ctx, "confidence calibration failed, keeping raw scores", "error", err)
+	}
+	metrics.ObserveAnalysisResults(results)
+
+	// Save analysis results to database. A transaction whose approval completed during
+	// the analysis keeps its approved verdict, and the new one is left out of the response.
+	saved := results[:0]
+	for _, result := range results {
+		err := SaveAnalysisResult(bookkeeping, orgID, result)
+		if errors.Is(err, ErrTransactionLocked) {
+			slog.WarnContext(ctx, "transaction was approved during analysis, discarding result", "transaction_id", result.TransactionID)
+			continue
+		}
+		if err != nil {
+			slog.WarnContext(ctx, "failed to save analysis result", "transaction_id", result.TransactionID, "error", err)
+		}
+		saved = append(saved, result)
+	}
+	results = saved
+	slog.InfoContext(ctx, "analysis completed", "organization_id", orgID,
+		"transactions", len(transactions), "results", len(results), "model", usage.Model, "language", AnalysisLanguage(settings.Language), "tokens", usage.TotalTokens)
+
+	return &models.AnalyzeResponse{Results: results, Usage: usage}, nil
+}
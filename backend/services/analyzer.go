@@ -0,0 +1,550 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"strings"
+	"time"
+
+	"halalguard-backend/config"
+	"halalguard-backend/metrics"
+	"halalguard-backend/models"
+	"halalguard-backend/prompts"
+	"halalguard-backend/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Model providers selectable with LLM_PROVIDER
+const (
+	ProviderGemini = "gemini"
+	// ProviderOpenAI is any OpenAI-compatible chat completions endpoint, e.g. vLLM or llama.cpp
+	ProviderOpenAI = "openai"
+	ProviderOllama = "ollama"
+)
+
+// errEmptyResponse is returned when a provider answers without any content
+var errEmptyResponse = errors.New("empty response from AI")
+
+// languageNames maps supported analysis languages to the name used in the prompt
+var languageNames = map[string]string{
+	models.LanguageIndonesian: "Bahasa Indonesia",
+	models.LanguageEnglish:    "English",
+	models.LanguageArabic:     "Arabic",
+}
+
+// Analyzer classifies transactions with a language model. Every provider shares the
+// prompt templates, analysis cache, retries, circuit breaker and result validation.
+type Analyzer interface {
+	// AnalyzeTransactions analyzes transactions with the tenant's model, language and
+	// rule packs, and the configured sampling parameters unless override replaces them
+	AnalyzeTransactions(ctx context.Context, transactions []models.TransactionInput, screenings map[string]models.ScreeningResult, settings models.TenantSettings, override *models.GenerationOverride) ([]models.AnalysisResult, models.TokenUsage, error)
+	// GenerationSettings resolves the model and sampling parameters of an analysis
+	GenerationSettings(tenantModel string, override *models.GenerationOverride) models.GenerationSettings
+	// Templates lists the prompt templates
+	Templates() []models.PromptTemplate
+	// PreviewPrompt renders the prompt for the transactions without calling the model
+	PreviewPrompt(transactions []models.TransactionInput, settings models.TenantSettings) (*models.PromptPreview, error)
+	// Status reports recent model latency, error rate and the circuit breaker state
+	Status() models.LLMStatus
+	Close()
+}
+
+// completionBackend sends rendered prompts to one model provider
+type completionBackend interface {
+	// provider names the backend in traces and status, e.g. "gemini"
+	provider() string
+	// complete makes one call and returns the response text and its token usage
+	complete(ctx context.Context, generation models.GenerationSettings, rendered *prompts.Rendered) (string, models.TokenUsage, error)
+	// retryable reports whether an error of complete is transient
+	retryable(err error) bool
+	// serviceFailure reports whether an error says anything about the provider's availability
+	serviceFailure(err error) bool
+	// errorReason labels an error for the llm_errors_total metric
+	errorReason(err error) string
+	close()
+}
+
+// llmAnalyzer implements Analyzer on top of a completion backend
+type llmAnalyzer struct {
+	backend completionBackend
+	cfg     config.GeminiConfig
+	cache   *analysisCache
+	breaker *circuitBreaker
+	prompts *prompts.Set
+	// defaults are the configured model and sampling parameters
+	defaults models.GenerationSettings
+}
+
+// NewAnalyzer creates the Analyzer of the configured provider, rendering its prompts
+// from templates. With ENSEMBLE_MODELS or ENSEMBLE_SAMPLES it votes over several answers.
+func NewAnalyzer(cfg *config.Config, templates *prompts.Set) (Analyzer, error) {
+	defaults := generationDefaults(cfg.Gemini)
+
+	var backend completionBackend
+	switch cfg.LLMProvider {
+	case ProviderGemini:
+		gemini, err := newGeminiBackend(cfg.GeminiAPIKey)
+		if err != nil {
+			return nil, err
+		}
+		backend = gemini
+	case ProviderOpenAI:
+		backend = newOpenAIBackend(cfg.OpenAI)
+		defaults.Model = cfg.OpenAI.Model
+	case ProviderOllama:
+		backend = newOllamaBackend(cfg.Ollama)
+		defaults.Model = cfg.Ollama.Model
+	default:
+		return nil, fmt.Errorf("unknown LLM provider %q", cfg.LLMProvider)
+	}
+
+	analyzer := &llmAnalyzer{
+		backend:  backend,
+		cfg:      cfg.Gemini,
+		cache:    newAnalysisCache(cfg.Gemini.CacheSize, cfg.Gemini.CacheTTL),
+		breaker:  newCircuitBreaker(cfg.Gemini.CircuitThreshold, cfg.Gemini.CircuitCooldown),
+		prompts:  templates,
+		defaults: defaults,
+	}
+	if cfg.Ensemble.Enabled() {
+		return &ensembleAnalyzer{llmAnalyzer: analyzer, cfg: cfg.Ensemble}, nil
+	}
+	return analyzer, nil
+}
+
+// generationDefaults converts the validated configuration to generation settings
+func generationDefaults(cfg config.GeminiConfig) models.GenerationSettings {
+	settings := models.GenerationSettings{
+		Model:           cfg.Model,
+		Temperature:     float32(cfg.Temperature),
+		TopP:            float32(cfg.TopP),
+		TopK:            int32(cfg.TopK),
+		MaxOutputTokens: int32(cfg.MaxOutputTokens),
+	}
+	if cfg.Seed != nil {
+		seed := int32(*cfg.Seed)
+		settings.Seed = &seed
+	}
+	return settings
+}
+
+// GenerationSettings resolves the settings an analysis runs with: the configured
+// defaults, then the tenant's model, then a per-request override
+func (a *llmAnalyzer) GenerationSettings(tenantModel string, override *models.GenerationOverride) models.GenerationSettings {
+	settings := a.defaults
+	if tenantModel != "" {
+		settings.Model = tenantModel
+	}
+	return override.Apply(settings)
+}
+
+// promptTransaction is a transaction as presented to the model, with optional screening context
+type promptTransaction struct {
+	models.TransactionInput
+	Screening *models.ScreeningResult `json:"shariaScreening,omitempty"`
+}
+
+// AnalyzeTransactions analyzes transactions with the tenant's model, language and rule
+// packs, and the configured sampling parameters unless override replaces them.
+// Screening results, keyed by transaction ID, are passed to the model as additional
+// context. Transactions analyzed before with the same settings are served from the
+// analysis cache; the token usage reported by the provider for the rest is returned
+// alongside the results. The request ID of ctx is forwarded to the provider.
+func (a *llmAnalyzer) AnalyzeTransactions(ctx context.Context, transactions []models.TransactionInput, screenings map[string]models.ScreeningResult, settings models.TenantSettings, override *models.GenerationOverride) ([]models.AnalysisResult, models.TokenUsage, error) {
+	generation := a.GenerationSettings(settings.Model, override)
+	modelName := generation.Model
+	usage := models.TokenUsage{Model: modelName}
+
+	if len(transactions) == 0 {
+		return []models.AnalysisResult{}, usage, nil
+	}
+
+	language := AnalysisLanguage(settings.Language)
+	promptVersion := a.prompts.Version(language)
+
+	// Build prompt input, skipping transactions with a cached result
+	cached := make(map[string]models.AnalysisResult)
+	keys := make(map[string]string)
+	var input []promptTransaction
+	for _, tx := range transactions {
+		item := promptTransaction{TransactionInput: tx}
+		if screening, ok := screenings[tx.ID]; ok {
+			item.Screening = &screening
+		}
+		key := analysisCacheKey(a.backend.provider(), generation, language, promptVersion, settings.RulePacks, item)
+		if result, ok := a.cache.get(key); ok {
+			cached[tx.ID] = result
+			continue
+		}
+		keys[tx.ID] = key
+		input = append(input, item)
+	}
+	if len(input) == 0 {
+		return mergeResults(transactions, cached, nil), usage, nil
+	}
+
+	rendered, err := renderPrompt(a.prompts, input, language, settings.RulePacks)
+	if err != nil {
+		return nil, usage, err
+	}
+
+	responseText, callUsage, err := a.generateContent(ctx, generation, rendered, len(input))
+	usage.PromptTokens = callUsage.PromptTokens
+	usage.CandidateTokens = callUsage.CandidateTokens
+	usage.TotalTokens = callUsage.TotalTokens
+	if usage.TotalTokens > 0 {
+		metrics.ObserveTokens(usage)
+	}
+	if err != nil {
+		metrics.LLMErrors.WithLabelValues(a.backend.provider(), modelName, a.errorReason(err)).Inc()
+		if errors.Is(err, errEmptyResponse) {
+			return nil, usage, err
+		}
+		return nil, usage, fmt.Errorf("failed to generate content: %w", err)
+	}
+
+	_, span := tracing.Tracer().Start(ctx, a.backend.provider()+".ParseResponse", trace.WithAttributes(
+		attribute.Int("halalguard.response_bytes", len(responseText)),
+	))
+	results, err := parseResults(responseText)
+	span.SetAttributes(attribute.Int("halalguard.result_count", len(results)))
+	endSpan(span, err)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to parse AI response", "model", modelName, "error", err, "ai_response", responseText)
+		metrics.LLMErrors.WithLabelValues(a.backend.provider(), modelName, "invalid_json").Inc()
+		return nil, usage, fmt.Errorf("failed to parse AI response: %w", err)
+	}
+	results = validateResults(ctx, input, results)
+
+	for i := range results {
+		results[i].Language = language
+		results[i].PromptVersion = rendered.Version
+		results[i].Generation = &generation
+		if key, ok := keys[results[i].TransactionID]; ok {
+			a.cache.put(key, results[i])
+		}
+	}
+
+	return mergeResults(transactions, cached, results), usage, nil
+}
+
+// Templates lists the prompt templates used by the analyzer
+func (a *llmAnalyzer) Templates() []models.PromptTemplate {
+	return a.prompts.List()
+}
+
+// PreviewPrompt renders the prompt that would be sent for the transactions, without calling the model
+func (a *llmAnalyzer) PreviewPrompt(transactions []models.TransactionInput, settings models.TenantSettings) (*models.PromptPreview, error) {
+	input := make([]promptTransaction, len(transactions))
+	for i, tx := range transactions {
+		input[i] = promptTransaction{TransactionInput: tx}
+	}
+	rendered, err := renderPrompt(a.prompts, input, AnalysisLanguage(settings.Language), settings.RulePacks)
+	if err != nil {
+		return nil, err
+	}
+	return &models.PromptPreview{Version: rendered.Version, SystemInstruction: rendered.System, Prompt: rendered.Prompt}, nil
+}
+
+// IsSupportedLanguage reports whether reasoning can be written in the language
+func IsSupportedLanguage(language string) bool {
+	_, ok := languageNames[language]
+	return ok
+}
+
+// AnalysisLanguage returns the language code an analysis runs in, defaulting to Indonesian
+func AnalysisLanguage(language string) string {
+	if IsSupportedLanguage(language) {
+		return language
+	}
+	return models.LanguageIndonesian
+}
+
+// renderPrompt fills the prompt templates for the language code with the transactions to analyze
+func renderPrompt(templates *prompts.Set, input []promptTransaction, language string, rulePacks []string) (*prompts.Rendered, error) {
+	transactionsJSON, err := json.Marshal(input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal transactions: %w", err)
+	}
+	return templates.Render(prompts.Data{
+		Language:     languageNames[language],
+		LanguageCode: language,
+		RulePacks:    rulePacks,
+		Transactions: string(transactionsJSON),
+	})
+}
+
+// generateContent calls the provider, retrying transient errors with exponential backoff.
+// Calls are refused with ErrCircuitOpen while the circuit breaker is open.
+// chunkSize is the number of transactions in the prompt, recorded on the trace span.
+func (a *llmAnalyzer) generateContent(ctx context.Context, generation models.GenerationSettings, rendered *prompts.Rendered, chunkSize int) (string, models.TokenUsage, error) {
+	if err := a.breaker.allow(); err != nil {
+		return "", models.TokenUsage{}, err
+	}
+
+	start := time.Now()
+	text, usage, err := a.generateWithRetry(ctx, generation, rendered, chunkSize)
+	metrics.RecentLLM.Observe(time.Since(start), err != nil)
+
+	// Request-specific failures say nothing about the provider's availability
+	if err == nil || a.serviceFailure(err) {
+		a.breaker.record(err)
+	} else {
+		a.breaker.record(nil)
+	}
+	return text, usage, err
+}
+
+// generateWithRetry calls the provider until it succeeds, fails permanently or runs out
+// of retries. Each attempt is traced as its own span.
+func (a *llmAnalyzer) generateWithRetry(ctx context.Context, generation models.GenerationSettings, rendered *prompts.Rendered, chunkSize int) (string, models.TokenUsage, error) {
+	provider := a.backend.provider()
+	modelName := generation.Model
+	backoff := a.cfg.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		attemptCtx, span := tracing.Tracer().Start(ctx, provider+".GenerateContent", trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+			attribute.String("gen_ai.system", provider),
+			attribute.String("gen_ai.request.model", modelName),
+			attribute.Float64("gen_ai.request.temperature", float64(generation.Temperature)),
+			attribute.Float64("gen_ai.request.top_p", float64(generation.TopP)),
+			attribute.Int("gen_ai.request.top_k", int(generation.TopK)),
+			attribute.Int("gen_ai.request.max_tokens", int(generation.MaxOutputTokens)),
+			attribute.Int("halalguard.chunk_size", chunkSize),
+			attribute.Int("halalguard.attempt", attempt+1),
+		))
+		start := time.Now()
+		text, usage, err := a.backend.complete(attemptCtx, generation, rendered)
+		outcome := "success"
+		if err != nil {
+			outcome = "error"
+		}
+		metrics.LLMRequestDuration.WithLabelValues(provider, modelName, outcome).Observe(time.Since(start).Seconds())
+		if err == nil {
+			span.SetAttributes(
+				attribute.Int("gen_ai.usage.input_tokens", int(usage.PromptTokens)),
+				attribute.Int("gen_ai.usage.output_tokens", int(usage.CandidateTokens)),
+				attribute.Int("gen_ai.usage.total_tokens", int(usage.TotalTokens)),
+			)
+		}
+		endSpan(span, err)
+
+		if err == nil || attempt >= a.cfg.MaxRetries || !a.backend.retryable(err) {
+			return text, usage, err
+		}
+
+		// Jitter spreads out retries from concurrent batches
+		delay := backoff + time.Duration(rand.Int63n(int64(backoff)/2+1))
+		slog.WarnContext(ctx, "model call failed, retrying", "provider", provider,
+			"model", modelName, "attempt", attempt+1, "max_attempts", a.cfg.MaxRetries+1, "delay", delay, "error", err)
+		metrics.LLMRetries.WithLabelValues(provider, modelName).Inc()
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return "", models.TokenUsage{}, ctx.Err()
+		}
+		backoff *= 2
+	}
+}
+
+// serviceFailure reports whether an error counts against the circuit breaker
+func (a *llmAnalyzer) serviceFailure(err error) bool {
+	if errors.Is(err, errEmptyResponse) {
+		return false
+	}
+	return a.backend.serviceFailure(err)
+}
+
+// errorReason labels a failed call for the llm_errors_total metric
+func (a *llmAnalyzer) errorReason(err error) string {
+	switch {
+	case errors.Is(err, ErrCircuitOpen):
+		return "circuit_open"
+	case errors.Is(err, errEmptyResponse):
+		return "empty_response"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "deadline_exceeded"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	}
+	return a.backend.errorReason(err)
+}
+
+// endSpan records err, if any, on the span and ends it
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(otelcodes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Status reports recent latency, error rate and the circuit breaker state of the provider
+func (a *llmAnalyzer) Status() models.LLMStatus {
+	state, lastSuccessAt, lastFailureAt, lastError := a.breaker.status()
+	return models.LLMStatus{
+		Provider:       a.backend.provider(),
+		LatencySummary: metrics.RecentLLM.Summary(),
+		Circuit:        state,
+		LastSuccessAt:  lastSuccessAt,
+		LastFailureAt:  lastFailureAt,
+		LastError:      lastError,
+	}
+}
+
+// Close releases the provider's connections
+func (a *llmAnalyzer) Close() {
+	a.backend.close()
+}
+
+// parseResults decodes the model's JSON answer. Besides the requested array it accepts
+// an object wrapping the array, such as {"results": [...]}, which chat models in JSON
+// mode tend to return, and a Markdown code fence around the JSON.
+func parseResults(text string) ([]models.AnalysisResult, error) {
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(text, "```") {
+		text = strings.TrimPrefix(text, "```json")
+		text = strings.TrimPrefix(text, "```")
+		text = strings.TrimSpace(strings.TrimSuffix(text, "```"))
+	}
+
+	var results []models.AnalysisResult
+	err := json.Unmarshal([]byte(text), &results)
+	if err == nil {
+		return results, nil
+	}
+
+	var wrapper map[string]json.RawMessage
+	if json.Unmarshal([]byte(text), &wrapper) != nil {
+		return nil, err
+	}
+	if _, ok := wrapper["transactionId"]; ok {
+		var result models.AnalysisResult
+		if err := json.Unmarshal([]byte(text), &result); err != nil {
+			return nil, err
+		}
+		return []models.AnalysisResult{result}, nil
+	}
+	for _, value := range wrapper {
+		if json.Unmarshal(value, &results) == nil {
+			return results, nil
+		}
+	}
+	return nil, err
+}
+
+// validateResults keeps one result per requested transaction and makes every result
+// safe to store: scores are clamped to their ranges, and a result with an unknown
+// status or violation type is sent to human review instead of being trusted.
+func validateResults(ctx context.Context, input []promptTransaction, results []models.AnalysisResult) []models.AnalysisResult {
+	requested := make(map[string]bool, len(input))
+	for _, tx := range input {
+		requested[tx.ID] = true
+	}
+
+	valid := make([]models.AnalysisResult, 0, len(results))
+	seen := make(map[string]bool, len(results))
+	for _, result := range results {
+		if !requested[result.TransactionID] || seen[result.TransactionID] {
+			slog.WarnContext(ctx, "dropping AI result for unexpected transaction", "transaction_id", result.TransactionID)
+			continue
+		}
+		seen[result.TransactionID] = true
+
+		if !validStatuses[result.Status] || !validViolationTypes[result.ViolationType] {
+			slog.WarnContext(ctx, "AI result has an unknown status or violation type, queuing for review",
+				"transaction_id", result.TransactionID, "status", result.Status, "violation_type", result.ViolationType)
+			result.Status = models.StatusNeedsReview
+			if !validViolationTypes[result.ViolationType] {
+				result.ViolationType = models.ViolationSyubhat
+			}
+		}
+		result.ConfidenceScore = clamp(result.ConfidenceScore, 0, 100)
+		result.Breakdown.RibaScore = clamp(result.Breakdown.RibaScore, 0, 1)
+		result.Breakdown.GhararScore = clamp(result.Breakdown.GhararScore, 0, 1)
+		result.Breakdown.MaysirScore = clamp(result.Breakdown.MaysirScore, 0, 1)
+		result.Breakdown.HalalScore = clamp(result.Breakdown.HalalScore, 0, 1)
+		result.Breakdown.JusticeScore = clamp(result.Breakdown.JusticeScore, 0, 1)
+		if m := result.MaslahahAnalysis; m != nil {
+			m.TotalScore = clamp(m.TotalScore, 0, 100)
+			m.Breakdown.EconomicJustice = clamp(m.Breakdown.EconomicJustice, 0, 100)
+			m.Breakdown.CommunityDevelopment = clamp(m.Breakdown.CommunityDevelopment, 0, 100)
+			m.Breakdown.EducationalImpact = clamp(m.Breakdown.EducationalImpact, 0, 100)
+			m.Breakdown.Environmental = clamp(m.Breakdown.Environmental, 0, 100)
+			m.Breakdown.SocialCohesion = clamp(m.Breakdown.SocialCohesion, 0, 100)
+		}
+		valid = append(valid, result)
+	}
+	return valid
+}
+
+var validStatuses = map[string]bool{
+	models.StatusCompliant:    true,
+	models.StatusNonCompliant: true,
+	models.StatusNeedsReview:  true,
+}
+
+var validViolationTypes = map[string]bool{
+	models.ViolationRiba:    true,
+	models.ViolationGharar:  true,
+	models.ViolationMaysir:  true,
+	models.ViolationHalal:   true,
+	models.ViolationSyubhat: true,
+}
+
+func clamp(value, lo, hi float64) float64 {
+	return max(lo, min(value, hi))
+}
+
+// analysisCacheKey identifies a transaction analyzed by the provider with the given
+// generation settings, language, prompt templates and rule packs
+func analysisCacheKey(provider string, generation models.GenerationSettings, language, promptVersion string, rulePacks []string, tx promptTransaction) string {
+	data, _ := json.Marshal(struct {
+		Provider      string
+		Generation    models.GenerationSettings
+		Language      string
+		PromptVersion string
+		RulePacks     []string
+		Transaction   promptTransaction
+	}{provider, generation, language, promptVersion, rulePacks, tx})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// mergeResults orders cached and freshly generated results by the input transactions,
+// keeping any generated result whose ID was not in the input at the end
+func mergeResults(transactions []models.TransactionInput, cached map[string]models.AnalysisResult, generated []models.AnalysisResult) []models.AnalysisResult {
+	if len(cached) == 0 {
+		return generated
+	}
+
+	byID := make(map[string]models.AnalysisResult, len(generated))
+	for _, result := range generated {
+		byID[result.TransactionID] = result
+	}
+
+	results := make([]models.AnalysisResult, 0, len(transactions))
+	seen := make(map[string]bool, len(transactions))
+	for _, tx := range transactions {
+		seen[tx.ID] = true
+		if result, ok := cached[tx.ID]; ok {
+			results = append(results, result)
+		} else if result, ok := byID[tx.ID]; ok {
+			results = append(results, result)
+		}
+	}
+	for _, result := range generated {
+		if !seen[result.TransactionID] {
+			results = append(results, result)
+		}
+	}
+	return results
+}
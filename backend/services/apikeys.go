@@ -0,0 +1,140 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"halalguard-backend/database"
+	"halalguard-backend/models"
+)
+
+// apiKeyPrefix marks HalalGuard API keys so they are recognisable in logs and secret scanners
+const apiKeyPrefix = "hg_"
+
+var (
+	// ErrInvalidAPIKey is returned for unknown, malformed or revoked API keys
+	ErrInvalidAPIKey = errors.New("invalid API key")
+	// ErrAPIKeyNotFound is returned when revoking an unknown API key
+	ErrAPIKeyNotFound = errors.New("API key not found")
+)
+
+// HashAPIKey returns the hex SHA-256 digest stored in place of the key.
+// Keys carry 256 bits of randomness, so a fast hash is sufficient.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateAPIKey issues a new API key for an organization. The plaintext key is returned once and never stored.
+func CreateAPIKey(orgID, name, role string) (*models.CreateAPIKeyResponse, error) {
+	if _, err := GetOrganization(orgID); err != nil {
+		return nil, err
+	}
+	exists, err := RoleExists(role)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrRoleNotFound, role)
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate API key: %w", err)
+	}
+	key := apiKeyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+
+	resp := &models.CreateAPIKeyResponse{
+		APIKey: models.APIKey{OrganizationID: orgID, Name: name, Prefix: key[:len(apiKeyPrefix)+8], Role: role},
+		Key:    key,
+	}
+
+	query := `
+		INSERT INTO api_keys (organization_id, name, prefix, key_hash, role)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`
+	if err := database.DB.QueryRow(query, orgID, name, resp.Prefix, HashAPIKey(key), role).Scan(&resp.ID, &resp.CreatedAt); err != nil {
+		return nil, fmt.Errorf("failed to save API key: %w", err)
+	}
+
+	return resp, nil
+}
+
+// GetAPIKeys lists an organization's API keys without their secrets
+func GetAPIKeys(orgID string) ([]models.APIKey, error) {
+	rows, err := database.DB.Query(`
+		SELECT id, organization_id, name, prefix, role, created_at, last_used_at, revoked_at
+		FROM api_keys
+		WHERE organization_id = $1
+		ORDER BY created_at DESC
+	`, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query API keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []models.APIKey{}
+	for rows.Next() {
+		var key models.APIKey
+		var lastUsedAt, revokedAt sql.NullTime
+		if err := rows.Scan(&key.ID, &key.OrganizationID, &key.Name, &key.Prefix, &key.Role, &key.CreatedAt, &lastUsedAt, &revokedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan API key: %w", err)
+		}
+		if lastUsedAt.Valid {
+			key.LastUsedAt = &lastUsedAt.Time
+		}
+		if revokedAt.Valid {
+			key.RevokedAt = &revokedAt.Time
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+// RevokeAPIKey revokes an API key so it can no longer authenticate
+func RevokeAPIKey(orgID string, id int64) error {
+	res, err := database.DB.Exec(`
+		UPDATE api_keys SET revoked_at = CURRENT_TIMESTAMP
+		WHERE organization_id = $1 AND id = $2 AND revoked_at IS NULL
+	`, orgID, id)
+	if err != nil {
+		return fmt.Errorf("failed to revoke API key: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// AuthenticateAPIKey resolves a plaintext API key to its stored record
+func AuthenticateAPIKey(key string) (*models.APIKey, error) {
+	if !strings.HasPrefix(key, apiKeyPrefix) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	query := `
+		UPDATE api_keys SET last_used_at = CURRENT_TIMESTAMP
+		WHERE key_hash = $1 AND revoked_at IS NULL
+		RETURNING id, organization_id, name, prefix, role, created_at
+	`
+
+	var apiKey models.APIKey
+	err := database.DB.QueryRow(query, HashAPIKey(key)).
+		Scan(&apiKey.ID, &apiKey.OrganizationID, &apiKey.Name, &apiKey.Prefix, &apiKey.Role, &apiKey.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to authenticate API key: %w", err)
+	}
+
+	return &apiKey, nil
+}
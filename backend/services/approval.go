@@ -0,0 +1,389 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"halalguard-backend/database"
+	"halalguard-backend/models"
+
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrApprovalNotRequired is returned when no approval chain applies to a transaction
+	ErrApprovalNotRequired = errors.New("no approval chain applies to this transaction")
+	// ErrApprovalClosed is returned when the chain has already been approved or rejected
+	ErrApprovalClosed = errors.New("approval chain already completed")
+	// ErrApprovalWrongRole is returned when the approver's role does not match the current step
+	ErrApprovalWrongRole = errors.New("role does not match the current approval step")
+	// ErrDuplicateApprover is returned when the same person tries to approve two steps
+	ErrDuplicateApprover = errors.New("approver already signed a previous step")
+	// ErrTransactionLocked is returned when re-analysing an approved transaction
+	ErrTransactionLocked = errors.New("transaction is locked by a completed approval")
+	// ErrApprovalStepDecided is returned when a concurrent request decided the same step first
+	ErrApprovalStepDecided = errors.New("approval step already decided")
+)
+
+// pqUniqueViolation is the Postgres error code of a unique constraint violation
+const pqUniqueViolation = "23505"
+
+const approvalChainColumns = `id, name, COALESCE(transaction_type, ''), min_amount, steps`
+
+func scanApprovalChain(row rowScanner) (*models.ApprovalChain, error) {
+	var chain models.ApprovalChain
+	if err := row.Scan(&chain.ID, &chain.Name, &chain.TransactionType, &chain.MinAmount, pq.Array(&chain.Steps)); err != nil {
+		return nil, err
+	}
+	return &chain, nil
+}
+
+// GetApprovalChains lists an organization's approval chains
+func GetApprovalChains(orgID string) ([]models.ApprovalChain, error) {
+	rows, err := database.DB.Query(`
+		SELECT `+approvalChainColumns+`
+		FROM approval_chains
+		WHERE organization_id = $1
+		ORDER BY min_amount, name
+	`, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query approval chains: %w", err)
+	}
+	defer rows.Close()
+
+	chains := []models.ApprovalChain{}
+	for rows.Next() {
+		chain, err := scanApprovalChain(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan approval chain: %w", err)
+		}
+		chains = append(chains, *chain)
+	}
+
+	return chains, rows.Err()
+}
+
+// SaveApprovalChain creates or updates an organization's approval chain by name
+func SaveApprovalChain(orgID string, chain models.ApprovalChain) (*models.ApprovalChain, error) {
+	query := `
+		INSERT INTO approval_chains (organization_id, name, transaction_type, min_amount, steps)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (organization_id, name) DO UPDATE SET
+			transaction_type = EXCLUDED.transaction_type,
+			min_amount = EXCLUDED.min_amount,
+			steps = EXCLUDED.steps
+		RETURNING id
+	`
+
+	transactionType := sql.NullString{String: chain.TransactionType, Valid: chain.TransactionType != ""}
+	err := database.DB.QueryRow(query, orgID, chain.Name, transactionType, chain.MinAmount, pq.Array(chain.Steps)).Scan(&chain.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save approval chain: %w", err)
+	}
+
+	return &chain, nil
+}
+
+// matchApprovalChain finds the chain for a transaction. Chains for the specific
+// transaction type win over generic ones, then the highest amount threshold wins.
+func matchApprovalChain(q querier, orgID string, amount float64, transactionType string) (*models.ApprovalChain, error) {
+	query := `
+		SELECT ` + approvalChainColumns + `
+		FROM approval_chains
+		WHERE organization_id = $1
+			AND ABS($2) >= min_amount
+			AND (transaction_type IS NULL OR LOWER(transaction_type) = LOWER($3))
+		ORDER BY (transaction_type IS NOT NULL) DESC, min_amount DESC
+		LIMIT 1
+	`
+
+	chain, err := scanApprovalChain(q.QueryRow(query, orgID, amount, transactionType))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to match approval chain: %w", err)
+	}
+	return chain, nil
+}
+
+// querier is implemented by both *sql.DB and *sql.Tx
+type querier interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// GetApprovalStatus shows where a transaction sits in its approval chain
+func GetApprovalStatus(orgID, transactionID string) (*models.ApprovalStatus, error) {
+	return approvalStatus(database.DB, orgID, transactionID, false)
+}
+
+// approvalStatus loads the approval state, locking the approval row when forUpdate is set
+func approvalStatus(q querier, orgID, transactionID string, forUpdate bool) (*models.ApprovalStatus, error) {
+	status := &models.ApprovalStatus{TransactionID: transactionID, Steps: []models.ApprovalStep{}}
+
+	err := q.QueryRow(`SELECT amount, type FROM transactions WHERE organization_id = $1 AND id = $2`, orgID, transactionID).
+		Scan(&status.Amount, &status.Type)
+	if err == sql.ErrNoRows {
+		return nil, ErrTransactionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query transaction: %w", err)
+	}
+
+	approvalQuery := `
+		SELECT chain_id, state, locked_at IS NOT NULL
+		FROM approvals
+		WHERE organization_id = $1 AND transaction_id = $2`
+	if forUpdate {
+		approvalQuery += ` FOR UPDATE`
+	}
+
+	var chainID sql.NullInt64
+	err = q.QueryRow(approvalQuery, orgID, transactionID).Scan(&chainID, &status.State, &status.Locked)
+	switch {
+	case err == sql.ErrNoRows:
+		status.Chain, err = matchApprovalChain(q, orgID, status.Amount, status.Type)
+		if err != nil {
+			return nil, err
+		}
+		status.State = models.ApprovalPending
+	case err != nil:
+		return nil, fmt.Errorf("failed to query approval: %w", err)
+	case chainID.Valid:
+		status.Chain, err = scanApprovalChain(q.QueryRow(`SELECT `+approvalChainColumns+` FROM approval_chains WHERE id = $1`, chainID.Int64))
+		if err != nil {
+			return nil, fmt.Errorf("failed to query approval chain: %w", err)
+		}
+	}
+
+	if status.Chain == nil {
+		status.State = models.ApprovalNotRequired
+		return status, nil
+	}
+
+	rows, err := q.Query(`
+		SELECT step_index, role, approver, decision, COALESCE(comment, ''), decided_at
+		FROM approval_steps
+		WHERE organization_id = $1 AND transaction_id = $2
+		ORDER BY step_index
+	`, orgID, transactionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query approval steps: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var step models.ApprovalStep
+		if err := rows.Scan(&step.StepIndex, &step.Role, &step.Approver, &step.Decision, &step.Comment, &step.DecidedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan approval step: %w", err)
+		}
+		status.Steps = append(status.Steps, step)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	status.CurrentStep = len(status.Steps)
+	if status.State == models.ApprovalPending && status.CurrentStep < len(status.Chain.Steps) {
+		status.NextRole = status.Chain.Steps[status.CurrentStep]
+	}
+
+	return status, nil
+}
+
+// DecideApproval records an approval or rejection for the current step of a transaction's chain.
+// The approver is the authenticated subject, who may sign only one step of a chain.
+// Completing the last step locks the transaction against re-analysis.
+func DecideApproval(orgID, transactionID, approver string, req models.ApprovalRequest) (*models.ApprovalStatus, error) {
+	result, err := GetTransactionByID(orgID, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	if result.Analysis == nil {
+		return nil, ErrAnalysisNotFound
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// The transaction row lock serializes decisions with each other, including the
+	// first step when no approval row exists yet, and with re-analysis
+	if _, err := lockTransaction(context.Background(), tx, orgID, transactionID); err != nil {
+		return nil, err
+	}
+	status, err := approvalStatus(tx, orgID, transactionID, true)
+	if err != nil {
+		return nil, err
+	}
+	if status.State == models.ApprovalNotRequired {
+		return nil, ErrApprovalNotRequired
+	}
+	if status.State != models.ApprovalPending {
+		return nil, ErrApprovalClosed
+	}
+	if req.Role != status.NextRole {
+		return nil, fmt.Errorf("%w: expected %s", ErrApprovalWrongRole, status.NextRole)
+	}
+	for _, step := range status.Steps {
+		if step.Approver == approver {
+			return nil, ErrDuplicateApprover
+		}
+	}
+
+	state := models.ApprovalPending
+	if req.Decision == models.ApprovalRejected {
+		state = models.ApprovalRejected
+	} else if status.CurrentStep == len(status.Chain.Steps)-1 {
+		state = models.ApprovalApproved
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO approvals (organization_id, transaction_id, chain_id, state, locked_at)
+		VALUES ($1, $2, $3, $4, CASE WHEN $4 = 'approved' THEN CURRENT_TIMESTAMP END)
+		ON CONFLICT (organization_id, transaction_id) DO UPDATE SET
+			state = EXCLUDED.state,
+			locked_at = EXCLUDED.locked_at
+	`, orgID, transactionID, status.Chain.ID, state)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save approval: %w", err)
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO approval_steps (organization_id, transaction_id, chain_id, step_index, role, approver, decision, comment)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`, orgID, transactionID, status.Chain.ID, status.CurrentStep, req.Role, approver, req.Decision, req.Comment)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == pqUniqueViolation {
+		return nil, ErrApprovalStepDecided
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to save approval step: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit approval: %w", err)
+	}
+
+	return GetApprovalStatus(orgID, transactionID)
+}
+
+// GetLockedTransactionIDs returns which of the organization's given transactions are locked by a completed approval
+func GetLockedTransactionIDs(ctx context.Context, orgID string, ids []string) ([]string, error) {
+	rows, err := database.DB.QueryContext(ctx, `
+		SELECT transaction_id FROM approvals
+		WHERE organization_id = $1 AND locked_at IS NOT NULL AND transaction_id = ANY($2)
+	`, orgID, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query locked transactions: %w", err)
+	}
+	defer rows.Close()
+
+	var locked []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		locked = append(locked, id)
+	}
+
+	return locked, rows.Err()
+}
+
+// lockTransaction locks a transaction's row until q commits and reports whether a
+// completed approval locks it against re-analysis. Saving a re-analysis and deciding
+// an approval both take this lock, so neither can interleave with the other.
+func lockTransaction(ctx context.Context, q querier, orgID, transactionID string) (bool, error) {
+	var locked bool
+	err := q.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM approvals
+			WHERE organization_id = t.organization_id AND transaction_id = t.id AND locked_at IS NOT NULL
+		)
+		FROM transactions t
+		WHERE t.organization_id = $1 AND t.id = $2
+		FOR UPDATE OF t
+	`, orgID, transactionID).Scan(&locked)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to lock transaction: %w", err)
+	}
+	return locked, nil
+}
+
+// resetApprovals discards an unfinished or rejected approval after a re-analysis,
+// since it was given against the previous verdict
+func resetApprovals(ctx context.Context, q querier, orgID, transactionID string) error {
+	_, err := q.ExecContext(ctx, `
+		WITH reset AS (
+			DELETE FROM approvals
+			WHERE organization_id = $1 AND transaction_id = $2 AND locked_at IS NULL
+			RETURNING transaction_id
+		)
+		DELETE FROM approval_steps
+		WHERE organization_id = $1 AND transaction_id IN (SELECT transaction_id FROM reset)
+	`, orgID, transactionID)
+	if err != nil {
+		return fmt.Errorf("failed to reset approvals: %w", err)
+	}
+	return nil
+}
+
+// GetApprovalStatuses lists an organization's analysed transactions that are in, or require, an approval chain
+func GetApprovalStatuses(orgID, state string, limit int) ([]models.ApprovalStatus, error) {
+	rows, err := database.DB.Query(`
+		SELECT t.id
+		FROM transactions t
+		JOIN `+analysisJoinOn+`
+		LEFT JOIN approvals ap ON ap.organization_id = t.organization_id AND ap.transaction_id = t.id
+		WHERE t.organization_id = $1
+			AND ($2 = '' OR COALESCE(ap.state, 'pending') = $2)
+			AND (ap.transaction_id IS NOT NULL OR EXISTS (
+				SELECT 1 FROM approval_chains c
+				WHERE c.organization_id = t.organization_id
+					AND ABS(t.amount) >= c.min_amount
+					AND (c.transaction_type IS NULL OR LOWER(c.transaction_type) = LOWER(t.type))
+			))
+		ORDER BY t.created_at DESC
+		LIMIT $3
+	`, orgID, state, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query approvals: %w", err)
+	}
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan approval: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	statuses := make([]models.ApprovalStatus, 0, len(ids))
+	for _, id := range ids {
+		status, err := GetApprovalStatus(orgID, id)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, *status)
+	}
+
+	return statuses, nil
+}
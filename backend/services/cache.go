@@ -0,0 +1,84 @@
+package services
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"halalguard-backend/metrics"
+	"halalguard-backend/models"
+)
+
+// analysisCache is an in-memory LRU of analysis results keyed by a hash of the
+// tenant settings and the transaction as presented to the model
+type analysisCache struct {
+	size int
+	ttl  time.Duration
+
+	mu      sync.Mutex
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type cacheEntry struct {
+	key       string
+	result    models.AnalysisResult
+	expiresAt time.Time
+}
+
+func newAnalysisCache(size int, ttl time.Duration) *analysisCache {
+	return &analysisCache{
+		size:    size,
+		ttl:     ttl,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// get returns a cached result and records the hit or miss
+func (c *analysisCache) get(key string) (models.AnalysisResult, bool) {
+	if c.size <= 0 {
+		return models.AnalysisResult{}, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		entry := element.Value.(*cacheEntry)
+		if time.Now().Before(entry.expiresAt) {
+			c.order.MoveToFront(element)
+			metrics.AnalysisCacheRequests.WithLabelValues("hit").Inc()
+			return entry.result, true
+		}
+		c.order.Remove(element)
+		delete(c.entries, key)
+	}
+
+	metrics.AnalysisCacheRequests.WithLabelValues("miss").Inc()
+	return models.AnalysisResult{}, false
+}
+
+// put stores a result, evicting the least recently used entry when full
+func (c *analysisCache) put(key string, result models.AnalysisResult) {
+	if c.size <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &cacheEntry{key: key, result: result, expiresAt: time.Now().Add(c.ttl)}
+	if element, ok := c.entries[key]; ok {
+		element.Value = entry
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(entry)
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
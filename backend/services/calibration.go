@@ -0,0 +1,480 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"halalguard-backend/config"
+	"halalguard-backend/database"
+	"halalguard-backend/models"
+)
+
+// calibrationBins is the number of equal-width confidence bins of a reliability curve
+const calibrationBins = 10
+
+// calibrationFolds is the number of cross-validation folds the Brier scores and
+// reliability curve are measured with
+const calibrationFolds = 5
+
+// calibrationSample is a labeled analysis: the model's raw confidence and whether its
+// verdict matched the label
+type calibrationSample struct {
+	confidence float64
+	correct    bool
+}
+
+// ParseCalibrationLabelsCSV reads confirmed verdicts from a CSV with the header
+// transaction_id,status,violation_type. Labels must be a final verdict, so
+// "Butuh Tinjauan" is rejected.
+func ParseCalibrationLabelsCSV(r io.Reader) ([]models.CalibrationLabel, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	index := make(map[string]int, len(header))
+	for i, column := range header {
+		index[strings.ToLower(strings.TrimSpace(column))] = i
+	}
+	for _, column := range []string{"transaction_id", "status", "violation_type"} {
+		if _, ok := index[column]; !ok {
+			return nil, fmt.Errorf("CSV is missing column %q", column)
+		}
+	}
+
+	var labels []models.CalibrationLabel
+	seen := make(map[string]bool)
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV line %d: %w", line, err)
+		}
+
+		label := models.CalibrationLabel{
+			TransactionID: strings.TrimSpace(record[index["transaction_id"]]),
+			Status:        strings.TrimSpace(record[index["status"]]),
+			ViolationType: strings.TrimSpace(record[index["violation_type"]]),
+		}
+		if label.TransactionID == "" {
+			return nil, fmt.Errorf("missing transaction_id on CSV line %d", line)
+		}
+		if !models.IsValidStatus(label.Status) || label.Status == models.StatusNeedsReview {
+			return nil, fmt.Errorf("invalid status %q on CSV line %d", label.Status, line)
+		}
+		if !models.IsValidViolationType(label.ViolationType) {
+			return nil, fmt.Errorf("invalid violation_type %q on CSV line %d", label.ViolationType, line)
+		}
+		if seen[label.TransactionID] {
+			return nil, fmt.Errorf("duplicate transaction_id %q on CSV line %d", label.TransactionID, line)
+		}
+		seen[label.TransactionID] = true
+		labels = append(labels, label)
+	}
+
+	return labels, nil
+}
+
+// ImportCalibrationLabels stores an organization's labels, replacing earlier labels of
+// the same transactions
+func ImportCalibrationLabels(ctx context.Context, orgID string, labels []models.CalibrationLabel) (int, error) {
+	tx, err := database.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO calibration_labels (organization_id, transaction_id, status, violation_type)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (organization_id, transaction_id) DO UPDATE SET
+			status = EXCLUDED.status,
+			violation_type = EXCLUDED.violation_type,
+			imported_at = CURRENT_TIMESTAMP
+	`
+	for _, label := range labels {
+		if _, err := tx.ExecContext(ctx, query, orgID, label.TransactionID, label.Status, label.ViolationType); err != nil {
+			return 0, fmt.Errorf("failed to save label for %s: %w", label.TransactionID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit labels: %w", err)
+	}
+	return len(labels), nil
+}
+
+// FitCalibration fits a calibration model per violation type from the organization's
+// labeled analyses and replaces the stored models. A verdict is correct when both its
+// status and violation type match the label; analyses still awaiting review are
+// skipped. Violation types with fewer than cfg.MinSamples samples are reported with
+// method "none" and are not calibrated.
+func FitCalibration(ctx context.Context, orgID string, cfg config.CalibrationConfig) (*models.CalibrationReport, error) {
+	rows, err := database.DB.QueryContext(ctx, `
+		SELECT a.violation_type, COALESCE(a.raw_confidence_score, a.confidence_score),
+			a.status = l.status AND a.violation_type = l.violation_type
+		FROM calibration_labels l
+		JOIN analysis_results a ON a.organization_id = l.organization_id AND a.transaction_id = l.transaction_id
+		WHERE l.organization_id = $1 AND a.status <> $2
+	`, orgID, models.StatusNeedsReview)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query labeled analyses: %w", err)
+	}
+	defer rows.Close()
+
+	samples := make(map[string][]calibrationSample)
+	for rows.Next() {
+		var violationType string
+		var sample calibrationSample
+		if err := rows.Scan(&violationType, &sample.confidence, &sample.correct); err != nil {
+			return nil, fmt.Errorf("failed to scan labeled analysis: %w", err)
+		}
+		samples[violationType] = append(samples[violationType], sample)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	fittedAt := time.Now().UTC()
+	var fitted []models.CalibrationModel
+	for violationType, typeSamples := range samples {
+		model := fitCalibrationModel(typeSamples, cfg)
+		model.ViolationType = violationType
+		model.FittedAt = fittedAt
+		fitted = append(fitted, model)
+	}
+	sort.Slice(fitted, func(i, j int) bool { return fitted[i].ViolationType < fitted[j].ViolationType })
+
+	tx, err := database.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM calibration_models WHERE organization_id = $1`, orgID); err != nil {
+		return nil, fmt.Errorf("failed to clear calibration: %w", err)
+	}
+	for _, model := range fitted {
+		data, err := json.Marshal(model)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode calibration: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO calibration_models (organization_id, violation_type, model, fitted_at)
+			VALUES ($1, $2, $3, $4)
+		`, orgID, model.ViolationType, data, fittedAt); err != nil {
+			return nil, fmt.Errorf("failed to save calibration for %s: %w", model.ViolationType, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit calibration: %w", err)
+	}
+
+	return GetCalibrationReport(ctx, orgID)
+}
+
+// GetCalibrationReport returns the organization's stored calibration models with
+// their reliability curves and Brier scores
+func GetCalibrationReport(ctx context.Context, orgID string) (*models.CalibrationReport, error) {
+	report := &models.CalibrationReport{Models: []models.CalibrationModel{}}
+	err := database.DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM calibration_labels WHERE organization_id = $1`, orgID).Scan(&report.Labels)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count labels: %w", err)
+	}
+
+	calibrations, err := getCalibrationModels(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	for _, model := range calibrations {
+		report.Models = append(report.Models, model)
+	}
+	sort.Slice(report.Models, func(i, j int) bool { return report.Models[i].ViolationType < report.Models[j].ViolationType })
+	return report, nil
+}
+
+// getCalibrationModels loads the organization's calibration models keyed by violation type
+func getCalibrationModels(ctx context.Context, orgID string) (map[string]models.CalibrationModel, error) {
+	rows, err := database.DB.QueryContext(ctx, `SELECT model FROM calibration_models WHERE organization_id = $1`, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query calibration: %w", err)
+	}
+	defer rows.Close()
+
+	calibrations := make(map[string]models.CalibrationModel)
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("failed to scan calibration: %w", err)
+		}
+		var model models.CalibrationModel
+		if err := json.Unmarshal(data, &model); err != nil {
+			return nil, fmt.Errorf("failed to decode calibration: %w", err)
+		}
+		calibrations[model.ViolationType] = model
+	}
+	return calibrations, rows.Err()
+}
+
+// ApplyCalibration replaces the confidenceScore of fresh results with the calibrated
+// score of their violation type, keeping the model's own score in RawConfidenceScore.
+// Results of uncalibrated violation types are left unchanged.
+func ApplyCalibration(ctx context.Context, orgID string, results []models.AnalysisResult) error {
+	calibrations, err := getCalibrationModels(ctx, orgID)
+	if err != nil {
+		return err
+	}
+	for i := range results {
+		model, ok := calibrations[results[i].ViolationType]
+		if !ok || model.Method == models.CalibrationNone {
+			continue
+		}
+		raw := results[i].ConfidenceScore
+		if results[i].RawConfidenceScore != nil {
+			raw = *results[i].RawConfidenceScore
+		}
+		results[i].RawConfidenceScore = &raw
+		results[i].ConfidenceScore = math.Round(calibrate(model, raw)*100) / 100
+	}
+	return nil
+}
+
+// fitCalibrationModel fits the configured method to all samples of one violation type.
+// Its reliability curve and Brier scores use out-of-fold predictions, so they measure
+// the calibration on analyses the mapping was not fitted on.
+func fitCalibrationModel(samples []calibrationSample, cfg config.CalibrationConfig) models.CalibrationModel {
+	model := models.CalibrationModel{Method: models.CalibrationNone, Samples: len(samples)}
+	heldOut := make([]float64, len(samples))
+	for i, sample := range samples {
+		heldOut[i] = sample.confidence
+	}
+	if len(samples) >= cfg.MinSamples {
+		model = fitCalibrationMethod(samples, cfg.Method)
+		model.Samples = len(samples)
+		heldOut = crossValidate(samples, cfg.Method)
+	}
+
+	bins := make([]models.CalibrationBin, calibrationBins)
+	for i := range bins {
+		bins[i].Lower = float64(i) * 100 / calibrationBins
+		bins[i].Upper = float64(i+1) * 100 / calibrationBins
+	}
+	var correct float64
+	for i, sample := range samples {
+		outcome := 0.0
+		if sample.correct {
+			outcome = 1
+		}
+		calibrated := heldOut[i]
+		correct += outcome
+		model.BrierRaw += math.Pow(sample.confidence/100-outcome, 2)
+		model.BrierCalibrated += math.Pow(calibrated/100-outcome, 2)
+
+		bin := &bins[min(int(sample.confidence*calibrationBins/100), calibrationBins-1)]
+		bin.Count++
+		bin.MeanConfidence += sample.confidence
+		bin.MeanCalibrated += calibrated
+		bin.Accuracy += outcome
+	}
+	if n := float64(len(samples)); n > 0 {
+		model.Accuracy = correct / n
+		model.BrierRaw /= n
+		model.BrierCalibrated /= n
+	}
+
+	model.Curve = []models.CalibrationBin{}
+	for _, bin := range bins {
+		if bin.Count == 0 {
+			continue
+		}
+		n := float64(bin.Count)
+		bin.MeanConfidence /= n
+		bin.MeanCalibrated /= n
+		bin.Accuracy /= n
+		model.Curve = append(model.Curve, bin)
+	}
+	return model
+}
+
+// fitCalibrationMethod fits method (isotonic or platt) to the samples
+func fitCalibrationMethod(samples []calibrationSample, method string) models.CalibrationModel {
+	model := models.CalibrationModel{Method: method}
+	switch method {
+	case models.CalibrationPlatt:
+		model.PlattA, model.PlattB = fitPlatt(samples)
+	default:
+		model.Method = models.CalibrationIsotonic
+		model.Points = fitIsotonic(samples)
+	}
+	return model
+}
+
+// crossValidate returns the calibrated confidence of each sample predicted by a model
+// fitted on the other folds. Samples are ordered by confidence and dealt round-robin
+// into calibrationFolds folds, so every fold spans the confidence range and the split
+// does not depend on the order the samples were loaded in.
+func crossValidate(samples []calibrationSample, method string) []float64 {
+	order := make([]int, len(samples))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := samples[order[i]], samples[order[j]]
+		if a.confidence != b.confidence {
+			return a.confidence < b.confidence
+		}
+		return !a.correct && b.correct
+	})
+
+	folds := min(calibrationFolds, len(samples))
+	predicted := make([]float64, len(samples))
+	for fold := 0; fold < folds; fold++ {
+		var train []calibrationSample
+		for rank, i := range order {
+			if rank%folds != fold {
+				train = append(train, samples[i])
+			}
+		}
+		model := fitCalibrationMethod(train, method)
+		for rank, i := range order {
+			if rank%folds == fold {
+				predicted[i] = calibrate(model, samples[i].confidence)
+			}
+		}
+	}
+	return predicted
+}
+
+// calibrate maps a raw confidenceScore (0-100) through the model
+func calibrate(model models.CalibrationModel, confidence float64) float64 {
+	switch model.Method {
+	case models.CalibrationPlatt:
+		return 100 / (1 + math.Exp(-(model.PlattA*confidence/100 + model.PlattB)))
+	case models.CalibrationIsotonic:
+		return interpolate(model.Points, confidence)
+	}
+	return confidence
+}
+
+// interpolate evaluates the piecewise-linear function through points, sorted by
+// confidence, holding the end values constant outside them
+func interpolate(points []models.CalibrationPoint, confidence float64) float64 {
+	if len(points) == 0 {
+		return confidence
+	}
+	if confidence <= points[0].Confidence {
+		return points[0].Calibrated
+	}
+	for i := 1; i < len(points); i++ {
+		if confidence <= points[i].Confidence {
+			lo, hi := points[i-1], points[i]
+			return lo.Calibrated + (hi.Calibrated-lo.Calibrated)*(confidence-lo.Confidence)/(hi.Confidence-lo.Confidence)
+		}
+	}
+	return points[len(points)-1].Calibrated
+}
+
+// fitIsotonic fits a non-decreasing mapping from confidence to accuracy with the
+// pool-adjacent-violators algorithm. Each pooled block becomes a point at its mean
+// confidence and accuracy.
+func fitIsotonic(samples []calibrationSample) []models.CalibrationPoint {
+	sorted := append([]calibrationSample(nil), samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].confidence < sorted[j].confidence })
+
+	type block struct {
+		sumConfidence, sumCorrect, n float64
+	}
+	var blocks []block
+	for i, sample := range sorted {
+		correct := 0.0
+		if sample.correct {
+			correct = 1
+		}
+		// Samples with equal confidence must map to the same value
+		if i > 0 && sample.confidence == sorted[i-1].confidence {
+			last := &blocks[len(blocks)-1]
+			last.sumConfidence += sample.confidence
+			last.sumCorrect += correct
+			last.n++
+		} else {
+			blocks = append(blocks, block{sample.confidence, correct, 1})
+		}
+		for len(blocks) > 1 {
+			prev, last := blocks[len(blocks)-2], blocks[len(blocks)-1]
+			if prev.sumCorrect/prev.n <= last.sumCorrect/last.n {
+				break
+			}
+			blocks = blocks[:len(blocks)-1]
+			blocks[len(blocks)-1] = block{prev.sumConfidence + last.sumConfidence, prev.sumCorrect + last.sumCorrect, prev.n + last.n}
+		}
+	}
+
+	points := make([]models.CalibrationPoint, len(blocks))
+	for i, b := range blocks {
+		points[i] = models.CalibrationPoint{
+			Confidence: b.sumConfidence / b.n,
+			Calibrated: 100 * b.sumCorrect / b.n,
+		}
+	}
+	return points
+}
+
+// fitPlatt fits a logistic curve to the samples by Newton's method, using Platt's
+// smoothed targets so that perfectly separated samples still converge
+func fitPlatt(samples []calibrationSample) (a, b float64) {
+	var positives, negatives float64
+	for _, sample := range samples {
+		if sample.correct {
+			positives++
+		} else {
+			negatives++
+		}
+	}
+	high := (positives + 1) / (positives + 2)
+	low := 1 / (negatives + 2)
+
+	b = math.Log((positives + 1) / (negatives + 1))
+	for iteration := 0; iteration < 100; iteration++ {
+		var gradA, gradB, hessAA, hessAB, hessBB float64
+		for _, sample := range samples {
+			x := sample.confidence / 100
+			target := low
+			if sample.correct {
+				target = high
+			}
+			p := 1 / (1 + math.Exp(-(a*x + b)))
+			weight := max(p*(1-p), 1e-12)
+			gradA += (p - target) * x
+			gradB += p - target
+			hessAA += weight * x * x
+			hessAB += weight * x
+			hessBB += weight
+		}
+		hessAA += 1e-9
+		hessBB += 1e-9
+		det := hessAA*hessBB - hessAB*hessAB
+		if det <= 0 {
+			break
+		}
+		stepA := (hessBB*gradA - hessAB*gradB) / det
+		stepB := (hessAA*gradB - hessAB*gradA) / det
+		a -= stepA
+		b -= stepB
+		if math.Abs(stepA)+math.Abs(stepB) < 1e-9 {
+			break
+		}
+	}
+	return a, b
+}
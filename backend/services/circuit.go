@@ -0,0 +1,98 @@
+package services
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned without calling Gemini while the circuit breaker is open
+var ErrCircuitOpen = errors.New("LLM circuit breaker is open")
+
+// Circuit breaker states
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half_open"
+)
+
+// circuitBreaker stops calling Gemini after consecutive failures and lets a
+// single trial call through once the cooldown has passed
+type circuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu            sync.Mutex
+	state         string
+	failures      int
+	openedAt      time.Time
+	trialInFlight bool
+	lastSuccessAt *time.Time
+	lastFailureAt *time.Time
+	lastError     string
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{threshold: threshold, cooldown: cooldown, state: CircuitClosed}
+}
+
+// allow reports whether a call may proceed, moving an expired open circuit to half-open
+func (b *circuitBreaker) allow() error {
+	if b.threshold <= 0 {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case CircuitOpen:
+		if time.Since(b.openedAt) < b.cooldown {
+			return ErrCircuitOpen
+		}
+		b.state = CircuitHalfOpen
+		b.trialInFlight = true
+		return nil
+	case CircuitHalfOpen:
+		if b.trialInFlight {
+			return ErrCircuitOpen
+		}
+		b.trialInFlight = true
+	}
+	return nil
+}
+
+// record closes the circuit on success and opens it once failures reach the threshold
+func (b *circuitBreaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.trialInFlight = false
+	if err == nil {
+		b.state = CircuitClosed
+		b.failures = 0
+		b.lastSuccessAt = &now
+		return
+	}
+
+	b.failures++
+	b.lastFailureAt = &now
+	b.lastError = err.Error()
+	if b.threshold > 0 && (b.state == CircuitHalfOpen || b.failures >= b.threshold) {
+		b.state = CircuitOpen
+		b.openedAt = now
+	}
+}
+
+// status reports the breaker state, moving an expired open circuit to half-open for display
+func (b *circuitBreaker) status() (state string, lastSuccessAt, lastFailureAt *time.Time, lastError string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	state = b.state
+	if state == CircuitOpen && time.Since(b.openedAt) >= b.cooldown {
+		state = CircuitHalfOpen
+	}
+	return state, b.lastSuccessAt, b.lastFailureAt, b.lastError
+}
@@ -1,62 +1,99 @@
 package services
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 
 	"halalguard-backend/database"
 	"halalguard-backend/models"
 )
 
-// SaveTransaction saves a transaction to the database
-func SaveTransaction(tx models.TransactionInput) error {
+// ErrTransactionNotFound is returned when a transaction ID is unknown
+var ErrTransactionNotFound = errors.New("transaction not found")
+
+// SaveTransaction saves an organization's transaction to the database
+func SaveTransaction(ctx context.Context, orgID string, tx models.TransactionInput) error {
 	query := `
-		INSERT INTO transactions (id, description, amount, date, type)
-		VALUES ($1, $2, $3, $4, $5)
-		ON CONFLICT (id) DO UPDATE SET
+		INSERT INTO transactions (organization_id, id, description, amount, date, type)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (organization_id, id) DO UPDATE SET
 			description = EXCLUDED.description,
 			amount = EXCLUDED.amount,
 			date = EXCLUDED.date,
 			type = EXCLUDED.type
 	`
 
-	_, err := database.DB.Exec(query, tx.ID, tx.Description, tx.Amount, tx.Date, tx.Type)
+	dbTx, err := database.DB.BeginTx(ctx, nil)
 	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer dbTx.Rollback()
+
+	if locked, err := lockTransaction(ctx, dbTx, orgID, tx.ID); err != nil {
+		return err
+	} else if locked {
+		return fmt.Errorf("%w: %s", ErrTransactionLocked, tx.ID)
+	}
+	if _, err := dbTx.ExecContext(ctx, query, orgID, tx.ID, tx.Description, tx.Amount, tx.Date, tx.Type); err != nil {
 		return fmt.Errorf("failed to save transaction: %w", err)
 	}
 
-	return nil
+	return dbTx.Commit()
 }
 
-// SaveAnalysisResult saves analysis result to the database
-func SaveAnalysisResult(result models.AnalysisResult) error {
+// SaveAnalysisResult saves an organization's analysis result to the database. The
+// result replaces the transaction's previous verdict, whose unfinished approval and
+// review are discarded; its reasoning is kept per language. It fails with
+// ErrTransactionLocked when an approval completed while the transaction was analyzed.
+func SaveAnalysisResult(ctx context.Context, orgID string, result models.AnalysisResult) error {
 	query := `
-		INSERT INTO analysis_results (
-			transaction_id, status, violation_type, confidence_score,
-			riba_score, gharar_score, maysir_score, halal_score, justice_score,
-			maslahah_total_score, maslahah_economic_justice, maslahah_community_dev,
-			maslahah_educational, maslahah_environmental, maslahah_social_cohesion,
-			maslahah_projection, reasoning, suggested_correction
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)
-		ON CONFLICT (transaction_id) DO UPDATE SET
-			status = EXCLUDED.status,
-			violation_type = EXCLUDED.violation_type,
-			confidence_score = EXCLUDED.confidence_score,
-			riba_score = EXCLUDED.riba_score,
-			gharar_score = EXCLUDED.gharar_score,
-			maysir_score = EXCLUDED.maysir_score,
-			halal_score = EXCLUDED.halal_score,
-			justice_score = EXCLUDED.justice_score,
-			maslahah_total_score = EXCLUDED.maslahah_total_score,
-			maslahah_economic_justice = EXCLUDED.maslahah_economic_justice,
-			maslahah_community_dev = EXCLUDED.maslahah_community_dev,
-			maslahah_educational = EXCLUDED.maslahah_educational,
-			maslahah_environmental = EXCLUDED.maslahah_environmental,
-			maslahah_social_cohesion = EXCLUDED.maslahah_social_cohesion,
-			maslahah_projection = EXCLUDED.maslahah_projection,
+		WITH saved AS (
+			INSERT INTO analysis_results (
+				organization_id, transaction_id, status, violation_type, confidence_score,
+				riba_score, gharar_score, maysir_score, halal_score, justice_score,
+				maslahah_total_score, maslahah_economic_justice, maslahah_community_dev,
+				maslahah_educational, maslahah_environmental, maslahah_social_cohesion,
+				maslahah_projection, reasoning, suggested_correction, screening, prompt_version, generation, language, ensemble,
+				raw_confidence_score
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25)
+			ON CONFLICT (organization_id, transaction_id) DO UPDATE SET
+				status = EXCLUDED.status,
+				violation_type = EXCLUDED.violation_type,
+				confidence_score = EXCLUDED.confidence_score,
+				riba_score = EXCLUDED.riba_score,
+				gharar_score = EXCLUDED.gharar_score,
+				maysir_score = EXCLUDED.maysir_score,
+				halal_score = EXCLUDED.halal_score,
+				justice_score = EXCLUDED.justice_score,
+				maslahah_total_score = EXCLUDED.maslahah_total_score,
+				maslahah_economic_justice = EXCLUDED.maslahah_economic_justice,
+				maslahah_community_dev = EXCLUDED.maslahah_community_dev,
+				maslahah_educational = EXCLUDED.maslahah_educational,
+				maslahah_environmental = EXCLUDED.maslahah_environmental,
+				maslahah_social_cohesion = EXCLUDED.maslahah_social_cohesion,
+				maslahah_projection = EXCLUDED.maslahah_projection,
+				reasoning = EXCLUDED.reasoning,
+				suggested_correction = EXCLUDED.suggested_correction,
+				screening = EXCLUDED.screening,
+				prompt_version = EXCLUDED.prompt_version,
+				generation = EXCLUDED.generation,
+				language = EXCLUDED.language,
+				ensemble = EXCLUDED.ensemble,
+				raw_confidence_score = EXCLUDED.raw_confidence_score
+			RETURNING organization_id, transaction_id, language, reasoning, suggested_correction, maslahah_projection, prompt_version
+		)
+		INSERT INTO analysis_texts (organization_id, transaction_id, language, reasoning, suggested_correction, maslahah_projection, prompt_version)
+		SELECT * FROM saved
+		ON CONFLICT (organization_id, transaction_id, language) DO UPDATE SET
 			reasoning = EXCLUDED.reasoning,
-			suggested_correction = EXCLUDED.suggested_correction
+			suggested_correction = EXCLUDED.suggested_correction,
+			maslahah_projection = EXCLUDED.maslahah_projection,
+			prompt_version = EXCLUDED.prompt_version,
+			created_at = CURRENT_TIMESTAMP
 	`
 
 	var maslahahTotal, maslahahEconomic, maslahahCommunity, maslahahEducational, maslahahEnvironmental, maslahahSocial sql.NullFloat64
@@ -72,141 +109,115 @@ func SaveAnalysisResult(result models.AnalysisResult) error {
 		maslahahProjection = sql.NullString{String: result.MaslahahAnalysis.LongTermProjection, Valid: true}
 	}
 
-	_, err := database.DB.Exec(query,
-		result.TransactionID, result.Status, result.ViolationType, result.ConfidenceScore,
+	var screening []byte
+	if result.Screening != nil {
+		var err error
+		if screening, err = json.Marshal(result.Screening); err != nil {
+			return fmt.Errorf("failed to encode screening: %w", err)
+		}
+	}
+	var generation []byte
+	if result.Generation != nil {
+		var err error
+		if generation, err = json.Marshal(result.Generation); err != nil {
+			return fmt.Errorf("failed to encode generation settings: %w", err)
+		}
+	}
+	var ensemble []byte
+	if result.Ensemble != nil {
+		var err error
+		if ensemble, err = json.Marshal(result.Ensemble); err != nil {
+			return fmt.Errorf("failed to encode ensemble verdict: %w", err)
+		}
+	}
+
+	tx, err := database.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if locked, err := lockTransaction(ctx, tx, orgID, result.TransactionID); err != nil {
+		return err
+	} else if locked {
+		return fmt.Errorf("%w: %s", ErrTransactionLocked, result.TransactionID)
+	}
+
+	_, err = tx.ExecContext(ctx, query,
+		orgID, result.TransactionID, result.Status, result.ViolationType, result.ConfidenceScore,
 		result.Breakdown.RibaScore, result.Breakdown.GhararScore, result.Breakdown.MaysirScore,
 		result.Breakdown.HalalScore, result.Breakdown.JusticeScore,
 		maslahahTotal, maslahahEconomic, maslahahCommunity, maslahahEducational,
 		maslahahEnvironmental, maslahahSocial, maslahahProjection,
-		result.Reasoning, result.SuggestedCorrection,
+		result.Reasoning, result.SuggestedCorrection, screening, result.PromptVersion, generation,
+		AnalysisLanguage(result.Language), ensemble, result.RawConfidenceScore,
 	)
 
 	if err != nil {
 		return fmt.Errorf("failed to save analysis result: %w", err)
 	}
+	if err := resetApprovals(ctx, tx, orgID, result.TransactionID); err != nil {
+		return err
+	}
+	if err := resetReviews(ctx, tx, orgID, result.TransactionID); err != nil {
+		return err
+	}
 
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit analysis result: %w", err)
+	}
 	return nil
 }
 
-// GetAllTransactions retrieves all transactions with their analysis
-func GetAllTransactions() ([]models.CombinedResult, error) {
-	query := `
-		SELECT 
-			t.id, t.description, t.amount, t.date, t.type,
-			a.status, a.violation_type, a.confidence_score,
-			a.riba_score, a.gharar_score, a.maysir_score, a.halal_score, a.justice_score,
-			a.maslahah_total_score, a.maslahah_economic_justice, a.maslahah_community_dev,
-			a.maslahah_educational, a.maslahah_environmental, a.maslahah_social_cohesion,
-			a.maslahah_projection, a.reasoning, a.suggested_correction
-		FROM transactions t
-		LEFT JOIN analysis_results a ON t.id = a.transaction_id
-		ORDER BY t.created_at DESC
-	`
-
-	rows, err := database.DB.Query(query)
-	if err != nil {
-		return nil, fmt.Errorf("failed to query transactions: %w", err)
-	}
-	defer rows.Close()
-
-	var results []models.CombinedResult
-
-	for rows.Next() {
-		var result models.CombinedResult
-		var status, violationType, reasoning sql.NullString
-		var confidenceScore, ribaScore, ghararScore, maysirScore, halalScore, justiceScore sql.NullFloat64
-		var maslahahTotal, maslahahEconomic, maslahahCommunity, maslahahEducational, maslahahEnvironmental, maslahahSocial sql.NullFloat64
-		var maslahahProjection, suggestedCorrection sql.NullString
-
-		err := rows.Scan(
-			&result.ID, &result.Description, &result.Amount, &result.Date, &result.Type,
-			&status, &violationType, &confidenceScore,
-			&ribaScore, &ghararScore, &maysirScore, &halalScore, &justiceScore,
-			&maslahahTotal, &maslahahEconomic, &maslahahCommunity,
-			&maslahahEducational, &maslahahEnvironmental, &maslahahSocial,
-			&maslahahProjection, &reasoning, &suggestedCorrection,
-		)
-
-		if err != nil {
-			log.Printf("Error scanning row: %v", err)
-			continue
-		}
-
-		// If analysis exists, populate it
-		if status.Valid {
-			result.Analysis = &models.AnalysisResult{
-				TransactionID:   result.ID,
-				Status:          status.String,
-				ViolationType:   violationType.String,
-				ConfidenceScore: confidenceScore.Float64,
-				Breakdown: models.ComplianceBreakdown{
-					RibaScore:    ribaScore.Float64,
-					GhararScore:  ghararScore.Float64,
-					MaysirScore:  maysirScore.Float64,
-					HalalScore:   halalScore.Float64,
-					JusticeScore: justiceScore.Float64,
-				},
-				Reasoning:           reasoning.String,
-				SuggestedCorrection: suggestedCorrection.String,
-			}
+// combinedResultColumns lists the columns read by scanCombinedResult
+const combinedResultColumns = `
+	t.id, t.description, t.amount, t.date, t.type,
+	a.status, a.violation_type, a.confidence_score,
+	a.riba_score, a.gharar_score, a.maysir_score, a.halal_score, a.justice_score,
+	a.maslahah_total_score, a.maslahah_economic_justice, a.maslahah_community_dev,
+	a.maslahah_educational, a.maslahah_environmental, a.maslahah_social_cohesion,
+	a.maslahah_projection, a.reasoning, a.suggested_correction, a.screening, a.prompt_version, a.generation, a.language, a.ensemble, a.raw_confidence_score,
+	r.reviewer, r.state, r.assigned_at, r.final_status, r.final_violation_type,
+	r.justification, r.decided_at`
 
-			// Add Maslahah analysis if available
-			if maslahahTotal.Valid {
-				result.Analysis.MaslahahAnalysis = &models.MaslahahAnalysis{
-					TotalScore: maslahahTotal.Float64,
-					Breakdown: models.MaslahahBreakdown{
-						EconomicJustice:      maslahahEconomic.Float64,
-						CommunityDevelopment: maslahahCommunity.Float64,
-						EducationalImpact:    maslahahEducational.Float64,
-						Environmental:        maslahahEnvironmental.Float64,
-						SocialCohesion:       maslahahSocial.Float64,
-					},
-					LongTermProjection: maslahahProjection.String,
-				}
-			}
-		}
+// analysisJoinOn joins analysis_results a to transactions t within the same organization
+const analysisJoinOn = `analysis_results a ON a.organization_id = t.organization_id AND a.transaction_id = t.id`
 
-		results = append(results, result)
-	}
+// combinedResultFrom joins transactions with their AI analysis and human review
+const combinedResultFrom = `
+	FROM transactions t
+	LEFT JOIN ` + analysisJoinOn + `
+	LEFT JOIN reviews r ON r.organization_id = t.organization_id AND r.transaction_id = t.id`
 
-	return results, nil
+// rowScanner is implemented by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
 }
 
-// GetTransactionByID retrieves a specific transaction with analysis
-func GetTransactionByID(id string) (*models.CombinedResult, error) {
-	query := `
-		SELECT 
-			t.id, t.description, t.amount, t.date, t.type,
-			a.status, a.violation_type, a.confidence_score,
-			a.riba_score, a.gharar_score, a.maysir_score, a.halal_score, a.justice_score,
-			a.maslahah_total_score, a.maslahah_economic_justice, a.maslahah_community_dev,
-			a.maslahah_educational, a.maslahah_environmental, a.maslahah_social_cohesion,
-			a.maslahah_projection, a.reasoning, a.suggested_correction
-		FROM transactions t
-		LEFT JOIN analysis_results a ON t.id = a.transaction_id
-		WHERE t.id = $1
-	`
-
+// scanCombinedResult scans a row selected with combinedResultColumns,
+// followed by any extra columns scanned into extra
+func scanCombinedResult(row rowScanner, extra ...interface{}) (*models.CombinedResult, error) {
 	var result models.CombinedResult
 	var status, violationType, reasoning sql.NullString
-	var confidenceScore, ribaScore, ghararScore, maysirScore, halalScore, justiceScore sql.NullFloat64
+	var confidenceScore, rawConfidenceScore, ribaScore, ghararScore, maysirScore, halalScore, justiceScore sql.NullFloat64
 	var maslahahTotal, maslahahEconomic, maslahahCommunity, maslahahEducational, maslahahEnvironmental, maslahahSocial sql.NullFloat64
-	var maslahahProjection, suggestedCorrection sql.NullString
+	var maslahahProjection, suggestedCorrection, promptVersion, language sql.NullString
+	var screening, generation, ensemble []byte
+	var reviewer, reviewState, finalStatus, finalViolation, justification sql.NullString
+	var assignedAt, decidedAt sql.NullTime
 
-	err := database.DB.QueryRow(query, id).Scan(
+	err := row.Scan(append([]interface{}{
 		&result.ID, &result.Description, &result.Amount, &result.Date, &result.Type,
 		&status, &violationType, &confidenceScore,
 		&ribaScore, &ghararScore, &maysirScore, &halalScore, &justiceScore,
 		&maslahahTotal, &maslahahEconomic, &maslahahCommunity,
 		&maslahahEducational, &maslahahEnvironmental, &maslahahSocial,
-		&maslahahProjection, &reasoning, &suggestedCorrection,
-	)
-
+		&maslahahProjection, &reasoning, &suggestedCorrection, &screening, &promptVersion, &generation, &language, &ensemble, &rawConfidenceScore,
+		&reviewer, &reviewState, &assignedAt, &finalStatus, &finalViolation,
+		&justification, &decidedAt,
+	}, extra...)...)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("transaction not found")
-		}
-		return nil, fmt.Errorf("failed to query transaction: %w", err)
+		return nil, err
 	}
 
 	// If analysis exists, populate it
@@ -225,6 +236,8 @@ func GetTransactionByID(id string) (*models.CombinedResult, error) {
 			},
 			Reasoning:           reasoning.String,
 			SuggestedCorrection: suggestedCorrection.String,
+			Language:            language.String,
+			PromptVersion:       promptVersion.String,
 		}
 
 		// Add Maslahah analysis if available
@@ -241,7 +254,127 @@ func GetTransactionByID(id string) (*models.CombinedResult, error) {
 				LongTermProjection: maslahahProjection.String,
 			}
 		}
+
+		if len(screening) > 0 {
+			result.Analysis.Screening = &models.ScreeningResult{}
+			if err := json.Unmarshal(screening, result.Analysis.Screening); err != nil {
+				return nil, fmt.Errorf("failed to decode screening: %w", err)
+			}
+		}
+		if len(generation) > 0 {
+			result.Analysis.Generation = &models.GenerationSettings{}
+			if err := json.Unmarshal(generation, result.Analysis.Generation); err != nil {
+				return nil, fmt.Errorf("failed to decode generation settings: %w", err)
+			}
+		}
+		if rawConfidenceScore.Valid {
+			result.Analysis.RawConfidenceScore = &rawConfidenceScore.Float64
+		}
+		if len(ensemble) > 0 {
+			result.Analysis.Ensemble = &models.EnsembleVerdict{}
+			if err := json.Unmarshal(ensemble, result.Analysis.Ensemble); err != nil {
+				return nil, fmt.Errorf("failed to decode ensemble verdict: %w", err)
+			}
+		}
+	}
+
+	// If a human review exists, populate it alongside the AI verdict
+	if reviewState.Valid {
+		result.Review = &models.ReviewDecision{
+			Reviewer:      reviewer.String,
+			State:         reviewState.String,
+			Status:        finalStatus.String,
+			ViolationType: finalViolation.String,
+			Justification: justification.String,
+		}
+		if assignedAt.Valid {
+			result.Review.AssignedAt = &assignedAt.Time
+		}
+		if decidedAt.Valid {
+			result.Review.DecidedAt = &decidedAt.Time
+		}
 	}
 
 	return &result, nil
 }
+
+// GetAllTransactions retrieves an organization's transactions with their analysis
+func GetAllTransactions(orgID string) ([]models.CombinedResult, error) {
+	query := `
+		SELECT ` + combinedResultColumns + combinedResultFrom + `
+		WHERE t.organization_id = $1
+		ORDER BY t.created_at DESC
+	`
+
+	rows, err := database.DB.Query(query, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query transactions: %w", err)
+	}
+	defer rows.Close()
+
+	var results []models.CombinedResult
+
+	for rows.Next() {
+		result, err := scanCombinedResult(rows)
+		if err != nil {
+			slog.Warn("failed to scan row", "error", err)
+			continue
+		}
+
+		results = append(results, *result)
+	}
+
+	return results, nil
+}
+
+// GetTransactionByID retrieves a specific transaction of an organization with analysis
+// and its reasoning in every analyzed language
+func GetTransactionByID(orgID, id string) (*models.CombinedResult, error) {
+	query := `
+		SELECT ` + combinedResultColumns + combinedResultFrom + `
+		WHERE t.organization_id = $1 AND t.id = $2
+	`
+
+	result, err := scanCombinedResult(database.DB.QueryRow(query, orgID, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrTransactionNotFound
+		}
+		return nil, fmt.Errorf("failed to query transaction: %w", err)
+	}
+
+	if result.Analysis != nil {
+		if result.Texts, err = getAnalysisTexts(orgID, id); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// getAnalysisTexts returns the reasoning of a transaction's analyses, one per language
+func getAnalysisTexts(orgID, transactionID string) ([]models.AnalysisText, error) {
+	rows, err := database.DB.Query(`
+		SELECT language, reasoning, suggested_correction, maslahah_projection, prompt_version, created_at
+		FROM analysis_texts
+		WHERE organization_id = $1 AND transaction_id = $2
+		ORDER BY language
+	`, orgID, transactionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query analysis texts: %w", err)
+	}
+	defer rows.Close()
+
+	var texts []models.AnalysisText
+	for rows.Next() {
+		var text models.AnalysisText
+		var suggestedCorrection, projection sql.NullString
+		if err := rows.Scan(&text.Language, &text.Reasoning, &suggestedCorrection, &projection, &text.PromptVersion, &text.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan analysis text: %w", err)
+		}
+		text.SuggestedCorrection = suggestedCorrection.String
+		text.LongTermProjection = projection.String
+		texts = append(texts, text)
+	}
+	return texts, rows.Err()
+}
@@ -0,0 +1,240 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"halalguard-backend/config"
+	"halalguard-backend/metrics"
+	"halalguard-backend/models"
+)
+
+// ensembleAnalyzer asks several models, or several samples of one model, to analyze
+// each batch and returns the majority verdict. Everything but AnalyzeTransactions is
+// served by the underlying analyzer.
+type ensembleAnalyzer struct {
+	*llmAnalyzer
+	cfg config.EnsembleConfig
+}
+
+// ensembleMember is one model and sample voting in an ensemble
+type ensembleMember struct {
+	model    string
+	sample   int
+	override *models.GenerationOverride
+}
+
+// memberAnswer is what one ensemble member returned for a batch
+type memberAnswer struct {
+	member  ensembleMember
+	results map[string]models.AnalysisResult
+	usage   models.TokenUsage
+	err     error
+}
+
+// members lists who votes on an analysis. ENSEMBLE_MODELS replace the tenant's model
+// unless the request overrides the model. Samples after the first are seeded apart so
+// their answers, and their cache entries, differ.
+func (e *ensembleAnalyzer) members(settings models.TenantSettings, override *models.GenerationOverride) []ensembleMember {
+	base := e.GenerationSettings(settings.Model, override)
+	modelNames := e.cfg.Models
+	if len(modelNames) == 0 || (override != nil && override.Model != "") {
+		modelNames = []string{base.Model}
+	}
+	var baseSeed int32
+	if base.Seed != nil {
+		baseSeed = *base.Seed
+	}
+
+	var members []ensembleMember
+	for _, model := range modelNames {
+		for sample := 0; sample < e.cfg.Samples; sample++ {
+			memberOverride := models.GenerationOverride{}
+			if override != nil {
+				memberOverride = *override
+			}
+			memberOverride.Model = model
+			if sample > 0 {
+				seed := baseSeed + int32(sample)
+				memberOverride.Seed = &seed
+			}
+			members = append(members, ensembleMember{model: model, sample: sample, override: &memberOverride})
+		}
+	}
+	return members
+}
+
+// AnalyzeTransactions runs every ensemble member concurrently and votes on each
+// transaction's status and violation type. The batch fails unless more than half of
+// the members answer. The returned usage sums all members and breaks them down by
+// model in Calls.
+func (e *ensembleAnalyzer) AnalyzeTransactions(ctx context.Context, transactions []models.TransactionInput, screenings map[string]models.ScreeningResult, settings models.TenantSettings, override *models.GenerationOverride) ([]models.AnalysisResult, models.TokenUsage, error) {
+	members := e.members(settings, override)
+	answers := make([]memberAnswer, len(members))
+	var wg sync.WaitGroup
+	for i, member := range members {
+		wg.Add(1)
+		go func(i int, member ensembleMember) {
+			defer wg.Done()
+			results, usage, err := e.llmAnalyzer.AnalyzeTransactions(ctx, transactions, screenings, settings, member.override)
+			byID := make(map[string]models.AnalysisResult, len(results))
+			for _, result := range results {
+				byID[result.TransactionID] = result
+			}
+			answers[i] = memberAnswer{member: member, results: byID, usage: usage, err: err}
+		}(i, member)
+	}
+	wg.Wait()
+
+	usage := ensembleUsage(answers)
+	var answered []memberAnswer
+	var errs []error
+	for _, answer := range answers {
+		if answer.err != nil {
+			slog.WarnContext(ctx, "ensemble member failed", "model", answer.member.model, "sample", answer.member.sample, "error", answer.err)
+			errs = append(errs, fmt.Errorf("%s (sample %d): %w", answer.member.model, answer.member.sample, answer.err))
+			continue
+		}
+		answered = append(answered, answer)
+	}
+	if len(answered)*2 <= len(members) {
+		return nil, usage, fmt.Errorf("ensemble analysis failed: %d of %d members answered: %w", len(answered), len(members), errors.Join(errs...))
+	}
+
+	results := make([]models.AnalysisResult, 0, len(transactions))
+	for _, tx := range transactions {
+		if result, ok := e.vote(tx.ID, answered); ok {
+			outcome := "agreed"
+			if result.Ensemble.Contested {
+				outcome = "contested"
+			}
+			metrics.EnsembleVotes.WithLabelValues(outcome).Inc()
+			results = append(results, result)
+		}
+	}
+	return results, usage, nil
+}
+
+// vote combines the members' results for a transaction. The majority status and
+// violation type win; ties go to the earlier member. Members that returned no result
+// for the transaction count against the majority. The majority's confidence and
+// compliance scores are averaged, and the result is routed to review when agreement
+// or score spread miss the configured thresholds.
+func (e *ensembleAnalyzer) vote(transactionID string, answers []memberAnswer) (models.AnalysisResult, bool) {
+	var opinions []models.ModelOpinion
+	var voters []models.AnalysisResult
+	counts := make(map[string]int)
+	var order []string
+	for _, answer := range answers {
+		result, ok := answer.results[transactionID]
+		if !ok {
+			continue
+		}
+		opinions = append(opinions, models.ModelOpinion{
+			Model:           answer.member.model,
+			Sample:          answer.member.sample,
+			Status:          result.Status,
+			ViolationType:   result.ViolationType,
+			ConfidenceScore: result.ConfidenceScore,
+			Breakdown:       result.Breakdown,
+			Reasoning:       result.Reasoning,
+		})
+		voters = append(voters, result)
+		key := verdictKey(result)
+		if counts[key] == 0 {
+			order = append(order, key)
+		}
+		counts[key]++
+	}
+	if len(voters) == 0 {
+		return models.AnalysisResult{}, false
+	}
+
+	majority := order[0]
+	for _, key := range order[1:] {
+		if counts[key] > counts[majority] {
+			majority = key
+		}
+	}
+
+	var winner *models.AnalysisResult
+	var confidence, lowest, highest float64
+	var breakdown models.ComplianceBreakdown
+	for i := range voters {
+		if verdictKey(voters[i]) != majority {
+			continue
+		}
+		score := voters[i].ConfidenceScore
+		if winner == nil {
+			winner = &voters[i]
+			lowest, highest = score, score
+		}
+		lowest, highest = min(lowest, score), max(highest, score)
+		confidence += score
+		breakdown.RibaScore += voters[i].Breakdown.RibaScore
+		breakdown.GhararScore += voters[i].Breakdown.GhararScore
+		breakdown.MaysirScore += voters[i].Breakdown.MaysirScore
+		breakdown.HalalScore += voters[i].Breakdown.HalalScore
+		breakdown.JusticeScore += voters[i].Breakdown.JusticeScore
+	}
+	n := float64(counts[majority])
+
+	result := *winner
+	result.ConfidenceScore = confidence / n
+	result.Breakdown = models.ComplianceBreakdown{
+		RibaScore:    breakdown.RibaScore / n,
+		GhararScore:  breakdown.GhararScore / n,
+		MaysirScore:  breakdown.MaysirScore / n,
+		HalalScore:   breakdown.HalalScore / n,
+		JusticeScore: breakdown.JusticeScore / n,
+	}
+	verdict := &models.EnsembleVerdict{
+		Votes:                 len(answers),
+		Agreement:             n / float64(len(answers)),
+		MajorityStatus:        winner.Status,
+		MajorityViolationType: winner.ViolationType,
+		ScoreSpread:           highest - lowest,
+		Opinions:              opinions,
+	}
+	verdict.Contested = verdict.Agreement < e.cfg.MinAgreement || verdict.ScoreSpread > e.cfg.MaxScoreSpread
+	if verdict.Contested {
+		result.Status = models.StatusNeedsReview
+	}
+	result.Ensemble = verdict
+	return result, true
+}
+
+// verdictKey identifies the verdict a result votes for
+func verdictKey(result models.AnalysisResult) string {
+	return result.Status + "|" + result.ViolationType
+}
+
+// ensembleUsage sums the token usage of the members, broken down by model
+func ensembleUsage(answers []memberAnswer) models.TokenUsage {
+	var total models.TokenUsage
+	var modelNames []string
+	byModel := make(map[string]*models.TokenUsage)
+	for _, answer := range answers {
+		call, ok := byModel[answer.usage.Model]
+		if !ok {
+			call = &models.TokenUsage{Model: answer.usage.Model}
+			byModel[answer.usage.Model] = call
+			modelNames = append(modelNames, answer.usage.Model)
+		}
+		call.PromptTokens += answer.usage.PromptTokens
+		call.CandidateTokens += answer.usage.CandidateTokens
+		call.TotalTokens += answer.usage.TotalTokens
+		total.PromptTokens += answer.usage.PromptTokens
+		total.CandidateTokens += answer.usage.CandidateTokens
+		total.TotalTokens += answer.usage.TotalTokens
+	}
+	total.Model = strings.Join(modelNames, "+")
+	for _, model := range modelNames {
+		total.Calls = append(total.Calls, *byModel[model])
+	}
+	return total
+}
@@ -2,138 +2,104 @@ package services
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"strings"
 
+	"halalguard-backend/logging"
 	"halalguard-backend/models"
+	"halalguard-backend/prompts"
 
 	"github.com/google/generative-ai-go/genai"
 	"google.golang.org/api/option"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
-type GeminiService struct {
+// geminiBackend calls Google Gemini through the genai SDK
+type geminiBackend struct {
 	client *genai.Client
-	ctx    context.Context
 }
 
-// NewGeminiService creates a new Gemini AI service
-func NewGeminiService(apiKey string) (*GeminiService, error) {
-	ctx := context.Background()
-	client, err := genai.NewClient(ctx, option.WithAPIKey(apiKey))
+func newGeminiBackend(apiKey string) (*geminiBackend, error) {
+	client, err := genai.NewClient(context.Background(), option.WithAPIKey(apiKey))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Gemini client: %w", err)
 	}
-
-	return &GeminiService{
-		client: client,
-		ctx:    ctx,
-	}, nil
+	return &geminiBackend{client: client}, nil
 }
 
-// AnalyzeTransactions analyzes transactions using Gemini AI
-func (s *GeminiService) AnalyzeTransactions(transactions []models.TransactionInput) ([]models.AnalysisResult, error) {
-	if len(transactions) == 0 {
-		return []models.AnalysisResult{}, nil
-	}
-
-	model := s.client.GenerativeModel("gemini-2.5-flash")
+func (b *geminiBackend) provider() string {
+	return ProviderGemini
+}
 
-	// Set system instruction
+// complete sends the prompt with a JSON response type. The Gemini SDK has no seed
+// parameter; the seed is still recorded and part of the cache key.
+func (b *geminiBackend) complete(ctx context.Context, generation models.GenerationSettings, rendered *prompts.Rendered) (string, models.TokenUsage, error) {
+	model := b.client.GenerativeModel(generation.Model)
 	model.SystemInstruction = &genai.Content{
-		Parts: []genai.Part{
-			genai.Text("Anda adalah sistem AI HalalGuard. Output harus JSON valid. Gunakan Bahasa Indonesia untuk reasoning dan correction."),
-		},
+		Parts: []genai.Part{genai.Text(rendered.System)},
 	}
-
-	// Configure JSON response
+	model.SetTemperature(generation.Temperature)
+	model.SetTopP(generation.TopP)
+	model.SetTopK(generation.TopK)
+	model.SetMaxOutputTokens(generation.MaxOutputTokens)
 	model.ResponseMIMEType = "application/json"
 
-	// Build prompt
-	transactionsJSON, err := json.Marshal(transactions)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal transactions: %w", err)
+	if requestID := logging.RequestIDFrom(ctx); requestID != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "x-request-id", requestID)
 	}
 
-	prompt := fmt.Sprintf(`
-Bertindaklah sebagai Auditor Kepatuhan Syariah DAN Analis Dampak Sosial Ekonomi Islam (Maslahah).
-
-Tugas 1: COMPLIANCE SCORE (Kepatuhan Hukum)
-Nilai berdasarkan 5 Prinsip (0.0 Buruk - 1.0 Baik):
-1. Riba (30%%): Bebas bunga.
-2. Gharar (25%%): Kejelasan akad.
-3. Maysir (20%%): Bebas judi.
-4. Halal Goods (15%%): Objek halal.
-5. Justice/Keadilan (10%%): Kewajaran harga.
-
-Tugas 2: MASLAHAH IMPACT SCORE (Dampak Sosial/Manfaat)
-Nilai dampak sosial transaksi ini (0-100) berdasarkan dimensi berikut:
-1. Keadilan Ekonomi (30%%): Distribusi kekayaan, pengentasan kemiskinan.
-2. Pengembangan Komunitas (25%%): Lapangan kerja, infrastruktur lokal.
-3. Dampak Pendidikan (20%%): Peningkatan skill, literasi.
-4. Kelestarian Lingkungan (15%%): Green investment, keberlanjutan.
-5. Kohesi Sosial (10%%): Kepercayaan komunitas, integrasi sosial.
+	resp, err := model.GenerateContent(ctx, genai.Text(rendered.Prompt))
+	if err != nil {
+		return "", models.TokenUsage{}, err
+	}
 
-Berikan proyeksi dampak jangka panjang singkat untuk aspek Maslahah.
+	var usage models.TokenUsage
+	if resp.UsageMetadata != nil {
+		usage.PromptTokens = int64(resp.UsageMetadata.PromptTokenCount)
+		usage.CandidateTokens = int64(resp.UsageMetadata.CandidatesTokenCount)
+		usage.TotalTokens = int64(resp.UsageMetadata.TotalTokenCount)
+	}
 
-PENTING: Response harus berupa array JSON dengan struktur berikut untuk setiap transaksi:
-{
-  "transactionId": "string",
-  "status": "Patuh" | "Tidak Patuh" | "Butuh Tinjauan",
-  "violationType": "Riba" | "Gharar" | "Maysir" | "Halal" | "Syubhat",
-  "confidenceScore": number (0-100),
-  "breakdown": {
-    "ribaScore": number (0-1),
-    "ghararScore": number (0-1),
-    "maysirScore": number (0-1),
-    "halalScore": number (0-1),
-    "justiceScore": number (0-1)
-  },
-  "maslahahAnalysis": {
-    "totalScore": number (0-100),
-    "breakdown": {
-      "economicJustice": number (0-100),
-      "communityDevelopment": number (0-100),
-      "educationalImpact": number (0-100),
-      "environmental": number (0-100),
-      "socialCohesion": number (0-100)
-    },
-    "longTermProjection": "string"
-  },
-  "reasoning": "string",
-  "suggestedCorrection": "string (optional)"
+	if len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil || len(resp.Candidates[0].Content.Parts) == 0 {
+		return "", usage, errEmptyResponse
+	}
+	return fmt.Sprintf("%v", resp.Candidates[0].Content.Parts[0]), usage, nil
 }
 
-Data Input:
-%s
-`, string(transactionsJSON))
-
-	// Generate content
-	resp, err := model.GenerateContent(s.ctx, genai.Text(prompt))
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate content: %w", err)
+// retryable reports whether a Gemini error is transient (rate limited or unavailable)
+func (b *geminiBackend) retryable(err error) bool {
+	switch status.Code(err) {
+	case codes.ResourceExhausted, codes.Unavailable, codes.DeadlineExceeded, codes.Internal, codes.Aborted:
+		return true
 	}
+	return false
+}
 
-	// Extract text from response
-	if len(resp.Candidates) == 0 || len(resp.Candidates[0].Content.Parts) == 0 {
-		return nil, fmt.Errorf("empty response from AI")
+// serviceFailure excludes blocked prompts and invalid requests
+func (b *geminiBackend) serviceFailure(err error) bool {
+	var blocked *genai.BlockedError
+	if errors.As(err, &blocked) {
+		return false
 	}
+	return status.Code(err) != codes.InvalidArgument
+}
 
-	// Parse JSON response
-	var results []models.AnalysisResult
-	responseText := fmt.Sprintf("%v", resp.Candidates[0].Content.Parts[0])
-
-	if err := json.Unmarshal([]byte(responseText), &results); err != nil {
-		log.Printf("Failed to parse AI response: %s", responseText)
-		return nil, fmt.Errorf("failed to parse AI response: %w", err)
+func (b *geminiBackend) errorReason(err error) string {
+	var blocked *genai.BlockedError
+	if errors.As(err, &blocked) {
+		return "blocked"
 	}
-
-	return results, nil
+	if code := status.Code(err); code != codes.Unknown {
+		return strings.ToLower(code.String())
+	}
+	return "unknown"
 }
 
-// Close closes the Gemini client
-func (s *GeminiService) Close() {
-	if s.client != nil {
-		s.client.Close()
+func (b *geminiBackend) close() {
+	if b.client != nil {
+		b.client.Close()
 	}
 }
@@ -0,0 +1,191 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"halalguard-backend/database"
+	"halalguard-backend/models"
+)
+
+// ErrJobNotFound is returned when an analysis job ID is unknown to the organization
+var ErrJobNotFound = errors.New("analysis job not found")
+
+// errJobLeaseLost is returned when a worker updates a job it no longer holds,
+// because its lease expired and another worker reclaimed the job
+var errJobLeaseLost = errors.New("analysis job lease lost")
+
+const jobColumns = `id, organization_id, state, transactions, language, generation, result, error, request_id, attempts, created_at, started_at, finished_at`
+
+// scanJob reads a row selected with jobColumns
+func scanJob(row rowScanner) (*models.AnalysisJob, error) {
+	var job models.AnalysisJob
+	var transactions, generation, result []byte
+	var startedAt, finishedAt sql.NullTime
+	err := row.Scan(&job.ID, &job.OrganizationID, &job.State, &transactions, &job.Language, &generation, &result, &job.Error,
+		&job.RequestID, &job.Attempts, &job.CreatedAt, &startedAt, &finishedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(transactions, &job.Transactions); err != nil {
+		return nil, fmt.Errorf("failed to decode job transactions: %w", err)
+	}
+	if len(generation) > 0 {
+		job.Generation = &models.GenerationOverride{}
+		if err := json.Unmarshal(generation, job.Generation); err != nil {
+			return nil, fmt.Errorf("failed to decode job generation override: %w", err)
+		}
+	}
+	if len(result) > 0 {
+		job.Result = &models.AnalyzeResponse{}
+		if err := json.Unmarshal(result, job.Result); err != nil {
+			return nil, fmt.Errorf("failed to decode job result: %w", err)
+		}
+	}
+	if startedAt.Valid {
+		job.StartedAt = &startedAt.Time
+	}
+	if finishedAt.Valid {
+		job.FinishedAt = &finishedAt.Time
+	}
+	return &job, nil
+}
+
+// EnqueueAnalysisJob queues an analysis request for the background workers
+func EnqueueAnalysisJob(ctx context.Context, orgID, requestID string, req models.AnalyzeRequest) (*models.AnalysisJob, error) {
+	data, err := json.Marshal(req.Transactions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode transactions: %w", err)
+	}
+	var generation []byte
+	if req.Generation != nil {
+		if generation, err = json.Marshal(req.Generation); err != nil {
+			return nil, fmt.Errorf("failed to encode generation override: %w", err)
+		}
+	}
+
+	job, err := scanJob(database.DB.QueryRowContext(ctx, `
+		INSERT INTO analysis_jobs (organization_id, transactions, language, generation, request_id)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING `+jobColumns, orgID, data, req.Language, generation, requestID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to enqueue analysis job: %w", err)
+	}
+	return job, nil
+}
+
+// GetAnalysisJob returns an organization's analysis job
+func GetAnalysisJob(orgID string, id int64) (*models.AnalysisJob, error) {
+	job, err := scanJob(database.DB.QueryRow(`
+		SELECT `+jobColumns+` FROM analysis_jobs WHERE organization_id = $1 AND id = $2
+	`, orgID, id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: %d", ErrJobNotFound, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query analysis job: %w", err)
+	}
+	return job, nil
+}
+
+// claimAnalysisJob marks the oldest queued job as running and returns it, or nil if the
+// queue is empty. SKIP LOCKED lets several workers and replicas claim jobs concurrently.
+// Running jobs whose lease expired, because their worker died, are claimed again until
+// they reach maxAttempts; after that they are marked failed.
+func claimAnalysisJob(ctx context.Context, lease time.Duration, maxAttempts int) (*models.AnalysisJob, error) {
+	_, err := database.DB.ExecContext(ctx, `
+		UPDATE analysis_jobs SET state = 'failed', lease_expires_at = NULL, finished_at = CURRENT_TIMESTAMP,
+			error = 'worker stopped responding on each of ' || attempts || ' attempts'
+		WHERE state = 'running' AND lease_expires_at < CURRENT_TIMESTAMP AND attempts >= $1
+	`, maxAttempts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fail abandoned analysis jobs: %w", err)
+	}
+
+	job, err := scanJob(database.DB.QueryRowContext(ctx, `
+		UPDATE analysis_jobs SET
+			state = 'running', attempts = attempts + 1, started_at = CURRENT_TIMESTAMP,
+			lease_expires_at = CURRENT_TIMESTAMP + $1 * INTERVAL '1 second'
+		WHERE id = (
+			SELECT id FROM analysis_jobs
+			WHERE state = 'queued'
+				OR (state = 'running' AND lease_expires_at < CURRENT_TIMESTAMP AND attempts < $2)
+			ORDER BY created_at, id
+			FOR UPDATE SKIP LOCKED
+			LIMIT 1
+		)
+		RETURNING `+jobColumns, lease.Seconds(), maxAttempts))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim analysis job: %w", err)
+	}
+	return job, nil
+}
+
+// renewAnalysisJobLease extends the lease of a job the worker still holds
+func renewAnalysisJobLease(ctx context.Context, job *models.AnalysisJob, lease time.Duration) error {
+	res, err := database.DB.ExecContext(ctx, `
+		UPDATE analysis_jobs SET lease_expires_at = CURRENT_TIMESTAMP + $3 * INTERVAL '1 second'
+		WHERE id = $1 AND attempts = $2 AND state = 'running'
+	`, job.ID, job.Attempts, lease.Seconds())
+	if err != nil {
+		return fmt.Errorf("failed to renew analysis job lease: %w", err)
+	}
+	return heldJobUpdated(res)
+}
+
+// heldJobUpdated maps an update of a job no longer held by the worker to errJobLeaseLost
+func heldJobUpdated(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return errJobLeaseLost
+	}
+	return nil
+}
+
+// finishAnalysisJob stores the outcome of a job. Only the worker holding the claim
+// (the same attempt, still running) may store it.
+func finishAnalysisJob(ctx context.Context, job *models.AnalysisJob, response *models.AnalyzeResponse, jobErr error) error {
+	state, message := models.JobSucceeded, ""
+	var result []byte
+	if jobErr != nil {
+		state, message = models.JobFailed, jobErr.Error()
+	} else {
+		var err error
+		if result, err = json.Marshal(response); err != nil {
+			return fmt.Errorf("failed to encode job result: %w", err)
+		}
+	}
+
+	res, err := database.DB.ExecContext(ctx, `
+		UPDATE analysis_jobs SET state = $3, result = $4, error = $5,
+			lease_expires_at = NULL, finished_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND attempts = $2 AND state = 'running'
+	`, job.ID, job.Attempts, state, result, message)
+	if err != nil {
+		return fmt.Errorf("failed to finish analysis job: %w", err)
+	}
+	return heldJobUpdated(res)
+}
+
+// requeueAnalysisJob returns an unfinished job the worker still holds to the queue
+func requeueAnalysisJob(ctx context.Context, job *models.AnalysisJob) error {
+	res, err := database.DB.ExecContext(ctx, `
+		UPDATE analysis_jobs SET state = 'queued', started_at = NULL, lease_expires_at = NULL
+		WHERE id = $1 AND attempts = $2 AND state = 'running'
+	`, job.ID, job.Attempts)
+	if err != nil {
+		return fmt.Errorf("failed to requeue analysis job: %w", err)
+	}
+	return heldJobUpdated(res)
+}
@@ -0,0 +1,134 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"halalguard-backend/logging"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// maxErrorBody bounds how much of an error response is kept in the error message
+const maxErrorBody = 512
+
+// httpStatusError is a non-2xx response of an HTTP model provider
+type httpStatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *httpStatusError) Error() string {
+	return fmt.Sprintf("HTTP %d: %s", e.StatusCode, e.Body)
+}
+
+// chatMessage is a message of the OpenAI and Ollama chat APIs
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// httpBackend holds what the HTTP model providers share: the client, the base URL
+// and error classification by HTTP status
+type httpBackend struct {
+	client  *http.Client
+	baseURL string
+	header  http.Header
+}
+
+func newHTTPBackend(baseURL string, timeout time.Duration, header http.Header) httpBackend {
+	return httpBackend{
+		client: &http.Client{
+			Timeout:   timeout,
+			Transport: otelhttp.NewTransport(http.DefaultTransport),
+		},
+		baseURL: strings.TrimRight(baseURL, "/"),
+		header:  header,
+	}
+}
+
+// post sends body as JSON to path and decodes a 2xx response into out. The request ID
+// of ctx is forwarded in the X-Request-ID header.
+func (b *httpBackend) post(ctx context.Context, path string, body, out interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.baseURL+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	for key, values := range b.header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if requestID := logging.RequestIDFrom(ctx); requestID != "" {
+		req.Header.Set("X-Request-ID", requestID)
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return &httpStatusError{StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(message))}
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// retryable treats rate limiting, server errors and network failures as transient
+func (b *httpBackend) retryable(err error) bool {
+	var statusErr *httpStatusError
+	if errors.As(err, &statusErr) {
+		switch statusErr.StatusCode {
+		case http.StatusRequestTimeout, http.StatusTooManyRequests:
+			return true
+		}
+		return statusErr.StatusCode >= 500
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// serviceFailure excludes requests the provider rejected as invalid
+func (b *httpBackend) serviceFailure(err error) bool {
+	var statusErr *httpStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode != http.StatusBadRequest && statusErr.StatusCode != http.StatusUnprocessableEntity
+	}
+	return true
+}
+
+func (b *httpBackend) errorReason(err error) string {
+	var statusErr *httpStatusError
+	if errors.As(err, &statusErr) {
+		return "http_" + strconv.Itoa(statusErr.StatusCode)
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		if netErr.Timeout() {
+			return "timeout"
+		}
+		return "unavailable"
+	}
+	return "unknown"
+}
+
+func (b *httpBackend) close() {
+	b.client.CloseIdleConnections()
+}
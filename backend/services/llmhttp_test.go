@@ -0,0 +1,59 @@
+package services
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// stubResponse is the status and body a stub model server answers with
+type stubResponse struct {
+	status int
+	body   string
+}
+
+// newStubServer serves response at path and records the decoded JSON body and the
+// headers of the last request
+func newStubServer(t *testing.T, path string, response stubResponse) (*httptest.Server, *map[string]interface{}, *http.Header) {
+	t.Helper()
+	var body map[string]interface{}
+	var header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != path {
+			t.Errorf("request = %s %s, want POST %s", r.Method, r.URL.Path, path)
+		}
+		data, _ := io.ReadAll(r.Body)
+		body = nil
+		if err := json.Unmarshal(data, &body); err != nil {
+			t.Errorf("request body is not JSON: %v", err)
+		}
+		header = r.Header.Clone()
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(response.status)
+		io.WriteString(w, response.body)
+	}))
+	t.Cleanup(server.Close)
+	return server, &body, &header
+}
+
+// errorCases are the responses every HTTP backend must classify alike
+var errorCases = []struct {
+	name           string
+	response       stubResponse
+	retryable      bool
+	serviceFailure bool
+	reason         string
+}{
+	{"rate limited", stubResponse{http.StatusTooManyRequests, `{"error":"slow down"}`}, true, true, "http_429"},
+	{"request timeout", stubResponse{http.StatusRequestTimeout, ``}, true, true, "http_408"},
+	{"server error", stubResponse{http.StatusInternalServerError, `boom`}, true, true, "http_500"},
+	{"bad gateway", stubResponse{http.StatusBadGateway, ``}, true, true, "http_502"},
+	{"unavailable", stubResponse{http.StatusServiceUnavailable, ``}, true, true, "http_503"},
+	{"bad request", stubResponse{http.StatusBadRequest, `{"error":"bad model"}`}, false, false, "http_400"},
+	{"unprocessable", stubResponse{http.StatusUnprocessableEntity, ``}, false, false, "http_422"},
+	{"unauthorized", stubResponse{http.StatusUnauthorized, ``}, false, true, "http_401"},
+	{"not found", stubResponse{http.StatusNotFound, ``}, false, true, "http_404"},
+	{"malformed JSON", stubResponse{http.StatusOK, `{"choices": [`}, false, true, "unknown"},
+}
@@ -0,0 +1,27 @@
+package services
+
+// Principle describes a weighted scoring dimension used in the analysis
+type Principle struct {
+	Key         string
+	Name        string
+	Description string
+	Weight      float64
+}
+
+// CompliancePrinciples are the five Sharia principles behind the compliance score
+var CompliancePrinciples = []Principle{
+	{Key: "riba", Name: "Riba", Description: "Bebas bunga.", Weight: 0.30},
+	{Key: "gharar", Name: "Gharar", Description: "Kejelasan akad.", Weight: 0.25},
+	{Key: "maysir", Name: "Maysir", Description: "Bebas judi.", Weight: 0.20},
+	{Key: "halal", Name: "Halal Goods", Description: "Objek halal.", Weight: 0.15},
+	{Key: "justice", Name: "Justice/Keadilan", Description: "Kewajaran harga.", Weight: 0.10},
+}
+
+// MaslahahDimensions are the social impact dimensions behind the maslahah score
+var MaslahahDimensions = []Principle{
+	{Key: "economicJustice", Name: "Keadilan Ekonomi", Description: "Distribusi kekayaan, pengentasan kemiskinan.", Weight: 0.30},
+	{Key: "communityDevelopment", Name: "Pengembangan Komunitas", Description: "Lapangan kerja, infrastruktur lokal.", Weight: 0.25},
+	{Key: "educationalImpact", Name: "Dampak Pendidikan", Description: "Peningkatan skill, literasi.", Weight: 0.20},
+	{Key: "environmental", Name: "Kelestarian Lingkungan", Description: "Green investment, keberlanjutan.", Weight: 0.15},
+	{Key: "socialCohesion", Name: "Kohesi Sosial", Description: "Kepercayaan komunitas, integrasi sosial.", Weight: 0.10},
+}
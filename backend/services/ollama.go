@@ -0,0 +1,78 @@
+package services
+
+import (
+	"context"
+
+	"halalguard-backend/config"
+	"halalguard-backend/models"
+	"halalguard-backend/prompts"
+)
+
+// ollamaBackend calls the chat API of an Ollama server
+type ollamaBackend struct {
+	httpBackend
+}
+
+type ollamaRequest struct {
+	Model    string        `json:"model"`
+	Messages []chatMessage `json:"messages"`
+	Stream   bool          `json:"stream"`
+	Format   string        `json:"format"`
+	Options  ollamaOptions `json:"options"`
+}
+
+type ollamaOptions struct {
+	Temperature float32 `json:"temperature"`
+	TopP        float32 `json:"top_p"`
+	TopK        int32   `json:"top_k"`
+	NumPredict  int32   `json:"num_predict"`
+	Seed        *int32  `json:"seed,omitempty"`
+}
+
+type ollamaResponse struct {
+	Message         chatMessage `json:"message"`
+	PromptEvalCount int64       `json:"prompt_eval_count"`
+	EvalCount       int64       `json:"eval_count"`
+}
+
+func newOllamaBackend(cfg config.OllamaConfig) *ollamaBackend {
+	return &ollamaBackend{httpBackend: newHTTPBackend(cfg.BaseURL, cfg.Timeout, nil)}
+}
+
+func (b *ollamaBackend) provider() string {
+	return ProviderOllama
+}
+
+// complete posts a non-streaming request to /api/chat in JSON mode
+func (b *ollamaBackend) complete(ctx context.Context, generation models.GenerationSettings, rendered *prompts.Rendered) (string, models.TokenUsage, error) {
+	request := ollamaRequest{
+		Model: generation.Model,
+		Messages: []chatMessage{
+			{Role: "system", Content: rendered.System},
+			{Role: "user", Content: rendered.Prompt},
+		},
+		Format: "json",
+		Options: ollamaOptions{
+			Temperature: generation.Temperature,
+			TopP:        generation.TopP,
+			TopK:        generation.TopK,
+			NumPredict:  generation.MaxOutputTokens,
+			Seed:        generation.Seed,
+		},
+	}
+
+	var response ollamaResponse
+	if err := b.post(ctx, "/api/chat", request, &response); err != nil {
+		return "", models.TokenUsage{}, err
+	}
+
+	usage := models.TokenUsage{
+		PromptTokens:    response.PromptEvalCount,
+		CandidateTokens: response.EvalCount,
+		TotalTokens:     response.PromptEvalCount + response.EvalCount,
+	}
+	if response.Message.Content == "" {
+		return "", usage, errEmptyResponse
+	}
+	return response.Message.Content, usage, nil
+}
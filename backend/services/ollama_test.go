@@ -0,0 +1,100 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"halalguard-backend/config"
+	"halalguard-backend/models"
+)
+
+func TestOllamaComplete(t *testing.T) {
+	server, body, _ := newStubServer(t, "/api/chat", stubResponse{http.StatusOK, `{
+		"model": "llama3.1",
+		"message": {"role": "assistant", "content": "[{\"transactionId\":\"TXN001\"}]"},
+		"done": true,
+		"prompt_eval_count": 95,
+		"eval_count": 40
+	}`})
+	backend := newOllamaBackend(config.OllamaConfig{BaseURL: server.URL, Timeout: time.Second})
+	defer backend.close()
+
+	text, usage, err := backend.complete(context.Background(), models.GenerationSettings{
+		Model: "llama3.1", Temperature: 0.2, TopP: 0.9, TopK: 40, MaxOutputTokens: 2048,
+	}, testRendered)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != `[{"transactionId":"TXN001"}]` {
+		t.Errorf("text = %q", text)
+	}
+	want := models.TokenUsage{PromptTokens: 95, CandidateTokens: 40, TotalTokens: 135}
+	if !reflect.DeepEqual(usage, want) {
+		t.Errorf("usage = %+v, want %+v", usage, want)
+	}
+
+	wantBody := map[string]interface{}{
+		"model": "llama3.1",
+		"messages": []interface{}{
+			map[string]interface{}{"role": "system", "content": "system prompt"},
+			map[string]interface{}{"role": "user", "content": "analysis prompt"},
+		},
+		"stream": false,
+		"format": "json",
+		"options": map[string]interface{}{
+			"temperature": 0.2,
+			"top_p":       0.9,
+			"top_k":       float64(40),
+			"num_predict": float64(2048),
+		},
+	}
+	if !reflect.DeepEqual(*body, wantBody) {
+		t.Errorf("request body = %v, want %v", *body, wantBody)
+	}
+}
+
+func TestOllamaCompleteEmpty(t *testing.T) {
+	server, _, _ := newStubServer(t, "/api/chat", stubResponse{http.StatusOK, `{
+		"message": {"role": "assistant", "content": ""},
+		"prompt_eval_count": 12,
+		"eval_count": 0
+	}`})
+	backend := newOllamaBackend(config.OllamaConfig{BaseURL: server.URL, Timeout: time.Second})
+	defer backend.close()
+
+	_, usage, err := backend.complete(context.Background(), models.GenerationSettings{Model: "m"}, testRendered)
+	if !errors.Is(err, errEmptyResponse) {
+		t.Errorf("err = %v, want errEmptyResponse", err)
+	}
+	if usage.TotalTokens != 12 {
+		t.Errorf("usage of an empty response = %d, want 12", usage.TotalTokens)
+	}
+}
+
+func TestOllamaErrors(t *testing.T) {
+	for _, tc := range errorCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server, _, _ := newStubServer(t, "/api/chat", tc.response)
+			backend := newOllamaBackend(config.OllamaConfig{BaseURL: server.URL, Timeout: time.Second})
+			defer backend.close()
+
+			_, _, err := backend.complete(context.Background(), models.GenerationSettings{Model: "m"}, testRendered)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if got := backend.retryable(err); got != tc.retryable {
+				t.Errorf("retryable = %v, want %v (err %v)", got, tc.retryable, err)
+			}
+			if got := backend.serviceFailure(err); got != tc.serviceFailure {
+				t.Errorf("serviceFailure = %v, want %v", got, tc.serviceFailure)
+			}
+			if got := backend.errorReason(err); got != tc.reason {
+				t.Errorf("errorReason = %q, want %q", got, tc.reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"context"
+	"net/http"
+
+	"halalguard-backend/config"
+	"halalguard-backend/models"
+	"halalguard-backend/prompts"
+)
+
+// openAIBackend calls an OpenAI-compatible chat completions endpoint, such as the
+// OpenAI API or a self-hosted vLLM or llama.cpp server
+type openAIBackend struct {
+	httpBackend
+}
+
+type openAIRequest struct {
+	Model       string        `json:"model"`
+	Messages    []chatMessage `json:"messages"`
+	Temperature float32       `json:"temperature"`
+	TopP        float32       `json:"top_p"`
+	MaxTokens   int32         `json:"max_tokens"`
+	Seed        *int32        `json:"seed,omitempty"`
+}
+
+type openAIResponse struct {
+	Choices []struct {
+		Message chatMessage `json:"message"`
+	} `json:"choices"`
+	Usage struct {
+		PromptTokens     int64 `json:"prompt_tokens"`
+		CompletionTokens int64 `json:"completion_tokens"`
+		TotalTokens      int64 `json:"total_tokens"`
+	} `json:"usage"`
+}
+
+func newOpenAIBackend(cfg config.OpenAIConfig) *openAIBackend {
+	header := make(http.Header)
+	if cfg.APIKey != "" {
+		header.Set("Authorization", "Bearer "+cfg.APIKey)
+	}
+	return &openAIBackend{httpBackend: newHTTPBackend(cfg.BaseURL, cfg.Timeout, header)}
+}
+
+func (b *openAIBackend) provider() string {
+	return ProviderOpenAI
+}
+
+// complete posts to /chat/completions. The chat completions API has no top_k, so the
+// configured top-k is not sent.
+func (b *openAIBackend) complete(ctx context.Context, generation models.GenerationSettings, rendered *prompts.Rendered) (string, models.TokenUsage, error) {
+	request := openAIRequest{
+		Model: generation.Model,
+		Messages: []chatMessage{
+			{Role: "system", Content: rendered.System},
+			{Role: "user", Content: rendered.Prompt},
+		},
+		Temperature: generation.Temperature,
+		TopP:        generation.TopP,
+		MaxTokens:   generation.MaxOutputTokens,
+		Seed:        generation.Seed,
+	}
+
+	var response openAIResponse
+	if err := b.post(ctx, "/chat/completions", request, &response); err != nil {
+		return "", models.TokenUsage{}, err
+	}
+
+	usage := models.TokenUsage{
+		PromptTokens:    response.Usage.PromptTokens,
+		CandidateTokens: response.Usage.CompletionTokens,
+		TotalTokens:     response.Usage.TotalTokens,
+	}
+	if len(response.Choices) == 0 || response.Choices[0].Message.Content == "" {
+		return "", usage, errEmptyResponse
+	}
+	return response.Choices[0].Message.Content, usage, nil
+}
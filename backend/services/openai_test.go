@@ -0,0 +1,114 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"halalguard-backend/config"
+	"halalguard-backend/models"
+	"halalguard-backend/prompts"
+)
+
+var testRendered = &prompts.Rendered{System: "system prompt", Prompt: "analysis prompt"}
+
+func TestOpenAIComplete(t *testing.T) {
+	server, body, header := newStubServer(t, "/v1/chat/completions", stubResponse{http.StatusOK, `{
+		"choices": [{"message": {"role": "assistant", "content": "[{\"transactionId\":\"TXN001\"}]"}}],
+		"usage": {"prompt_tokens": 120, "completion_tokens": 30, "total_tokens": 150}
+	}`})
+	backend := newOpenAIBackend(config.OpenAIConfig{BaseURL: server.URL + "/v1/", APIKey: "sk-test", Timeout: time.Second})
+	defer backend.close()
+
+	seed := int32(7)
+	text, usage, err := backend.complete(context.Background(), models.GenerationSettings{
+		Model: "gpt-4o-mini", Temperature: 0.2, TopP: 0.9, TopK: 40, MaxOutputTokens: 2048, Seed: &seed,
+	}, testRendered)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != `[{"transactionId":"TXN001"}]` {
+		t.Errorf("text = %q", text)
+	}
+	want := models.TokenUsage{PromptTokens: 120, CandidateTokens: 30, TotalTokens: 150}
+	if !reflect.DeepEqual(usage, want) {
+		t.Errorf("usage = %+v, want %+v", usage, want)
+	}
+
+	wantBody := map[string]interface{}{
+		"model": "gpt-4o-mini",
+		"messages": []interface{}{
+			map[string]interface{}{"role": "system", "content": "system prompt"},
+			map[string]interface{}{"role": "user", "content": "analysis prompt"},
+		},
+		"temperature": 0.2,
+		"top_p":       0.9,
+		"max_tokens":  float64(2048),
+		"seed":        float64(7),
+	}
+	if !reflect.DeepEqual(*body, wantBody) {
+		t.Errorf("request body = %v, want %v", *body, wantBody)
+	}
+	if got := header.Get("Authorization"); got != "Bearer sk-test" {
+		t.Errorf("Authorization = %q, want bearer API key", got)
+	}
+}
+
+func TestOpenAICompleteEmpty(t *testing.T) {
+	server, _, _ := newStubServer(t, "/chat/completions", stubResponse{http.StatusOK, `{
+		"choices": [],
+		"usage": {"prompt_tokens": 10, "completion_tokens": 0, "total_tokens": 10}
+	}`})
+	backend := newOpenAIBackend(config.OpenAIConfig{BaseURL: server.URL, Timeout: time.Second})
+	defer backend.close()
+
+	_, usage, err := backend.complete(context.Background(), models.GenerationSettings{Model: "m"}, testRendered)
+	if !errors.Is(err, errEmptyResponse) {
+		t.Errorf("err = %v, want errEmptyResponse", err)
+	}
+	if usage.TotalTokens != 10 {
+		t.Errorf("usage of an empty response = %d, want 10", usage.TotalTokens)
+	}
+}
+
+func TestOpenAIErrors(t *testing.T) {
+	for _, tc := range errorCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server, _, _ := newStubServer(t, "/chat/completions", tc.response)
+			backend := newOpenAIBackend(config.OpenAIConfig{BaseURL: server.URL, Timeout: time.Second})
+			defer backend.close()
+
+			_, _, err := backend.complete(context.Background(), models.GenerationSettings{Model: "m"}, testRendered)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if got := backend.retryable(err); got != tc.retryable {
+				t.Errorf("retryable = %v, want %v (err %v)", got, tc.retryable, err)
+			}
+			if got := backend.serviceFailure(err); got != tc.serviceFailure {
+				t.Errorf("serviceFailure = %v, want %v", got, tc.serviceFailure)
+			}
+			if got := backend.errorReason(err); got != tc.reason {
+				t.Errorf("errorReason = %q, want %q", got, tc.reason)
+			}
+		})
+	}
+}
+
+func TestOpenAIUnreachable(t *testing.T) {
+	server, _, _ := newStubServer(t, "/chat/completions", stubResponse{http.StatusOK, `{}`})
+	server.Close()
+	backend := newOpenAIBackend(config.OpenAIConfig{BaseURL: server.URL, Timeout: time.Second})
+	defer backend.close()
+
+	_, _, err := backend.complete(context.Background(), models.GenerationSettings{Model: "m"}, testRendered)
+	if err == nil || !backend.retryable(err) {
+		t.Errorf("err = %v, want a retryable network error", err)
+	}
+	if got := backend.errorReason(err); got != "unavailable" {
+		t.Errorf("errorReason = %q, want unavailable", got)
+	}
+}
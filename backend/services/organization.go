@@ -0,0 +1,133 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"halalguard-backend/database"
+	"halalguard-backend/models"
+
+	"github.com/lib/pq"
+)
+
+// ErrOrganizationNotFound is returned for unknown organization IDs
+var ErrOrganizationNotFound = errors.New("organization not found")
+
+const organizationColumns = `id, name, model, language, rule_packs, created_at, updated_at`
+
+func scanOrganization(row rowScanner) (*models.Organization, error) {
+	var org models.Organization
+	err := row.Scan(&org.ID, &org.Name, &org.Model, &org.Language, pq.Array(&org.RulePacks), &org.CreatedAt, &org.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if org.RulePacks == nil {
+		org.RulePacks = []string{}
+	}
+	return &org, nil
+}
+
+// GetOrganization returns a tenant with its analysis settings
+func GetOrganization(orgID string) (*models.Organization, error) {
+	org, err := scanOrganization(database.DB.QueryRow(`SELECT `+organizationColumns+` FROM organizations WHERE id = $1`, orgID))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: %s", ErrOrganizationNotFound, orgID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organization: %w", err)
+	}
+	return org, nil
+}
+
+// GetOrganizations lists every tenant
+func GetOrganizations() ([]models.Organization, error) {
+	rows, err := database.DB.Query(`SELECT ` + organizationColumns + ` FROM organizations ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query organizations: %w", err)
+	}
+	defer rows.Close()
+
+	orgs := []models.Organization{}
+	for rows.Next() {
+		org, err := scanOrganization(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan organization: %w", err)
+		}
+		orgs = append(orgs, *org)
+	}
+
+	return orgs, rows.Err()
+}
+
+// SaveOrganization creates or renames a tenant. New tenants start with a copy
+// of the default organization's approval chains and screening methodologies.
+func SaveOrganization(orgID, name string) (*models.Organization, error) {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var inserted bool
+	err = tx.QueryRow(`
+		INSERT INTO organizations (id, name) VALUES ($1, $2)
+		ON CONFLICT (id) DO UPDATE SET name = EXCLUDED.name, updated_at = CURRENT_TIMESTAMP
+		RETURNING (xmax = 0)
+	`, orgID, name).Scan(&inserted)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save organization: %w", err)
+	}
+
+	if inserted {
+		_, err = tx.Exec(`
+			INSERT INTO approval_chains (organization_id, name, transaction_type, min_amount, steps)
+			SELECT $1, name, transaction_type, min_amount, steps
+			FROM approval_chains WHERE organization_id = $2
+			ON CONFLICT (organization_id, name) DO NOTHING
+		`, orgID, models.DefaultOrganizationID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to copy default approval chains: %w", err)
+		}
+		_, err = tx.Exec(`
+			INSERT INTO screening_methodologies (organization_id, name, debt_denominator, max_debt_ratio, max_non_halal_income_ratio, max_cash_securities_ratio)
+			SELECT $1, name, debt_denominator, max_debt_ratio, max_non_halal_income_ratio, max_cash_securities_ratio
+			FROM screening_methodologies WHERE organization_id = $2
+			ON CONFLICT (organization_id, name) DO NOTHING
+		`, orgID, models.DefaultOrganizationID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to copy default screening methodologies: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit organization: %w", err)
+	}
+
+	return GetOrganization(orgID)
+}
+
+// UpdateTenantSettings changes the model, language and rule packs used for a tenant's analyses
+func UpdateTenantSettings(orgID string, settings models.TenantSettings) (*models.Organization, error) {
+	if settings.Language == "" {
+		settings.Language = models.LanguageIndonesian
+	}
+	if settings.RulePacks == nil {
+		settings.RulePacks = []string{}
+	}
+
+	query := `
+		UPDATE organizations
+		SET model = $2, language = $3, rule_packs = $4, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		RETURNING ` + organizationColumns
+
+	org, err := scanOrganization(database.DB.QueryRow(query, orgID, settings.Model, settings.Language, pq.Array(settings.RulePacks)))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: %s", ErrOrganizationNotFound, orgID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update organization settings: %w", err)
+	}
+	return org, nil
+}
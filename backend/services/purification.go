@@ -0,0 +1,182 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"halalguard-backend/config"
+	"halalguard-backend/database"
+	"halalguard-backend/models"
+
+	"github.com/lib/pq"
+)
+
+// PurificationRatio returns the share of income that must be purified for a violation type.
+// Riba and Maysir income is purified in full, Syubhat income by the configured ratio.
+func PurificationRatio(violationType string, syubhatRatio float64) float64 {
+	switch violationType {
+	case models.ViolationRiba, models.ViolationMaysir:
+		return 1
+	case models.ViolationSyubhat:
+		return syubhatRatio
+	default:
+		return 0
+	}
+}
+
+// GetPurificationSummary computes an organization's purification obligations for income
+// transactions dated between from and to (both optional) and offsets them with recorded donations
+func GetPurificationSummary(orgID string, cfg config.PurificationConfig, from, to string) (*models.PurificationSummary, error) {
+	incomeTypes := make([]string, len(cfg.IncomeTypes))
+	for i, t := range cfg.IncomeTypes {
+		incomeTypes[i] = strings.ToLower(t)
+	}
+
+	query := `
+		SELECT t.id, t.description, t.date, t.type, t.amount, a.violation_type, COALESCE(d.donated, 0)
+		FROM transactions t
+		JOIN ` + analysisJoinOn + `
+		LEFT JOIN (
+			SELECT transaction_id, SUM(amount) AS donated
+			FROM purification_donations
+			WHERE organization_id = $1 AND transaction_id IS NOT NULL
+			GROUP BY transaction_id
+		) d ON d.transaction_id = t.id
+		WHERE t.organization_id = $1
+			AND a.violation_type = ANY($2)
+			AND LOWER(t.type) = ANY($3)
+			AND ($4 = '' OR try_date(t.date) >= NULLIF($4, '')::date)
+			AND ($5 = '' OR try_date(t.date) <= NULLIF($5, '')::date)
+		ORDER BY t.date, t.id
+	`
+
+	violations := []string{models.ViolationRiba, models.ViolationMaysir, models.ViolationSyubhat}
+	rows, err := database.DB.Query(query, orgID, pq.Array(violations), pq.Array(incomeTypes), from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query purification items: %w", err)
+	}
+	defer rows.Close()
+
+	summary := &models.PurificationSummary{
+		SyubhatRatio: cfg.SyubhatRatio,
+		Items:        []models.PurificationItem{},
+		Periods:      []models.PurificationPeriod{},
+	}
+	periods := map[string]*models.PurificationPeriod{}
+
+	for rows.Next() {
+		var item models.PurificationItem
+		if err := rows.Scan(&item.TransactionID, &item.Description, &item.Date, &item.Type,
+			&item.Amount, &item.ViolationType, &item.Donated); err != nil {
+			return nil, fmt.Errorf("failed to scan purification item: %w", err)
+		}
+
+		item.Ratio = PurificationRatio(item.ViolationType, cfg.SyubhatRatio)
+		item.Obligation = roundAmount(math.Abs(item.Amount) * item.Ratio)
+		item.Outstanding = math.Max(0, roundAmount(item.Obligation-item.Donated))
+		summary.Items = append(summary.Items, item)
+
+		key := periodOf(item.Date)
+		period, ok := periods[key]
+		if !ok {
+			period = &models.PurificationPeriod{Period: key}
+			periods[key] = period
+		}
+		period.Obligation += item.Obligation
+		period.Donated += item.Donated
+		period.Outstanding += item.Outstanding
+
+		summary.TotalObligation += item.Obligation
+		summary.TotalDonated += item.Donated
+		summary.Outstanding += item.Outstanding
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read purification items: %w", err)
+	}
+
+	unallocatedQuery := `
+		SELECT COALESCE(SUM(amount), 0)
+		FROM purification_donations
+		WHERE organization_id = $1 AND transaction_id IS NULL
+			AND ($2 = '' OR try_date(date) >= NULLIF($2, '')::date)
+			AND ($3 = '' OR try_date(date) <= NULLIF($3, '')::date)
+	`
+	if err := database.DB.QueryRow(unallocatedQuery, orgID, from, to).Scan(&summary.UnallocatedDonations); err != nil {
+		return nil, fmt.Errorf("failed to query unallocated donations: %w", err)
+	}
+
+	summary.TotalDonated = roundAmount(summary.TotalDonated + summary.UnallocatedDonations)
+	summary.TotalObligation = roundAmount(summary.TotalObligation)
+	summary.Outstanding = math.Max(0, roundAmount(summary.Outstanding-summary.UnallocatedDonations))
+
+	for _, period := range periods {
+		summary.Periods = append(summary.Periods, *period)
+	}
+	sort.Slice(summary.Periods, func(i, j int) bool {
+		return summary.Periods[i].Period < summary.Periods[j].Period
+	})
+
+	return summary, nil
+}
+
+// SaveDonation records an organization's purification donation, optionally allocated to a transaction
+func SaveDonation(orgID string, input models.DonationInput) (*models.Donation, error) {
+	query := `
+		INSERT INTO purification_donations (organization_id, transaction_id, amount, date, recipient, note)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`
+
+	transactionID := sql.NullString{String: input.TransactionID, Valid: input.TransactionID != ""}
+	donation := &models.Donation{DonationInput: input}
+
+	err := database.DB.QueryRow(query, orgID, transactionID, input.Amount, input.Date, input.Recipient, input.Note).
+		Scan(&donation.ID, &donation.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save donation: %w", err)
+	}
+
+	return donation, nil
+}
+
+// GetDonations lists an organization's recorded donations, newest first
+func GetDonations(orgID string) ([]models.Donation, error) {
+	query := `
+		SELECT id, COALESCE(transaction_id, ''), amount, date, recipient, COALESCE(note, ''), created_at
+		FROM purification_donations
+		WHERE organization_id = $1
+		ORDER BY date DESC, id DESC
+	`
+
+	rows, err := database.DB.Query(query, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query donations: %w", err)
+	}
+	defer rows.Close()
+
+	donations := []models.Donation{}
+	for rows.Next() {
+		var d models.Donation
+		if err := rows.Scan(&d.ID, &d.TransactionID, &d.Amount, &d.Date, &d.Recipient, &d.Note, &d.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan donation: %w", err)
+		}
+		donations = append(donations, d)
+	}
+
+	return donations, rows.Err()
+}
+
+// periodOf returns the YYYY-MM month of a YYYY-MM-DD date
+func periodOf(date string) string {
+	if len(date) >= 7 {
+		return date[:7]
+	}
+	return date
+}
+
+func roundAmount(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
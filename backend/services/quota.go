@@ -0,0 +1,197 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"halalguard-backend/database"
+	"halalguard-backend/models"
+)
+
+// ErrQuotaExceeded is matched by every *QuotaExceededError
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+// QuotaExceededError describes which tenant quota blocked a request
+type QuotaExceededError struct {
+	// Scope names the exhausted quota, e.g. "daily transactions"
+	Scope   string
+	Limit   int64
+	Used    int64
+	ResetAt time.Time
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("%s quota exceeded: %d of %d used, resets at %s",
+		e.Scope, e.Used, e.Limit, e.ResetAt.Format(time.RFC3339))
+}
+
+// Is makes errors.Is(err, ErrQuotaExceeded) match
+func (e *QuotaExceededError) Is(target error) bool {
+	return target == ErrQuotaExceeded
+}
+
+// quotaPeriods returns the current day and month usage windows in UTC
+func quotaPeriods(now time.Time) (day, month models.QuotaUsage) {
+	now = now.UTC()
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	day = models.QuotaUsage{
+		Period:  models.QuotaPeriodDay,
+		Start:   dayStart.Format("2006-01-02"),
+		ResetAt: dayStart.AddDate(0, 0, 1),
+	}
+	month = models.QuotaUsage{
+		Period:  models.QuotaPeriodMonth,
+		Start:   monthStart.Format("2006-01-02"),
+		ResetAt: monthStart.AddDate(0, 1, 0),
+	}
+	return day, month
+}
+
+// tenantQuota returns the quota limits of an organization
+func tenantQuota(ctx context.Context, q querier, orgID string) (*models.TenantQuota, error) {
+	var quota models.TenantQuota
+	err := q.QueryRowContext(ctx, `
+		SELECT daily_transaction_quota, monthly_transaction_quota, daily_token_quota, monthly_token_quota
+		FROM organizations WHERE id = $1
+	`, orgID).Scan(&quota.DailyTransactions, &quota.MonthlyTransactions, &quota.DailyTokens, &quota.MonthlyTokens)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: %s", ErrOrganizationNotFound, orgID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query quota: %w", err)
+	}
+	return &quota, nil
+}
+
+// SetTenantQuota changes the quota limits of an organization
+func SetTenantQuota(orgID string, quota models.TenantQuota) error {
+	res, err := database.DB.Exec(`
+		UPDATE organizations
+		SET daily_transaction_quota = $2, monthly_transaction_quota = $3,
+			daily_token_quota = $4, monthly_token_quota = $5, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+	`, orgID, quota.DailyTransactions, quota.MonthlyTransactions, quota.DailyTokens, quota.MonthlyTokens)
+	if err != nil {
+		return fmt.Errorf("failed to save quota: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("%w: %s", ErrOrganizationNotFound, orgID)
+	}
+	return nil
+}
+
+// GetQuotaStatus returns an organization's quota limits and its usage in the current day and month
+func GetQuotaStatus(orgID string) (*models.QuotaStatus, error) {
+	quota, err := tenantQuota(context.Background(), database.DB, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &models.QuotaStatus{OrganizationID: orgID, Limits: *quota}
+	status.Daily, status.Monthly = quotaPeriods(time.Now())
+
+	for _, usage := range []*models.QuotaUsage{&status.Daily, &status.Monthly} {
+		err := database.DB.QueryRow(`
+			SELECT transactions, tokens FROM usage_counters
+			WHERE organization_id = $1 AND period = $2 AND period_start = $3
+		`, orgID, usage.Period, usage.Start).Scan(&usage.Transactions, &usage.Tokens)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, fmt.Errorf("failed to query usage: %w", err)
+		}
+	}
+
+	return status, nil
+}
+
+// ReserveTransactions counts n transactions against the organization's daily and
+// monthly quotas, failing with a *QuotaExceededError when a transaction or token
+// quota would be exceeded. Counters are locked so concurrent batches cannot overshoot.
+func ReserveTransactions(ctx context.Context, orgID string, n int) error {
+	tx, err := database.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	quota, err := tenantQuota(ctx, tx, orgID)
+	if err != nil {
+		return err
+	}
+
+	day, month := quotaPeriods(time.Now())
+	limits := []struct {
+		usage             *models.QuotaUsage
+		transactionsLimit int64
+		tokensLimit       int64
+	}{
+		{&day, quota.DailyTransactions, quota.DailyTokens},
+		{&month, quota.MonthlyTransactions, quota.MonthlyTokens},
+	}
+
+	for _, limit := range limits {
+		usage := limit.usage
+		err := tx.QueryRowContext(ctx, `
+			INSERT INTO usage_counters (organization_id, period, period_start)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (organization_id, period, period_start) DO UPDATE SET period = EXCLUDED.period
+			RETURNING transactions, tokens
+		`, orgID, usage.Period, usage.Start).Scan(&usage.Transactions, &usage.Tokens)
+		if err != nil {
+			return fmt.Errorf("failed to lock usage counter: %w", err)
+		}
+
+		periodName := "daily"
+		if usage.Period == models.QuotaPeriodMonth {
+			periodName = "monthly"
+		}
+		if limit.transactionsLimit > 0 && usage.Transactions+int64(n) > limit.transactionsLimit {
+			return &QuotaExceededError{
+				Scope: periodName + " transactions", Limit: limit.transactionsLimit,
+				Used: usage.Transactions, ResetAt: usage.ResetAt,
+			}
+		}
+		if limit.tokensLimit > 0 && usage.Tokens >= limit.tokensLimit {
+			return &QuotaExceededError{
+				Scope: periodName + " tokens", Limit: limit.tokensLimit,
+				Used: usage.Tokens, ResetAt: usage.ResetAt,
+			}
+		}
+	}
+
+	if err := addUsage(ctx, tx, orgID, int64(n), 0); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit usage: %w", err)
+	}
+	return nil
+}
+
+// ReleaseTransactions returns reserved transactions to the quota after a failed analysis
+func ReleaseTransactions(ctx context.Context, orgID string, n int) error {
+	return addUsage(ctx, database.DB, orgID, -int64(n), 0)
+}
+
+// addUsage adjusts the current day and month counters of an organization
+func addUsage(ctx context.Context, q querier, orgID string, transactions, tokens int64) error {
+	day, month := quotaPeriods(time.Now())
+	for _, usage := range []models.QuotaUsage{day, month} {
+		_, err := q.ExecContext(ctx, `
+			INSERT INTO usage_counters (organization_id, period, period_start, transactions, tokens)
+			VALUES ($1, $2, $3, GREATEST($4::bigint, 0), GREATEST($5::bigint, 0))
+			ON CONFLICT (organization_id, period, period_start) DO UPDATE SET
+				transactions = GREATEST(usage_counters.transactions + $4::bigint, 0),
+				tokens = GREATEST(usage_counters.tokens + $5::bigint, 0)
+		`, orgID, usage.Period, usage.Start, transactions, tokens)
+		if err != nil {
+			return fmt.Errorf("failed to record usage: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+
+	"halalguard-backend/database"
+	"halalguard-backend/models"
+
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrRoleNotFound is returned for roles missing from the roles table
+	ErrRoleNotFound = errors.New("role not found")
+	// ErrUnknownPermission is returned when granting a permission that does not exist
+	ErrUnknownPermission = errors.New("unknown permission")
+	// ErrAdminRoleImmutable is returned when editing the admin role, which always holds every permission
+	ErrAdminRoleImmutable = errors.New("admin role permissions cannot be changed")
+)
+
+// HasPermission reports whether the role grants the permission in the organization.
+// An organization's own grants for the role replace the default grants in role_permissions.
+func HasPermission(orgID, role, permission string) (bool, error) {
+	var granted bool
+	query := `
+		SELECT COALESCE(
+			(SELECT $3 = ANY(permissions) FROM organization_role_permissions WHERE organization_id = $1 AND role = $2),
+			EXISTS (SELECT 1 FROM role_permissions WHERE role = $2 AND permission = $3)
+		)
+	`
+	if err := database.DB.QueryRow(query, orgID, role, permission).Scan(&granted); err != nil {
+		return false, fmt.Errorf("failed to check permission: %w", err)
+	}
+	return granted, nil
+}
+
+// RoleExists reports whether the role is defined
+func RoleExists(role string) (bool, error) {
+	var exists bool
+	if err := database.DB.QueryRow(`SELECT EXISTS (SELECT 1 FROM roles WHERE name = $1)`, role).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check role: %w", err)
+	}
+	return exists, nil
+}
+
+// GetRoles lists the roles with the permissions each grants in the organization
+func GetRoles(orgID string) ([]models.Role, error) {
+	query := `
+		SELECT r.name, r.description,
+			COALESCE(orp.permissions, COALESCE(
+				ARRAY_AGG(rp.permission ORDER BY rp.permission) FILTER (WHERE rp.permission IS NOT NULL), '{}'))
+		FROM roles r
+		LEFT JOIN role_permissions rp ON rp.role = r.name
+		LEFT JOIN organization_role_permissions orp ON orp.organization_id = $1 AND orp.role = r.name
+		GROUP BY r.name, r.description, orp.permissions
+		ORDER BY r.name
+	`
+
+	rows, err := database.DB.Query(query, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query roles: %w", err)
+	}
+	defer rows.Close()
+
+	roles := []models.Role{}
+	for rows.Next() {
+		var role models.Role
+		if err := rows.Scan(&role.Name, &role.Description, pq.Array(&role.Permissions)); err != nil {
+			return nil, fmt.Errorf("failed to scan role: %w", err)
+		}
+		roles = append(roles, role)
+	}
+
+	return roles, rows.Err()
+}
+
+// GetPermissions lists every permission that can be granted
+func GetPermissions() ([]models.Permission, error) {
+	rows, err := database.DB.Query(`SELECT name, description FROM permissions ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query permissions: %w", err)
+	}
+	defer rows.Close()
+
+	permissions := []models.Permission{}
+	for rows.Next() {
+		var permission models.Permission
+		if err := rows.Scan(&permission.Name, &permission.Description); err != nil {
+			return nil, fmt.Errorf("failed to scan permission: %w", err)
+		}
+		permissions = append(permissions, permission)
+	}
+
+	return permissions, rows.Err()
+}
+
+// SetRolePermissions replaces the permissions a role grants in the organization. The
+// default grants in role_permissions, and other organizations, are unaffected.
+func SetRolePermissions(orgID, role string, permissions []string) (*models.Role, error) {
+	if role == models.RoleAdmin {
+		return nil, ErrAdminRoleImmutable
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	unique := uniqueStrings(permissions)
+	result := &models.Role{Name: role, Permissions: make([]string, 0, len(unique))}
+	for permission := range unique {
+		result.Permissions = append(result.Permissions, permission)
+	}
+	sort.Strings(result.Permissions)
+
+	err = tx.QueryRow(`SELECT description FROM roles WHERE name = $1`, role).Scan(&result.Description)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: %s", ErrRoleNotFound, role)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load role: %w", err)
+	}
+
+	var known int
+	err = tx.QueryRow(`SELECT COUNT(*) FROM permissions WHERE name = ANY($1)`, pq.Array(permissions)).Scan(&known)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check permissions: %w", err)
+	}
+	if known != len(unique) {
+		return nil, ErrUnknownPermission
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO organization_role_permissions (organization_id, role, permissions, updated_at)
+		VALUES ($1, $2, $3, CURRENT_TIMESTAMP)
+		ON CONFLICT (organization_id, role) DO UPDATE SET
+			permissions = EXCLUDED.permissions,
+			updated_at = EXCLUDED.updated_at
+	`, orgID, role, pq.Array(result.Permissions))
+	if err != nil {
+		return nil, fmt.Errorf("failed to grant role permissions: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit role permissions: %w", err)
+	}
+
+	return result, nil
+}
+
+func uniqueStrings(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		set[value] = true
+	}
+	return set
+}
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"halalguard-backend/database"
+	"halalguard-backend/models"
+)
+
+// GetComplianceReport aggregates an organization's stored analyses for transactions dated between
+// from and to (inclusive), comparing the calendar date of each stored date. Compliance and maslahah averages are weighted by the absolute transaction amount.
+func GetComplianceReport(orgID, from, to string) (*models.ComplianceReport, error) {
+	report := &models.ComplianceReport{
+		From:        from,
+		To:          to,
+		GeneratedAt: time.Now(),
+	}
+
+	summaryQuery := `
+		SELECT
+			COUNT(t.id),
+			COUNT(a.id),
+			COALESCE(SUM(t.amount), 0),
+			COALESCE(SUM(a.confidence_score * ABS(t.amount)) / NULLIF(SUM(ABS(t.amount)) FILTER (WHERE a.id IS NOT NULL), 0), 0),
+			COALESCE(SUM(a.maslahah_total_score * ABS(t.amount)) / NULLIF(SUM(ABS(t.amount)) FILTER (WHERE a.maslahah_total_score IS NOT NULL), 0), 0)
+		FROM transactions t
+		LEFT JOIN ` + analysisJoinOn + `
+		WHERE t.organization_id = $1 AND try_date(t.date) BETWEEN $2::date AND $3::date
+	`
+
+	err := database.DB.QueryRow(summaryQuery, orgID, from, to).Scan(
+		&report.TotalTransactions, &report.AnalyzedTransactions, &report.TotalAmount,
+		&report.WeightedComplianceScore, &report.WeightedMaslahahScore,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query report summary: %w", err)
+	}
+
+	report.ByStatus, err = countAnalysesBy(orgID, "a.status", from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	report.ByViolationType, err = countAnalysesBy(orgID, "a.violation_type", from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	nonCompliantQuery := `
+		SELECT ` + combinedResultColumns + combinedResultFrom + `
+		WHERE t.organization_id = $1 AND try_date(t.date) BETWEEN $2::date AND $3::date AND a.status = $4
+		ORDER BY t.date, t.id
+	`
+
+	rows, err := database.DB.Query(nonCompliantQuery, orgID, from, to, models.StatusNonCompliant)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query non-compliant transactions: %w", err)
+	}
+	defer rows.Close()
+
+	report.NonCompliant = []models.CombinedResult{}
+	for rows.Next() {
+		result, err := scanCombinedResult(rows)
+		if err != nil {
+			slog.Warn("failed to scan row", "error", err)
+			continue
+		}
+		report.NonCompliant = append(report.NonCompliant, *result)
+	}
+
+	return report, nil
+}
+
+// countAnalysesBy groups analysed transactions in the date range by the given column
+func countAnalysesBy(orgID, column, from, to string) ([]models.CountByKey, error) {
+	query := `
+		SELECT ` + column + `, COUNT(*), COALESCE(SUM(t.amount), 0)
+		FROM transactions t
+		JOIN ` + analysisJoinOn + `
+		WHERE t.organization_id = $1 AND try_date(t.date) BETWEEN $2::date AND $3::date
+		GROUP BY ` + column + `
+		ORDER BY COUNT(*) DESC
+	`
+
+	rows, err := database.DB.Query(query, orgID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count analyses by %s: %w", column, err)
+	}
+	defer rows.Close()
+
+	counts := []models.CountByKey{}
+	for rows.Next() {
+		var count models.CountByKey
+		if err := rows.Scan(&count.Key, &count.Count, &count.Amount); err != nil {
+			return nil, fmt.Errorf("failed to scan count: %w", err)
+		}
+		counts = append(counts, count)
+	}
+
+	return counts, rows.Err()
+}
@@ -0,0 +1,184 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strings"
+
+	"halalguard-backend/models"
+
+	"github.com/go-pdf/fpdf"
+)
+
+// RenderComplianceReportPDF renders the compliance report as a PDF document
+func RenderComplianceReportPDF(report *models.ComplianceReport) ([]byte, error) {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(15, 15, 15)
+	pdf.SetAutoPageBreak(true, 20)
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-12)
+		pdf.SetFont("Helvetica", "I", 8)
+		pdf.SetTextColor(120, 120, 120)
+		pdf.CellFormat(0, 5, tr(fmt.Sprintf("HalalGuard AI - Laporan Kepatuhan Syariah %s s/d %s", report.From, report.To)), "", 0, "L", false, 0, "")
+		pdf.CellFormat(0, 5, fmt.Sprintf("Halaman %d/{nb}", pdf.PageNo()), "", 0, "R", false, 0, "")
+	})
+	pdf.AliasNbPages("")
+	pdf.AddPage()
+
+	heading := func(text string) {
+		pdf.Ln(4)
+		pdf.SetFont("Helvetica", "B", 12)
+		pdf.SetTextColor(6, 95, 70)
+		pdf.CellFormat(0, 8, tr(text), "B", 1, "L", false, 0, "")
+		pdf.SetTextColor(0, 0, 0)
+		pdf.Ln(2)
+	}
+	keyValue := func(key, value string) {
+		pdf.SetFont("Helvetica", "", 10)
+		pdf.CellFormat(70, 6, tr(key), "", 0, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "B", 10)
+		pdf.CellFormat(0, 6, tr(value), "", 1, "L", false, 0, "")
+	}
+	table := func(headers []string, widths []float64, rows [][]string) {
+		pdf.SetFont("Helvetica", "B", 9)
+		pdf.SetFillColor(236, 253, 245)
+		for i, header := range headers {
+			pdf.CellFormat(widths[i], 7, tr(header), "1", 0, "L", true, 0, "")
+		}
+		pdf.Ln(-1)
+		pdf.SetFont("Helvetica", "", 9)
+		for _, row := range rows {
+			for i, cell := range row {
+				align := "L"
+				if i > 0 {
+					align = "R"
+				}
+				pdf.CellFormat(widths[i], 6, tr(cell), "1", 0, align, false, 0, "")
+			}
+			pdf.Ln(-1)
+		}
+	}
+
+	// Title block
+	pdf.SetFont("Helvetica", "B", 18)
+	pdf.CellFormat(0, 10, tr("LAPORAN KEPATUHAN SYARIAH"), "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.SetTextColor(100, 100, 100)
+	pdf.CellFormat(0, 6, tr("HalalGuard AI - Laporan Audit Periodik untuk Dewan Pengawas Syariah"), "", 1, "L", false, 0, "")
+	pdf.CellFormat(0, 6, tr(fmt.Sprintf("Periode: %s s/d %s", report.From, report.To)), "", 1, "L", false, 0, "")
+	pdf.CellFormat(0, 6, tr("Dibuat: "+report.GeneratedAt.Format("2006-01-02 15:04:05 MST")), "", 1, "L", false, 0, "")
+	pdf.SetTextColor(0, 0, 0)
+
+	// Executive summary
+	heading("1. Ringkasan Eksekutif")
+	keyValue("Total transaksi", fmt.Sprintf("%d", report.TotalTransactions))
+	keyValue("Transaksi teranalisis", fmt.Sprintf("%d", report.AnalyzedTransactions))
+	keyValue("Total nominal", formatRupiah(report.TotalAmount))
+	keyValue("Rata-rata skor kepatuhan (tertimbang)", fmt.Sprintf("%.2f / 100", report.WeightedComplianceScore))
+	keyValue("Rata-rata skor maslahah (tertimbang)", fmt.Sprintf("%.2f / 100", report.WeightedMaslahahScore))
+
+	heading("2. Ringkasan per Status")
+	table([]string{"Status", "Jumlah", "Nominal"}, []float64{80, 30, 70}, countRows(report.ByStatus))
+
+	heading("3. Ringkasan per Jenis Pelanggaran")
+	table([]string{"Jenis", "Jumlah", "Nominal"}, []float64{80, 30, 70}, countRows(report.ByViolationType))
+
+	// Non-compliant transactions
+	heading(fmt.Sprintf("4. Transaksi Tidak Patuh (%d)", len(report.NonCompliant)))
+	if len(report.NonCompliant) == 0 {
+		pdf.SetFont("Helvetica", "I", 10)
+		pdf.CellFormat(0, 6, tr("Tidak ada transaksi tidak patuh pada periode ini."), "", 1, "L", false, 0, "")
+	}
+	for i, item := range report.NonCompliant {
+		pdf.SetFont("Helvetica", "B", 10)
+		pdf.MultiCell(0, 6, tr(fmt.Sprintf("%d. %s - %s (%s)", i+1, item.ID, item.Description, item.Date)), "", "L", false)
+		pdf.SetFont("Helvetica", "", 9)
+		pdf.MultiCell(0, 5, tr(fmt.Sprintf("Jenis: %s | Nominal: %s | Pelanggaran: %s | Skor: %.2f",
+			item.Type, formatRupiah(item.Amount), item.Analysis.ViolationType, item.Analysis.ConfidenceScore)), "", "L", false)
+		pdf.MultiCell(0, 5, tr("Alasan: "+item.Analysis.Reasoning), "", "L", false)
+		if item.Analysis.SuggestedCorrection != "" {
+			pdf.MultiCell(0, 5, tr("Saran perbaikan: "+item.Analysis.SuggestedCorrection), "", "L", false)
+		}
+		pdf.Ln(2)
+	}
+
+	// Sign-off
+	heading("5. Pengesahan Dewan Pengawas Syariah")
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.MultiCell(0, 5, tr("Laporan ini telah ditinjau dan disahkan oleh Dewan Pengawas Syariah."), "", "L", false)
+	pdf.Ln(14)
+	pdf.CellFormat(85, 6, "(______________________________)", "", 0, "C", false, 0, "")
+	pdf.CellFormat(85, 6, "(______________________________)", "", 1, "C", false, 0, "")
+	pdf.CellFormat(85, 6, tr("Ketua DPS"), "", 0, "C", false, 0, "")
+	pdf.CellFormat(85, 6, tr("Anggota DPS"), "", 1, "C", false, 0, "")
+	pdf.CellFormat(85, 6, tr("Tanggal: ______________"), "", 0, "C", false, 0, "")
+	pdf.CellFormat(85, 6, tr("Tanggal: ______________"), "", 1, "C", false, 0, "")
+
+	// Methodology appendix
+	pdf.AddPage()
+	heading("Lampiran: Metodologi Penilaian")
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.MultiCell(0, 5, tr("Skor kepatuhan (0-100) merupakan rata-rata tertimbang dari lima prinsip ekonomi Islam, masing-masing dinilai 0.0 (buruk) sampai 1.0 (baik):"), "", "L", false)
+	pdf.Ln(2)
+	table([]string{"Prinsip", "Kriteria", "Bobot"}, []float64{50, 100, 30}, principleRows(CompliancePrinciples))
+	pdf.Ln(4)
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.MultiCell(0, 5, tr("Skor maslahah (0-100) mengukur dampak sosial transaksi berdasarkan dimensi berikut:"), "", "L", false)
+	pdf.Ln(2)
+	table([]string{"Dimensi", "Kriteria", "Bobot"}, []float64{50, 100, 30}, principleRows(MaslahahDimensions))
+	pdf.Ln(4)
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.MultiCell(0, 5, tr("Rata-rata pada ringkasan eksekutif ditimbang berdasarkan nilai absolut nominal transaksi. "+
+		"Status \"Patuh\", \"Tidak Patuh\" dan \"Butuh Tinjauan\" serta jenis pelanggaran ditetapkan oleh analisis AI dan "+
+		"harus dikonfirmasi oleh Dewan Pengawas Syariah sebelum laporan ini disahkan."), "", "L", false)
+
+	if err := pdf.Error(); err != nil {
+		return nil, fmt.Errorf("failed to render report: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, fmt.Errorf("failed to write report: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func countRows(counts []models.CountByKey) [][]string {
+	rows := make([][]string, 0, len(counts))
+	for _, count := range counts {
+		rows = append(rows, []string{count.Key, fmt.Sprintf("%d", count.Count), formatRupiah(count.Amount)})
+	}
+	return rows
+}
+
+func principleRows(principles []Principle) [][]string {
+	rows := make([][]string, 0, len(principles))
+	for _, p := range principles {
+		rows = append(rows, []string{p.Name, p.Description, fmt.Sprintf("%.0f%%", p.Weight*100)})
+	}
+	return rows
+}
+
+// formatRupiah formats an amount using Indonesian thousand separators, e.g. "Rp 1.500.000,00"
+func formatRupiah(amount float64) string {
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	cents := int64(math.Round(amount * 100))
+	whole := fmt.Sprintf("%d", cents/100)
+
+	var groups []string
+	for len(whole) > 3 {
+		groups = append([]string{whole[len(whole)-3:]}, groups...)
+		whole = whole[:len(whole)-3]
+	}
+	groups = append([]string{whole}, groups...)
+
+	return fmt.Sprintf("%sRp %s,%02d", sign, strings.Join(groups, "."), cents%100)
+}
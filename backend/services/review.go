@@ -0,0 +1,160 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"halalguard-backend/database"
+	"halalguard-backend/models"
+)
+
+var (
+	// ErrAnalysisNotFound is returned when a transaction has no stored analysis
+	ErrAnalysisNotFound = errors.New("analysis not found")
+	// ErrReviewDecided is returned when a review has already been decided
+	ErrReviewDecided = errors.New("review already decided")
+	// ErrReviewAssignedElsewhere is returned when another reviewer holds the assignment
+	ErrReviewAssignedElsewhere = errors.New("review assigned to another reviewer")
+)
+
+// GetReviewQueue lists an organization's undecided analyses that need human review, either because the AI
+// returned "Butuh Tinjauan" or because its confidenceScore is below threshold. Items are
+// ordered by priority, the absolute amount multiplied by the model's uncertainty.
+func GetReviewQueue(orgID string, threshold float64, reviewer string, limit int) ([]models.ReviewQueueItem, error) {
+	query := `
+		SELECT ` + combinedResultColumns + `,
+			ABS(t.amount) * GREATEST(0, LEAST(1, 1 - a.confidence_score / 100.0)) AS priority,
+			GREATEST(0, LEAST(1, 1 - a.confidence_score / 100.0)) AS uncertainty
+		` + combinedResultFrom + `
+		WHERE t.organization_id = $1
+			AND a.id IS NOT NULL
+			AND (a.status = $2 OR a.confidence_score < $3)
+			AND (r.state IS NULL OR r.state <> $4)
+			AND ($5 = '' OR r.reviewer = $5)
+		ORDER BY priority DESC, t.id
+		LIMIT $6
+	`
+
+	rows, err := database.DB.Query(query, orgID, models.StatusNeedsReview, threshold, models.ReviewDecided, reviewer, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query review queue: %w", err)
+	}
+	defer rows.Close()
+
+	queue := []models.ReviewQueueItem{}
+	for rows.Next() {
+		var item models.ReviewQueueItem
+		result, err := scanCombinedResult(rows, &item.Priority, &item.Uncertainty)
+		if err != nil {
+			slog.Warn("failed to scan row", "error", err)
+			continue
+		}
+		item.CombinedResult = *result
+		queue = append(queue, item)
+	}
+
+	return queue, rows.Err()
+}
+
+// AssignReview assigns the review of a transaction's analysis to a reviewer. The
+// upsert skips decided reviews, so an assignment cannot reopen a concurrent decision.
+func AssignReview(orgID, transactionID, reviewer string) (*models.CombinedResult, error) {
+	if _, err := reviewableResult(orgID, transactionID); err != nil {
+		return nil, err
+	}
+
+	query := `
+		INSERT INTO reviews (organization_id, transaction_id, reviewer, state, assigned_at)
+		VALUES ($1, $2, $3, $4, CURRENT_TIMESTAMP)
+		ON CONFLICT (organization_id, transaction_id) DO UPDATE SET
+			reviewer = EXCLUDED.reviewer,
+			state = EXCLUDED.state,
+			assigned_at = EXCLUDED.assigned_at
+		WHERE reviews.state <> $5
+	`
+
+	res, err := database.DB.Exec(query, orgID, transactionID, reviewer, models.ReviewAssigned, models.ReviewDecided)
+	if err != nil {
+		return nil, fmt.Errorf("failed to assign review: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return nil, fmt.Errorf("failed to assign review: %w", err)
+	} else if n == 0 {
+		return nil, ErrReviewDecided
+	}
+
+	return GetTransactionByID(orgID, transactionID)
+}
+
+// DecideReview records a Sharia reviewer's final verdict. The AI verdict in
+// analysis_results is left untouched so both remain visible. The upsert only
+// replaces an undecided review held by the same reviewer, so of two concurrent
+// decisions on a transaction exactly one succeeds.
+func DecideReview(orgID, transactionID, reviewer string, req models.ReviewDecisionRequest) (*models.CombinedResult, error) {
+	if _, err := reviewableResult(orgID, transactionID); err != nil {
+		return nil, err
+	}
+
+	query := `
+		INSERT INTO reviews (
+			organization_id, transaction_id, reviewer, state, final_status, final_violation_type, justification, decided_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, CURRENT_TIMESTAMP)
+		ON CONFLICT (organization_id, transaction_id) DO UPDATE SET
+			reviewer = EXCLUDED.reviewer,
+			state = EXCLUDED.state,
+			final_status = EXCLUDED.final_status,
+			final_violation_type = EXCLUDED.final_violation_type,
+			justification = EXCLUDED.justification,
+			decided_at = EXCLUDED.decided_at
+		WHERE reviews.state <> EXCLUDED.state AND reviews.reviewer = EXCLUDED.reviewer
+	`
+
+	res, err := database.DB.Exec(query, orgID, transactionID, reviewer, models.ReviewDecided,
+		req.Status, req.ViolationType, req.Justification)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save review decision: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to save review decision: %w", err)
+	}
+	if n == 0 {
+		// The review was decided, or is held by someone else
+		current, err := reviewableResult(orgID, transactionID)
+		if err != nil {
+			return nil, err
+		}
+		if current.Review != nil && current.Review.State == models.ReviewDecided {
+			return nil, ErrReviewDecided
+		}
+		return nil, ErrReviewAssignedElsewhere
+	}
+
+	return GetTransactionByID(orgID, transactionID)
+}
+
+// resetReviews discards the review of a re-analyzed transaction, since it judged the
+// previous verdict; a new "Butuh Tinjauan" then returns to the queue
+func resetReviews(ctx context.Context, q querier, orgID, transactionID string) error {
+	_, err := q.ExecContext(ctx, `
+		DELETE FROM reviews WHERE organization_id = $1 AND transaction_id = $2
+	`, orgID, transactionID)
+	if err != nil {
+		return fmt.Errorf("failed to reset reviews: %w", err)
+	}
+	return nil
+}
+
+// reviewableResult loads a transaction and ensures it has an AI analysis to review
+func reviewableResult(orgID, transactionID string) (*models.CombinedResult, error) {
+	result, err := GetTransactionByID(orgID, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	if result.Analysis == nil {
+		return nil, ErrAnalysisNotFound
+	}
+	return result, nil
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"halalguard-backend/models"
+)
+
+// keywordRule flags transactions whose description contains one of its keywords
+type keywordRule struct {
+	violationType string
+	status        string
+	keywords      []string
+	reasoning     string
+	correction    string
+}
+
+// keywordRules are checked in order; the first matching rule decides the result
+var keywordRules = []keywordRule{
+	{
+		violationType: models.ViolationMaysir,
+		status:        models.StatusNonCompliant,
+		keywords:      []string{"judi", "togel", "kasino", "casino", "taruhan", "betting", "lotre", "lottery", "slot online", "poker"},
+		reasoning:     "Deskripsi mengandung unsur perjudian (%s).",
+		correction:    "Hentikan transaksi perjudian dan salurkan dana yang diperoleh sebagai dana sosial.",
+	},
+	{
+		violationType: models.ViolationRiba,
+		status:        models.StatusNonCompliant,
+		keywords:      []string{"bunga bank", "bunga pinjaman", "bunga kredit", "bunga deposito", "interest", "riba", "denda keterlambatan", "late fee", "pinjol", "pinjaman online", "kartu kredit", "credit card"},
+		reasoning:     "Deskripsi mengandung unsur bunga atau denda atas utang (%s).",
+		correction:    "Gunakan produk pembiayaan syariah (murabahah, ijarah, musyarakah) tanpa bunga.",
+	},
+	{
+		violationType: models.ViolationHalal,
+		status:        models.StatusNonCompliant,
+		keywords:      []string{"alkohol", "alcohol", "bir", "beer", "wine", "vodka", "whisky", "babi", "pork"},
+		reasoning:     "Objek transaksi termasuk barang haram (%s).",
+		correction:    "Ganti objek transaksi dengan barang atau jasa yang halal.",
+	},
+	{
+		violationType: models.ViolationGharar,
+		status:        models.StatusNeedsReview,
+		keywords:      []string{"forex", "binary option", "opsi biner", "futures", "derivatif", "derivative", "spekulasi", "margin trading"},
+		reasoning:     "Transaksi mengandung ketidakpastian atau spekulasi tinggi (%s) yang perlu ditinjau.",
+		correction:    "Pastikan akad, objek, dan harga transaksi jelas sebelum dilanjutkan.",
+	},
+	{
+		violationType: models.ViolationSyubhat,
+		status:        models.StatusNeedsReview,
+		keywords:      []string{"asuransi", "insurance", "leasing", "paylater", "cicilan"},
+		reasoning:     "Jenis transaksi (%s) bisa konvensional atau syariah sehingga perlu ditinjau.",
+		correction:    "Pastikan penyedia layanan memiliki sertifikasi syariah (DSN-MUI).",
+	},
+}
+
+// AnalyzeWithRules classifies transactions by keywords in their description, without
+// calling Gemini. It is a fast, offline screen: confidence scores are deliberately
+// modest and transactions matching no rule are reported compliant.
+func AnalyzeWithRules(transactions []models.TransactionInput) []models.AnalysisResult {
+	results := make([]models.AnalysisResult, len(transactions))
+	for i, tx := range transactions {
+		results[i] = analyzeWithRules(tx)
+	}
+	return results
+}
+
+func analyzeWithRules(tx models.TransactionInput) models.AnalysisResult {
+	// Match whole words only, so "bir" does not match "birokrasi"
+	words := strings.FieldsFunc(strings.ToLower(tx.Description), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	description := " " + strings.Join(words, " ") + " "
+	for _, rule := range keywordRules {
+		for _, keyword := range rule.keywords {
+			if !strings.Contains(description, " "+keyword+" ") {
+				continue
+			}
+			return models.AnalysisResult{
+				TransactionID:       tx.ID,
+				Status:              rule.status,
+				ViolationType:       rule.violationType,
+				ConfidenceScore:     70,
+				Breakdown:           ruleBreakdown(rule.violationType),
+				Reasoning:           fmt.Sprintf(rule.reasoning, keyword),
+				SuggestedCorrection: rule.correction,
+			}
+		}
+	}
+
+	return models.AnalysisResult{
+		TransactionID:   tx.ID,
+		Status:          models.StatusCompliant,
+		ViolationType:   models.ViolationHalal,
+		ConfidenceScore: 50,
+		Breakdown:       ruleBreakdown(""),
+		Reasoning:       "Tidak ditemukan kata kunci pelanggaran syariah pada deskripsi (analisis berbasis aturan).",
+	}
+}
+
+// ruleBreakdown scores every principle as satisfied except the violated one
+func ruleBreakdown(violationType string) models.ComplianceBreakdown {
+	breakdown := models.ComplianceBreakdown{RibaScore: 1, GhararScore: 1, MaysirScore: 1, HalalScore: 1, JusticeScore: 1}
+	switch violationType {
+	case models.ViolationRiba:
+		breakdown.RibaScore = 0.1
+	case models.ViolationGharar:
+		breakdown.GhararScore = 0.3
+	case models.ViolationMaysir:
+		breakdown.MaysirScore = 0.1
+	case models.ViolationHalal:
+		breakdown.HalalScore = 0.1
+	case models.ViolationSyubhat:
+		breakdown.HalalScore = 0.5
+	}
+	return breakdown
+}
@@ -0,0 +1,376 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"halalguard-backend/database"
+	"halalguard-backend/models"
+
+	"github.com/lib/pq"
+)
+
+// ErrMethodologyNotFound is returned when an unknown screening methodology is requested
+var ErrMethodologyNotFound = errors.New("screening methodology not found")
+
+// issuerCSVColumns maps the CSV header names to IssuerRatios fields
+var issuerCSVColumns = []string{
+	"ticker", "name", "period", "total_assets", "interest_bearing_debt",
+	"total_revenue", "non_halal_income", "cash_and_interest_securities", "market_cap",
+}
+
+// ImportIssuerRatiosCSV loads an organization's issuer financial ratios from CSV, replacing its
+// existing rows per ticker. The first row must be a header containing the columns in
+// issuerCSVColumns, in any order.
+func ImportIssuerRatiosCSV(orgID string, r io.Reader) (int, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	index := make(map[string]int, len(header))
+	for i, column := range header {
+		index[strings.ToLower(strings.TrimSpace(column))] = i
+	}
+	for _, column := range issuerCSVColumns {
+		if _, ok := index[column]; !ok {
+			return 0, fmt.Errorf("CSV is missing column %q", column)
+		}
+	}
+
+	var issuers []models.IssuerRatios
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to read CSV line %d: %w", line, err)
+		}
+
+		numbers := make(map[string]float64)
+		for _, column := range issuerCSVColumns[3:] {
+			value, err := strconv.ParseFloat(strings.TrimSpace(record[index[column]]), 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid %s on CSV line %d: %w", column, line, err)
+			}
+			numbers[column] = value
+		}
+
+		issuers = append(issuers, models.IssuerRatios{
+			Ticker:                    strings.ToUpper(strings.TrimSpace(record[index["ticker"]])),
+			Name:                      strings.TrimSpace(record[index["name"]]),
+			Period:                    strings.TrimSpace(record[index["period"]]),
+			TotalAssets:               numbers["total_assets"],
+			InterestBearingDebt:       numbers["interest_bearing_debt"],
+			TotalRevenue:              numbers["total_revenue"],
+			NonHalalIncome:            numbers["non_halal_income"],
+			CashAndInterestSecurities: numbers["cash_and_interest_securities"],
+			MarketCap:                 numbers["market_cap"],
+		})
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO issuer_ratios (
+			organization_id, ticker, name, period, total_assets, interest_bearing_debt, total_revenue,
+			non_halal_income, cash_and_interest_securities, market_cap, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, CURRENT_TIMESTAMP)
+		ON CONFLICT (organization_id, ticker) DO UPDATE SET
+			name = EXCLUDED.name,
+			period = EXCLUDED.period,
+			total_assets = EXCLUDED.total_assets,
+			interest_bearing_debt = EXCLUDED.interest_bearing_debt,
+			total_revenue = EXCLUDED.total_revenue,
+			non_halal_income = EXCLUDED.non_halal_income,
+			cash_and_interest_securities = EXCLUDED.cash_and_interest_securities,
+			market_cap = EXCLUDED.market_cap,
+			updated_at = EXCLUDED.updated_at
+	`
+
+	for _, issuer := range issuers {
+		if issuer.Ticker == "" {
+			return 0, fmt.Errorf("CSV contains a row without ticker")
+		}
+		_, err := tx.Exec(query, orgID, issuer.Ticker, issuer.Name, issuer.Period, issuer.TotalAssets,
+			issuer.InterestBearingDebt, issuer.TotalRevenue, issuer.NonHalalIncome,
+			issuer.CashAndInterestSecurities, issuer.MarketCap)
+		if err != nil {
+			return 0, fmt.Errorf("failed to save issuer %s: %w", issuer.Ticker, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit issuers: %w", err)
+	}
+
+	return len(issuers), nil
+}
+
+const issuerColumns = `
+	ticker, name, period, total_assets, interest_bearing_debt, total_revenue,
+	non_halal_income, cash_and_interest_securities, market_cap, updated_at`
+
+func scanIssuer(row rowScanner) (*models.IssuerRatios, error) {
+	var issuer models.IssuerRatios
+	err := row.Scan(&issuer.Ticker, &issuer.Name, &issuer.Period, &issuer.TotalAssets,
+		&issuer.InterestBearingDebt, &issuer.TotalRevenue, &issuer.NonHalalIncome,
+		&issuer.CashAndInterestSecurities, &issuer.MarketCap, &issuer.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &issuer, nil
+}
+
+// GetIssuerRatios retrieves the organization's stored ratios of one issuer
+func GetIssuerRatios(orgID, ticker string) (*models.IssuerRatios, error) {
+	query := `SELECT ` + issuerColumns + ` FROM issuer_ratios WHERE organization_id = $1 AND ticker = $2`
+
+	issuer, err := scanIssuer(database.DB.QueryRow(query, orgID, strings.ToUpper(ticker)))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("issuer not found")
+		}
+		return nil, fmt.Errorf("failed to query issuer: %w", err)
+	}
+
+	return issuer, nil
+}
+
+// GetAllIssuerRatios retrieves the organization's issuer ratios ordered by ticker
+func GetAllIssuerRatios(orgID string) ([]models.IssuerRatios, error) {
+	return queryIssuers(`SELECT `+issuerColumns+` FROM issuer_ratios WHERE organization_id = $1 ORDER BY ticker`, orgID)
+}
+
+func queryIssuers(query string, args ...interface{}) ([]models.IssuerRatios, error) {
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query issuers: %w", err)
+	}
+	defer rows.Close()
+
+	issuers := []models.IssuerRatios{}
+	for rows.Next() {
+		issuer, err := scanIssuer(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan issuer: %w", err)
+		}
+		issuers = append(issuers, *issuer)
+	}
+
+	return issuers, rows.Err()
+}
+
+// GetScreeningMethodologies lists the organization's screening methodologies
+func GetScreeningMethodologies(orgID string) ([]models.ScreeningMethodology, error) {
+	query := `
+		SELECT name, debt_denominator, max_debt_ratio, max_non_halal_income_ratio, max_cash_securities_ratio
+		FROM screening_methodologies
+		WHERE organization_id = $1
+		ORDER BY name
+	`
+
+	rows, err := database.DB.Query(query, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query methodologies: %w", err)
+	}
+	defer rows.Close()
+
+	methodologies := []models.ScreeningMethodology{}
+	for rows.Next() {
+		var m models.ScreeningMethodology
+		if err := rows.Scan(&m.Name, &m.DebtDenominator, &m.MaxDebtRatio, &m.MaxNonHalalIncomeRatio, &m.MaxCashSecuritiesRatio); err != nil {
+			return nil, fmt.Errorf("failed to scan methodology: %w", err)
+		}
+		methodologies = append(methodologies, m)
+	}
+
+	return methodologies, rows.Err()
+}
+
+// GetScreeningMethodology retrieves the organization's thresholds of one methodology
+func GetScreeningMethodology(orgID, name string) (*models.ScreeningMethodology, error) {
+	query := `
+		SELECT name, debt_denominator, max_debt_ratio, max_non_halal_income_ratio, max_cash_securities_ratio
+		FROM screening_methodologies
+		WHERE organization_id = $1 AND name = $2
+	`
+
+	var m models.ScreeningMethodology
+	err := database.DB.QueryRow(query, orgID, strings.ToUpper(name)).
+		Scan(&m.Name, &m.DebtDenominator, &m.MaxDebtRatio, &m.MaxNonHalalIncomeRatio, &m.MaxCashSecuritiesRatio)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("%w: %s", ErrMethodologyNotFound, name)
+		}
+		return nil, fmt.Errorf("failed to query methodology: %w", err)
+	}
+
+	return &m, nil
+}
+
+// SaveScreeningMethodology creates or updates the organization's thresholds of a methodology
+func SaveScreeningMethodology(orgID string, m models.ScreeningMethodology) error {
+	query := `
+		INSERT INTO screening_methodologies (organization_id, name, debt_denominator, max_debt_ratio, max_non_halal_income_ratio, max_cash_securities_ratio)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (organization_id, name) DO UPDATE SET
+			debt_denominator = EXCLUDED.debt_denominator,
+			max_debt_ratio = EXCLUDED.max_debt_ratio,
+			max_non_halal_income_ratio = EXCLUDED.max_non_halal_income_ratio,
+			max_cash_securities_ratio = EXCLUDED.max_cash_securities_ratio
+	`
+
+	_, err := database.DB.Exec(query, orgID, strings.ToUpper(m.Name), m.DebtDenominator, m.MaxDebtRatio,
+		m.MaxNonHalalIncomeRatio, m.MaxCashSecuritiesRatio)
+	if err != nil {
+		return fmt.Errorf("failed to save methodology: %w", err)
+	}
+
+	return nil
+}
+
+// ScreenIssuer evaluates an issuer's financial ratios against a methodology.
+// A threshold of 0 means the ratio is not part of the methodology.
+func ScreenIssuer(issuer models.IssuerRatios, m models.ScreeningMethodology) models.ScreeningResult {
+	debtBase := issuer.TotalAssets
+	if m.DebtDenominator == "marketCap" {
+		debtBase = issuer.MarketCap
+	}
+
+	candidates := []models.ScreeningRatio{
+		{Name: "interestBearingDebt/" + m.DebtDenominator, Value: ratio(issuer.InterestBearingDebt, debtBase), Threshold: m.MaxDebtRatio},
+		{Name: "nonHalalIncome/totalRevenue", Value: ratio(issuer.NonHalalIncome, issuer.TotalRevenue), Threshold: m.MaxNonHalalIncomeRatio},
+		{Name: "cashAndInterestSecurities/marketCap", Value: ratio(issuer.CashAndInterestSecurities, issuer.MarketCap), Threshold: m.MaxCashSecuritiesRatio},
+	}
+
+	result := models.ScreeningResult{
+		Ticker:      issuer.Ticker,
+		Name:        issuer.Name,
+		Period:      issuer.Period,
+		Methodology: m.Name,
+		Compliant:   true,
+		Ratios:      []models.ScreeningRatio{},
+	}
+	for _, r := range candidates {
+		if r.Threshold <= 0 {
+			continue
+		}
+		r.Passed = r.Value <= r.Threshold
+		result.Compliant = result.Compliant && r.Passed
+		result.Ratios = append(result.Ratios, r)
+	}
+
+	return result
+}
+
+// ratio divides value by base, treating a missing base as fully non-compliant
+func ratio(value, base float64) float64 {
+	if base <= 0 {
+		if value <= 0 {
+			return 0
+		}
+		return 1
+	}
+	return value / base
+}
+
+// ScreenTransactions screens investment transactions whose description mentions a ticker of the
+// organization's issuers, using its thresholds of the methodology. The returned map is keyed by transaction ID.
+func ScreenTransactions(orgID string, transactions []models.TransactionInput, methodologyName string) (map[string]models.ScreeningResult, error) {
+	tokensByTx := make(map[string][]string)
+	var tokens []string
+	for _, tx := range transactions {
+		if !strings.EqualFold(tx.Type, "Investment") {
+			continue
+		}
+		words := strings.FieldsFunc(strings.ToUpper(tx.Description), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '.'
+		})
+		tokensByTx[tx.ID] = words
+		tokens = append(tokens, words...)
+	}
+
+	screenings := make(map[string]models.ScreeningResult)
+	if len(tokens) == 0 {
+		return screenings, nil
+	}
+
+	issuers, err := queryIssuers(`SELECT `+issuerColumns+` FROM issuer_ratios WHERE organization_id = $1 AND ticker = ANY($2)`, orgID, pq.Array(tokens))
+	if err != nil || len(issuers) == 0 {
+		return screenings, err
+	}
+
+	methodology, err := GetScreeningMethodology(orgID, methodologyName)
+	if err != nil {
+		return nil, err
+	}
+
+	byTicker := make(map[string]models.IssuerRatios, len(issuers))
+	for _, issuer := range issuers {
+		byTicker[issuer.Ticker] = issuer
+	}
+
+	for txID, words := range tokensByTx {
+		for _, word := range words {
+			if issuer, ok := byTicker[word]; ok {
+				screenings[txID] = ScreenIssuer(issuer, *methodology)
+				break
+			}
+		}
+	}
+
+	return screenings, nil
+}
+
+// ApplyScreening attaches screening results to analysis results. An issuer failing the
+// financial ratio screen makes the investment non-compliant regardless of the AI verdict.
+func ApplyScreening(results []models.AnalysisResult, screenings map[string]models.ScreeningResult) {
+	for i := range results {
+		screening, ok := screenings[results[i].TransactionID]
+		if !ok {
+			results[i].Screening = nil
+			continue
+		}
+		results[i].Screening = &screening
+		if screening.Compliant {
+			continue
+		}
+
+		var failed []string
+		violation := models.ViolationRiba
+		for _, r := range screening.Ratios {
+			if r.Passed {
+				continue
+			}
+			failed = append(failed, fmt.Sprintf("%s %.1f%% > %.1f%%", r.Name, r.Value*100, r.Threshold*100))
+			if len(failed) == 1 && strings.HasPrefix(r.Name, "nonHalalIncome") {
+				violation = models.ViolationSyubhat
+			}
+		}
+
+		if results[i].Status != models.StatusNonCompliant {
+			results[i].Status = models.StatusNonCompliant
+			results[i].ViolationType = violation
+		}
+		results[i].Reasoning = strings.TrimSpace(fmt.Sprintf("%s Saham %s tidak lolos penyaringan rasio keuangan %s: %s.",
+			results[i].Reasoning, screening.Ticker, screening.Methodology, strings.Join(failed, "; ")))
+		if results[i].SuggestedCorrection == "" {
+			results[i].SuggestedCorrection = "Pilih saham yang termasuk dalam Daftar Efek Syariah (DES/ISSI)."
+		}
+	}
+}
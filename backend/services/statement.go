@@ -0,0 +1,71 @@
+package services
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"halalguard-backend/models"
+)
+
+// statementCSVColumns are the CSV header names of a transaction statement
+var statementCSVColumns = []string{"id", "description", "amount", "date", "type"}
+
+// ParseTransactionsCSV reads transactions from a statement CSV. The first row must be
+// a header containing the columns in statementCSVColumns, in any order.
+func ParseTransactionsCSV(r io.Reader) ([]models.TransactionInput, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	index := make(map[string]int, len(header))
+	for i, column := range header {
+		index[strings.ToLower(strings.TrimSpace(column))] = i
+	}
+	for _, column := range statementCSVColumns {
+		if _, ok := index[column]; !ok {
+			return nil, fmt.Errorf("CSV is missing column %q", column)
+		}
+	}
+
+	var transactions []models.TransactionInput
+	seen := make(map[string]bool)
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV line %d: %w", line, err)
+		}
+
+		amount, err := strconv.ParseFloat(strings.TrimSpace(record[index["amount"]]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid amount on CSV line %d: %w", line, err)
+		}
+
+		tx := models.TransactionInput{
+			ID:          strings.TrimSpace(record[index["id"]]),
+			Description: strings.TrimSpace(record[index["description"]]),
+			Amount:      amount,
+			Date:        strings.TrimSpace(record[index["date"]]),
+			Type:        strings.TrimSpace(record[index["type"]]),
+		}
+		if tx.ID == "" || tx.Description == "" {
+			return nil, fmt.Errorf("missing id or description on CSV line %d", line)
+		}
+		if seen[tx.ID] {
+			return nil, fmt.Errorf("duplicate transaction id %q on CSV line %d", tx.ID, line)
+		}
+		seen[tx.ID] = true
+		transactions = append(transactions, tx)
+	}
+
+	return transactions, nil
+}
@@ -0,0 +1,134 @@
+package services
+
+import (
+	"fmt"
+
+	"halalguard-backend/database"
+	"halalguard-backend/models"
+)
+
+// statsDateFilter restricts rows to the organization passed as $1 and the
+// optional from/to range passed as $2 and $3
+const statsDateFilter = `t.organization_id = $1 AND ($2 = '' OR t.date >= $2) AND ($3 = '' OR t.date <= $3)`
+
+// GetStats computes an organization's dashboard aggregates in the database using GROUP BY queries
+func GetStats(orgID string, filter models.StatsFilter) (*models.StatsResponse, error) {
+	stats := &models.StatsResponse{Bucket: filter.Bucket}
+
+	summaryQuery := `
+		SELECT
+			COUNT(t.id), COUNT(a.id), COALESCE(SUM(t.amount), 0),
+			COALESCE(AVG(a.confidence_score), 0),
+			COALESCE(AVG(a.riba_score), 0), COALESCE(AVG(a.gharar_score), 0),
+			COALESCE(AVG(a.maysir_score), 0), COALESCE(AVG(a.halal_score), 0),
+			COALESCE(AVG(a.justice_score), 0),
+			COALESCE(AVG(a.maslahah_total_score), 0),
+			COALESCE(AVG(a.maslahah_economic_justice), 0), COALESCE(AVG(a.maslahah_community_dev), 0),
+			COALESCE(AVG(a.maslahah_educational), 0), COALESCE(AVG(a.maslahah_environmental), 0),
+			COALESCE(AVG(a.maslahah_social_cohesion), 0)
+		FROM transactions t
+		LEFT JOIN ` + analysisJoinOn + `
+		WHERE ` + statsDateFilter
+
+	breakdown := &stats.AverageBreakdown
+	maslahah := &stats.AverageMaslahah
+	err := database.DB.QueryRow(summaryQuery, orgID, filter.From, filter.To).Scan(
+		&stats.Totals.Transactions, &stats.Totals.Analyzed, &stats.Totals.Amount,
+		&stats.AverageConfidenceScore,
+		&breakdown.RibaScore, &breakdown.GhararScore, &breakdown.MaysirScore,
+		&breakdown.HalalScore, &breakdown.JusticeScore,
+		&maslahah.TotalScore,
+		&maslahah.Breakdown.EconomicJustice, &maslahah.Breakdown.CommunityDevelopment,
+		&maslahah.Breakdown.EducationalImpact, &maslahah.Breakdown.Environmental,
+		&maslahah.Breakdown.SocialCohesion,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stats summary: %w", err)
+	}
+
+	if stats.ByStatus, err = groupStats(orgID, "a.status", "JOIN", filter); err != nil {
+		return nil, err
+	}
+	if stats.ByViolationType, err = groupStats(orgID, "a.violation_type", "JOIN", filter); err != nil {
+		return nil, err
+	}
+	if stats.ByType, err = groupStats(orgID, "t.type", "LEFT JOIN", filter); err != nil {
+		return nil, err
+	}
+	if stats.Series, err = statsSeries(orgID, filter); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// groupStats counts transactions and sums amounts per value of column
+func groupStats(orgID, column, join string, filter models.StatsFilter) ([]models.CountByKey, error) {
+	query := `
+		SELECT ` + column + `, COUNT(*), COALESCE(SUM(t.amount), 0)
+		FROM transactions t
+		` + join + ` ` + analysisJoinOn + `
+		WHERE ` + statsDateFilter + `
+		GROUP BY ` + column + `
+		ORDER BY COUNT(*) DESC
+	`
+
+	rows, err := database.DB.Query(query, orgID, filter.From, filter.To)
+	if err != nil {
+		return nil, fmt.Errorf("failed to group stats by %s: %w", column, err)
+	}
+	defer rows.Close()
+
+	counts := []models.CountByKey{}
+	for rows.Next() {
+		var count models.CountByKey
+		if err := rows.Scan(&count.Key, &count.Count, &count.Amount); err != nil {
+			return nil, fmt.Errorf("failed to scan stats row: %w", err)
+		}
+		counts = append(counts, count)
+	}
+
+	return counts, rows.Err()
+}
+
+// statsSeries buckets transactions by day, week or month of their transaction date.
+// Rows whose date is not in YYYY-MM-DD form are skipped.
+func statsSeries(orgID string, filter models.StatsFilter) ([]models.StatsBucket, error) {
+	query := `
+		SELECT
+			to_char(date_trunc($4, try_date(t.date)), 'YYYY-MM-DD') AS bucket,
+			COUNT(t.id), COALESCE(SUM(t.amount), 0),
+			COUNT(*) FILTER (WHERE a.status = $5),
+			COUNT(*) FILTER (WHERE a.status = $6),
+			COUNT(*) FILTER (WHERE a.status = $7),
+			COALESCE(AVG(a.confidence_score), 0),
+			COALESCE(AVG(a.maslahah_total_score), 0)
+		FROM transactions t
+		LEFT JOIN ` + analysisJoinOn + `
+		WHERE ` + statsDateFilter + ` AND try_date(t.date) IS NOT NULL
+		GROUP BY bucket
+		ORDER BY bucket
+	`
+
+	rows, err := database.DB.Query(query, orgID, filter.From, filter.To, filter.Bucket,
+		models.StatusCompliant, models.StatusNonCompliant, models.StatusNeedsReview)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stats series: %w", err)
+	}
+	defer rows.Close()
+
+	series := []models.StatsBucket{}
+	for rows.Next() {
+		var bucket models.StatsBucket
+		if err := rows.Scan(
+			&bucket.Bucket, &bucket.Transactions, &bucket.Amount,
+			&bucket.Compliant, &bucket.NonCompliant, &bucket.NeedsReview,
+			&bucket.AverageConfidenceScore, &bucket.AverageMaslahahScore,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan stats series: %w", err)
+		}
+		series = append(series, bucket)
+	}
+
+	return series, rows.Err()
+}
@@ -0,0 +1,162 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+
+	"halalguard-backend/config"
+	"halalguard-backend/database"
+	"halalguard-backend/models"
+
+	"github.com/lib/pq"
+)
+
+// UsageCurrency is the currency of the configured LLM prices
+const UsageCurrency = "USD"
+
+// PriceTokenUsage sets the cost of an LLM call from the per-model price table.
+// Models missing from the table are recorded at zero cost. The usage of an ensemble
+// is priced per model and costs the sum of its calls.
+func PriceTokenUsage(usage models.TokenUsage, prices map[string]config.ModelPrice) models.TokenUsage {
+	if len(usage.Calls) > 0 {
+		calls := make([]models.TokenUsage, len(usage.Calls))
+		usage.Cost = 0
+		for i, call := range usage.Calls {
+			calls[i] = PriceTokenUsage(call, prices)
+			usage.Cost += calls[i].Cost
+		}
+		usage.Calls = calls
+		usage.Cost = math.Round(usage.Cost*1e6) / 1e6
+		return usage
+	}
+
+	price, ok := prices[usage.Model]
+	if !ok {
+		slog.Warn("no price configured for model, recording zero cost", "model", usage.Model)
+		usage.Cost = 0
+		return usage
+	}
+
+	// Tokens beyond prompt and candidates (e.g. thinking tokens) are billed as output
+	outputTokens := max(usage.CandidateTokens, usage.TotalTokens-usage.PromptTokens)
+	cost := float64(usage.PromptTokens)*price.InputPerMillion/1e6 + float64(outputTokens)*price.OutputPerMillion/1e6
+	usage.Cost = math.Round(cost*1e6) / 1e6
+	return usage
+}
+
+// RecordLLMCall stores the token usage of an analysis call, linked to the transactions
+// in its batch, and adds the tokens to the organization's quota counters
+func RecordLLMCall(ctx context.Context, orgID string, usage models.TokenUsage, transactionIDs []string, succeeded bool) error {
+	tx, err := database.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO llm_usage (organization_id, model, prompt_tokens, candidate_tokens, total_tokens, cost, transaction_ids, succeeded)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`, orgID, usage.Model, usage.PromptTokens, usage.CandidateTokens, usage.TotalTokens, usage.Cost, pq.Array(transactionIDs), succeeded)
+	if err != nil {
+		return fmt.Errorf("failed to record LLM usage: %w", err)
+	}
+
+	if err := addUsage(ctx, tx, orgID, 0, usage.TotalTokens); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit LLM usage: %w", err)
+	}
+	return nil
+}
+
+// GetUsageReport aggregates LLM usage and cost by day, organization and model
+func GetUsageReport(filter models.UsageFilter) (*models.UsageReport, error) {
+	query := `
+		SELECT TO_CHAR(created_at, 'YYYY-MM-DD') AS day, organization_id, model,
+			COUNT(*), COALESCE(SUM(CARDINALITY(transaction_ids)), 0),
+			COALESCE(SUM(prompt_tokens), 0), COALESCE(SUM(candidate_tokens), 0),
+			COALESCE(SUM(total_tokens), 0), COALESCE(SUM(cost), 0)
+		FROM llm_usage
+		WHERE ($1 = '' OR organization_id = $1)
+			AND (NULLIF($2, '') IS NULL OR created_at::date >= NULLIF($2, '')::date)
+			AND (NULLIF($3, '') IS NULL OR created_at::date <= NULLIF($3, '')::date)
+		GROUP BY day, organization_id, model
+		ORDER BY day, organization_id, model
+	`
+
+	rows, err := database.DB.Query(query, filter.OrganizationID, filter.From, filter.To)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query LLM usage: %w", err)
+	}
+	defer rows.Close()
+
+	report := &models.UsageReport{Currency: UsageCurrency, Rows: []models.UsageSummary{}}
+	totals := &report.Totals
+	for rows.Next() {
+		var row models.UsageSummary
+		err := rows.Scan(&row.Date, &row.OrganizationID, &row.Model, &row.Calls, &row.Transactions,
+			&row.PromptTokens, &row.CandidateTokens, &row.TotalTokens, &row.Cost)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan LLM usage: %w", err)
+		}
+		report.Rows = append(report.Rows, row)
+
+		totals.Calls += row.Calls
+		totals.Transactions += row.Transactions
+		totals.PromptTokens += row.PromptTokens
+		totals.CandidateTokens += row.CandidateTokens
+		totals.TotalTokens += row.TotalTokens
+		totals.Cost += row.Cost
+	}
+	totals.Cost = math.Round(totals.Cost*1e6) / 1e6
+
+	return report, rows.Err()
+}
+
+// GetTransactionUsage lists the LLM calls whose batch included the transaction
+func GetTransactionUsage(orgID, transactionID string) ([]models.LLMCall, error) {
+	rows, err := database.DB.Query(`
+		SELECT id, organization_id, model, prompt_tokens, candidate_tokens, total_tokens, cost,
+			transaction_ids, succeeded, created_at
+		FROM llm_usage
+		WHERE organization_id = $1 AND transaction_ids @> ARRAY[$2]::text[]
+		ORDER BY created_at DESC
+	`, orgID, transactionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query LLM calls: %w", err)
+	}
+	defer rows.Close()
+
+	calls := []models.LLMCall{}
+	for rows.Next() {
+		var call models.LLMCall
+		err := rows.Scan(&call.ID, &call.OrganizationID, &call.Model, &call.PromptTokens, &call.CandidateTokens,
+			&call.TotalTokens, &call.Cost, pq.Array(&call.TransactionIDs), &call.Succeeded, &call.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan LLM call: %w", err)
+		}
+		calls = append(calls, call)
+	}
+
+	return calls, rows.Err()
+}
+
+// GetAnalysisActivity returns when the organization last called the LLM and how often in the last day
+func GetAnalysisActivity(orgID string) (*models.AnalysisActivity, error) {
+	var activity models.AnalysisActivity
+	err := database.DB.QueryRow(`
+		SELECT MAX(created_at) FILTER (WHERE succeeded),
+			MAX(created_at) FILTER (WHERE NOT succeeded),
+			COUNT(*) FILTER (WHERE created_at >= CURRENT_TIMESTAMP - INTERVAL '24 hours')
+		FROM llm_usage
+		WHERE organization_id = $1
+	`, orgID).Scan(&activity.LastAnalysisAt, &activity.LastFailedAnalysisAt, &activity.AnalysesLast24h)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query analysis activity: %w", err)
+	}
+	return &activity, nil
+}
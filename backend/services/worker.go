@@ -0,0 +1,161 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"halalguard-backend/config"
+	"halalguard-backend/logging"
+	"halalguard-backend/models"
+)
+
+// AnalysisWorkers run queued analysis jobs in the background
+type AnalysisWorkers struct {
+	pipeline *AnalysisPipeline
+	cfg      config.JobsConfig
+	// stop tells idle workers to exit; ctx is cancelled to abort running jobs
+	stop   chan struct{}
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewAnalysisWorkers creates the job workers; they do nothing until Start
+func NewAnalysisWorkers(pipeline *AnalysisPipeline, cfg config.JobsConfig) *AnalysisWorkers {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &AnalysisWorkers{
+		pipeline: pipeline,
+		cfg:      cfg,
+		stop:     make(chan struct{}),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// Start launches the configured number of workers
+func (w *AnalysisWorkers) Start() {
+	for i := 0; i < w.cfg.Workers; i++ {
+		w.wg.Add(1)
+		go w.run()
+	}
+	if w.cfg.Workers > 0 {
+		slog.Info("analysis workers started", "workers", w.cfg.Workers)
+	}
+}
+
+// Stop stops claiming jobs and waits for running jobs to finish. If ctx expires
+// first, running jobs are cancelled and put back in the queue for the next start,
+// and ctx's error is returned.
+func (w *AnalysisWorkers) Stop(ctx context.Context) error {
+	close(w.stop)
+	done := make(chan struct{})
+	go func() {
+		w.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		w.cancel()
+		return nil
+	case <-ctx.Done():
+		w.cancel()
+		<-done
+		return ctx.Err()
+	}
+}
+
+// renewLease extends the job's lease every third of ANALYSIS_JOB_LEASE until ctx is
+// done, so a long analysis is not reclaimed by another worker. If the lease is lost
+// the job is cancelled.
+func (w *AnalysisWorkers) renewLease(ctx context.Context, cancelJob context.CancelFunc, job *models.AnalysisJob) {
+	ticker := time.NewTicker(w.cfg.Lease / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		err := renewAnalysisJobLease(ctx, job, w.cfg.Lease)
+		switch {
+		case errors.Is(err, errJobLeaseLost):
+			slog.WarnContext(ctx, "analysis job lease lost, abandoning job", "job_id", job.ID, "attempt", job.Attempts)
+			cancelJob()
+			return
+		case err != nil && ctx.Err() == nil:
+			slog.ErrorContext(ctx, "failed to renew analysis job lease", "job_id", job.ID, "error", err)
+		}
+	}
+}
+
+func (w *AnalysisWorkers) run() {
+	defer w.wg.Done()
+	for {
+		select {
+		case <-w.stop:
+			return
+		default:
+		}
+
+		job, err := claimAnalysisJob(w.ctx, w.cfg.Lease, w.cfg.MaxAttempts)
+		if err != nil {
+			slog.Error("failed to claim analysis job", "error", err)
+		}
+		if job == nil {
+			select {
+			case <-w.stop:
+				return
+			case <-time.After(w.cfg.PollInterval):
+			}
+			continue
+		}
+		w.process(job)
+	}
+}
+
+// process runs one job, renewing its lease while it runs. A job interrupted by Stop
+// is requeued rather than failed; a job whose lease was lost is abandoned, since
+// another worker has reclaimed it.
+func (w *AnalysisWorkers) process(job *models.AnalysisJob) {
+	ctx := logging.WithRequestID(w.ctx, job.RequestID)
+	slog.InfoContext(ctx, "analysis job started", "job_id", job.ID, "organization_id", job.OrganizationID,
+		"transactions", len(job.Transactions), "attempt", job.Attempts)
+
+	jobCtx, cancelJob := context.WithCancel(ctx)
+	renewed := make(chan struct{})
+	go func() {
+		defer close(renewed)
+		w.renewLease(jobCtx, cancelJob, job)
+	}()
+	response, err := w.pipeline.Run(jobCtx, job.OrganizationID, models.AnalyzeRequest{
+		Transactions: job.Transactions,
+		Language:     job.Language,
+		Generation:   job.Generation,
+	})
+	cancelJob()
+	<-renewed
+
+	bookkeeping := context.WithoutCancel(ctx)
+	if err != nil && w.ctx.Err() != nil {
+		if err := requeueAnalysisJob(bookkeeping, job); err != nil {
+			slog.ErrorContext(ctx, "failed to requeue interrupted analysis job", "job_id", job.ID, "error", err)
+			return
+		}
+		slog.WarnContext(ctx, "analysis job interrupted by shutdown, requeued", "job_id", job.ID)
+		return
+	}
+
+	if err := finishAnalysisJob(bookkeeping, job, response, err); errors.Is(err, errJobLeaseLost) {
+		slog.WarnContext(ctx, "analysis job was reclaimed by another worker, outcome discarded", "job_id", job.ID)
+		return
+	} else if err != nil {
+		slog.ErrorContext(ctx, "failed to store analysis job outcome", "job_id", job.ID, "error", err)
+		return
+	}
+	slog.InfoContext(ctx, "analysis job finished", "job_id", job.ID, "succeeded", err == nil)
+}
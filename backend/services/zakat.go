@@ -0,0 +1,212 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"halalguard-backend/database"
+	"halalguard-backend/models"
+
+	"github.com/lib/pq"
+)
+
+const (
+	// NisabGoldGrams is the nisab of zakat maal expressed in grams of gold
+	NisabGoldGrams = 85.0
+	// ZakatRate is the zakat maal rate (2.5%)
+	ZakatRate = 0.025
+	// HaulDays is the length of one lunar (hijri) year in days
+	HaulDays = 354
+)
+
+var (
+	// ErrGoldPriceNotConfigured is returned when no gold price has been entered for nisab
+	ErrGoldPriceNotConfigured = errors.New("gold price for nisab not configured")
+	// ErrInvalidZakatInput is returned when the submitted holdings cannot be assessed
+	ErrInvalidZakatInput = errors.New("invalid zakat input")
+)
+
+// linkedTransaction is the part of a stored transaction relevant to zakat
+type linkedTransaction struct {
+	Date          string
+	Amount        float64
+	ViolationType string
+}
+
+// CalculateZakat computes zakat maal over the given holdings. Wealth identified as
+// non-halal by the analysis is excluded, since it must be purified instead.
+func CalculateZakat(orgID string, req models.ZakatRequest, syubhatRatio float64) (*models.ZakatCalculation, error) {
+	asOf := req.AsOf
+	if asOf == "" {
+		asOf = time.Now().Format("2006-01-02")
+	}
+	asOfDate, err := time.Parse("2006-01-02", asOf)
+	if err != nil {
+		return nil, fmt.Errorf("%w: asOf date %q must use YYYY-MM-DD", ErrInvalidZakatInput, asOf)
+	}
+
+	goldPrice, err := GetGoldPrice(orgID, asOf)
+	if err != nil {
+		return nil, err
+	}
+
+	linked, err := getLinkedTransactions(orgID, req.Holdings)
+	if err != nil {
+		return nil, err
+	}
+
+	calc := &models.ZakatCalculation{
+		AsOf:             asOf,
+		GoldPricePerGram: goldPrice.PricePerGram,
+		NisabGrams:       NisabGoldGrams,
+		NisabValue:       roundAmount(NisabGoldGrams * goldPrice.PricePerGram),
+		Rate:             ZakatRate,
+		Liabilities:      req.Liabilities,
+		Holdings:         make([]models.ZakatHoldingResult, 0, len(req.Holdings)),
+	}
+
+	var zakatableTotal float64
+	for _, holding := range req.Holdings {
+		result := models.ZakatHoldingResult{
+			ID:          holding.ID,
+			Category:    holding.Category,
+			Description: holding.Description,
+			Value:       holding.Amount,
+		}
+		if holding.Category == models.HoldingGold && holding.Grams > 0 {
+			result.Value = roundAmount(holding.Grams * goldPrice.PricePerGram)
+		}
+
+		haulStart := holding.AcquiredDate
+		for _, id := range holding.TransactionIDs {
+			tx, ok := linked[id]
+			if !ok {
+				return nil, fmt.Errorf("%w: transaction %s referenced by holding %s not found", ErrInvalidZakatInput, id, holding.ID)
+			}
+			if haulStart == "" || tx.Date < haulStart {
+				haulStart = tx.Date
+			}
+			result.NonHalalAmount += math.Abs(tx.Amount) * PurificationRatio(tx.ViolationType, syubhatRatio)
+		}
+		result.NonHalalAmount = roundAmount(math.Min(result.NonHalalAmount, result.Value))
+
+		if haulStart == "" {
+			result.HaulComplete = true
+			result.Note = "haul not tracked, assumed complete"
+		} else if start, err := time.Parse("2006-01-02", haulStart); err == nil {
+			result.HaulStart = haulStart
+			result.HaulDays = int(asOfDate.Sub(start).Hours() / 24)
+			result.HaulComplete = result.HaulDays >= HaulDays
+		} else {
+			return nil, fmt.Errorf("%w: haul start date %q for holding %s must use YYYY-MM-DD", ErrInvalidZakatInput, haulStart, holding.ID)
+		}
+
+		switch {
+		case holding.Category == models.HoldingReceivables && holding.Collectible != nil && !*holding.Collectible:
+			result.Note = "doubtful receivable, excluded until collected"
+		case !result.HaulComplete:
+			result.Note = "haul not yet complete"
+		default:
+			result.ZakatableValue = roundAmount(result.Value - result.NonHalalAmount)
+		}
+
+		calc.TotalValue += result.Value
+		calc.TotalNonHalal += result.NonHalalAmount
+		zakatableTotal += result.ZakatableValue
+		calc.Holdings = append(calc.Holdings, result)
+	}
+
+	calc.TotalValue = roundAmount(calc.TotalValue)
+	calc.TotalNonHalal = roundAmount(calc.TotalNonHalal)
+	calc.NetZakatable = roundAmount(math.Max(0, zakatableTotal-req.Liabilities))
+	calc.MeetsNisab = calc.NetZakatable >= calc.NisabValue
+
+	if calc.MeetsNisab && zakatableTotal > 0 {
+		calc.ZakatDue = roundAmount(calc.NetZakatable * ZakatRate)
+		// Apportion the zakat due across holdings after deducting liabilities
+		share := calc.NetZakatable / zakatableTotal
+		for i := range calc.Holdings {
+			calc.Holdings[i].Zakat = roundAmount(calc.Holdings[i].ZakatableValue * share * ZakatRate)
+		}
+	}
+
+	return calc, nil
+}
+
+// getLinkedTransactions loads the organization's transactions referenced by the holdings
+func getLinkedTransactions(orgID string, holdings []models.HoldingInput) (map[string]linkedTransaction, error) {
+	var ids []string
+	for _, holding := range holdings {
+		ids = append(ids, holding.TransactionIDs...)
+	}
+
+	linked := make(map[string]linkedTransaction, len(ids))
+	if len(ids) == 0 {
+		return linked, nil
+	}
+
+	query := `
+		SELECT t.id, t.date, t.amount, COALESCE(a.violation_type, '')
+		FROM transactions t
+		LEFT JOIN ` + analysisJoinOn + `
+		WHERE t.organization_id = $1 AND t.id = ANY($2)
+	`
+
+	rows, err := database.DB.Query(query, orgID, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query linked transactions: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id string
+		var tx linkedTransaction
+		if err := rows.Scan(&id, &tx.Date, &tx.Amount, &tx.ViolationType); err != nil {
+			return nil, fmt.Errorf("failed to scan linked transaction: %w", err)
+		}
+		linked[id] = tx
+	}
+
+	return linked, rows.Err()
+}
+
+// GetGoldPrice returns the organization's latest gold price effective on or before the given date
+func GetGoldPrice(orgID, asOf string) (*models.GoldPrice, error) {
+	query := `
+		SELECT id, price_per_gram, effective_date, created_at
+		FROM zakat_gold_prices
+		WHERE organization_id = $1 AND effective_date <= $2
+		ORDER BY effective_date DESC, id DESC
+		LIMIT 1
+	`
+
+	var price models.GoldPrice
+	err := database.DB.QueryRow(query, orgID, asOf).Scan(&price.ID, &price.PricePerGram, &price.EffectiveDate, &price.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("%w on or before %s", ErrGoldPriceNotConfigured, asOf)
+		}
+		return nil, fmt.Errorf("failed to query gold price: %w", err)
+	}
+
+	return &price, nil
+}
+
+// SaveGoldPrice stores the gold price per gram the organization uses to compute nisab
+func SaveGoldPrice(orgID string, input models.GoldPriceInput) (*models.GoldPrice, error) {
+	query := `
+		INSERT INTO zakat_gold_prices (organization_id, price_per_gram, effective_date)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at
+	`
+
+	price := &models.GoldPrice{GoldPriceInput: input}
+	if err := database.DB.QueryRow(query, orgID, input.PricePerGram, input.EffectiveDate).Scan(&price.ID, &price.CreatedAt); err != nil {
+		return nil, fmt.Errorf("failed to save gold price: %w", err)
+	}
+
+	return price, nil
+}
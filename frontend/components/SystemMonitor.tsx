@@ -1,59 +1,102 @@
 
 import React, { useState, useEffect } from 'react';
-import { LineChart, Line, XAxis, YAxis, CartesianGrid, Tooltip, ResponsiveContainer, AreaChart, Area } from 'recharts';
-import { ShieldCheck, Activity, Server, Lock, AlertCircle, CheckCircle, RefreshCw, FileText, Database, Scale } from 'lucide-react';
+import { XAxis, YAxis, CartesianGrid, Tooltip, ResponsiveContainer, AreaChart, Area } from 'recharts';
+import { ShieldCheck, Activity, Server, AlertCircle, CheckCircle, RefreshCw, FileText, Database, Scale } from 'lucide-react';
+import { getSystemStatus } from '../services/geminiService';
+import { SystemStatus } from '../types';
+
+const REFRESH_INTERVAL_MS = 15000;
+
+const CHECK_LABELS: Record<string, string> = {
+  database: 'Database PostgreSQL',
+  gemini: 'Gemini API',
+  openai: 'OpenAI-compatible API',
+  ollama: 'Ollama',
+};
+
+const CIRCUIT_LABELS: Record<string, string> = {
+  closed: 'Normal',
+  half_open: 'Pemulihan',
+  open: 'Terputus',
+};
+
+interface LogEntry {
+  ok: boolean;
+  message: string;
+}
+
+const formatTime = (value?: string) => (value ? new Date(value).toLocaleString('id-ID') : '-');
+
+const formatUptime = (seconds: number) => {
+  const days = Math.floor(seconds / 86400);
+  const hours = Math.floor((seconds % 86400) / 3600);
+  const minutes = Math.floor((seconds % 3600) / 60);
+  return days > 0 ? `${days}h ${hours}j` : `${hours}j ${minutes}m`;
+};
+
+const formatPercent = (ratio: number) => `${(ratio * 100).toFixed(1)}%`;
+
+// Builds the log panel from the readiness checks and recent activity
+const buildLogs = (status: SystemStatus): LogEntry[] => {
+  const logs: LogEntry[] = Object.entries(status.checks).map(([name, check]) => ({
+    ok: check.status !== 'down',
+    message: `${CHECK_LABELS[name] || name}... ${check.status.toUpperCase()}${
+      check.latencyMs !== undefined ? ` (${check.latencyMs.toFixed(1)}ms)` : ''
+    }${check.error ? ` - ${check.error}` : ''}`,
+  }));
+  logs.push({
+    ok: status.llm.circuit !== 'open',
+    message: `Circuit breaker ${CHECK_LABELS[status.llm.provider] || status.llm.provider}... ${CIRCUIT_LABELS[status.llm.circuit] || status.llm.circuit}`,
+  });
+  logs.push({ ok: true, message: `Analisis terakhir... ${formatTime(status.analysis.lastAnalysisAt)}` });
+  if (status.analysis.lastFailedAnalysisAt) {
+    logs.push({ ok: false, message: `Analisis gagal terakhir... ${formatTime(status.analysis.lastFailedAnalysisAt)}` });
+  }
+  return logs;
+};
 
 const SystemMonitor: React.FC = () => {
-  const [lastUpdate, setLastUpdate] = useState(new Date());
-  const [healthData, setHealthData] = useState<any[]>([]);
-  const [alerts, setAlerts] = useState<string[]>([
-    "System check initialized...",
-    "Model drift parameters normal.",
-    "Bias detection algorithms active.",
-  ]);
+  const [lastUpdate, setLastUpdate] = useState<Date | null>(null);
+  const [status, setStatus] = useState<SystemStatus | null>(null);
+  const [error, setError] = useState<string | null>(null);
 
-  // Simulate Real-time Data Generation
+  // Poll the backend for real latency, error rate and dependency status
   useEffect(() => {
-    // Initial data
-    const initialData = Array.from({ length: 10 }, (_, i) => ({
-      time: `00:${i * 5}`,
-      latency: 40 + Math.random() * 20,
-      accuracy: 98 + Math.random() * 1.5
-    }));
-    setHealthData(initialData);
+    let cancelled = false;
 
-    const interval = setInterval(() => {
-      setLastUpdate(new Date());
-      
-      // Update Chart Data
-      setHealthData(prev => {
-        const newData = [...prev.slice(1)];
-        const now = new Date();
-        newData.push({
-          time: `${now.getSeconds()}s`,
-          latency: 35 + Math.random() * 30, // Random latency between 35-65ms
-          accuracy: 98 + Math.random() * 2  // Random accuracy fluctuation
-        });
-        return newData;
-      });
+    const refresh = async () => {
+      try {
+        const data = await getSystemStatus();
+        if (cancelled) return;
+        setStatus(data);
+        setError(null);
+        setLastUpdate(new Date());
+      } catch (err) {
+        if (cancelled) return;
+        setError(err instanceof Error ? err.message : 'Failed to fetch system status');
+      }
+    };
 
-      // Add Random System Alert
-      const systemMessages = [
-        "Verifikasi integritas data... OK",
-        "Pengecekan bias IEEE 7003... PATUH",
-        "Sinkronisasi node... SELESAI",
-        "Enkripsi data... AKTIF (AES-256)",
-        "Latency check... STABIL",
-        "Validasi schema OJK... LULUS"
-      ];
-      const randomMsg = systemMessages[Math.floor(Math.random() * systemMessages.length)];
-      setAlerts(prev => [randomMsg, ...prev].slice(0, 5));
+    refresh();
+    const interval = setInterval(refresh, REFRESH_INTERVAL_MS);
 
-    }, 3000); // Visual update every 3s, conceptually representing the 45s cycle chunks
-
-    return () => clearInterval(interval);
+    return () => {
+      cancelled = true;
+      clearInterval(interval);
+    };
   }, []);
 
+  const healthData = (status?.series || []).map(point => ({
+    time: new Date(point.time).toLocaleTimeString('id-ID', { hour: '2-digit', minute: '2-digit' }),
+    p50: point.count > 0 ? point.p50Ms : null,
+    p90: point.count > 0 ? point.p90Ms : null,
+  }));
+
+  const logs: LogEntry[] = [
+    ...(error ? [{ ok: false, message: `Gagal memuat status sistem: ${error}` }] : []),
+    ...(status ? buildLogs(status) : []),
+  ];
+
   return (
     <div className="space-y-8 animate-in fade-in duration-500">
       
@@ -67,7 +110,7 @@ const SystemMonitor: React.FC = () => {
         </div>
         <div className="flex items-center gap-2 text-xs font-mono bg-slate-100 px-3 py-1 rounded-full text-slate-500 mt-2 md:mt-0">
            <RefreshCw className="w-3 h-3 animate-spin" />
-           Last Update: {lastUpdate.toLocaleTimeString()} (Interval: 45s)
+           Last Update: {lastUpdate ? lastUpdate.toLocaleTimeString() : '-'} (Interval: {REFRESH_INTERVAL_MS / 1000}s)
         </div>
       </div>
 
@@ -157,7 +200,7 @@ const SystemMonitor: React.FC = () => {
          <div className="lg:col-span-2 bg-white p-6 rounded-xl shadow-sm border border-slate-200">
             <h3 className="text-lg font-bold text-slate-800 mb-4 flex items-center gap-2">
                <Server className="w-5 h-5 text-indigo-500" />
-               Performa Sistem (Live Latency)
+               Performa Sistem (Latensi {status?.windowMinutes ?? 30} Menit Terakhir)
             </h3>
             <div className="h-64 w-full">
                <ResponsiveContainer width="100%" height="100%">
@@ -170,27 +213,36 @@ const SystemMonitor: React.FC = () => {
                      </defs>
                      <CartesianGrid strokeDasharray="3 3" vertical={false} stroke="#f1f5f9" />
                      <XAxis dataKey="time" hide />
-                     <YAxis hide domain={[0, 100]} />
+                     <YAxis hide />
                      <Tooltip 
                         contentStyle={{borderRadius: '8px', border: 'none', boxShadow: '0 4px 6px -1px rgb(0 0 0 / 0.1)'}}
                         itemStyle={{color: '#6366f1', fontWeight: 'bold'}}
                      />
-                     <Area type="monotone" dataKey="latency" stroke="#6366f1" fillOpacity={1} fill="url(#colorLatency)" strokeWidth={2} name="Latency (ms)" />
+                     <Area type="monotone" dataKey="p90" stroke="#a5b4fc" fill="none" strokeWidth={1} strokeDasharray="4 4" name="p90 (ms)" connectNulls />
+                     <Area type="monotone" dataKey="p50" stroke="#6366f1" fillOpacity={1} fill="url(#colorLatency)" strokeWidth={2} name="p50 (ms)" connectNulls />
                   </AreaChart>
                </ResponsiveContainer>
             </div>
-            <div className="grid grid-cols-3 gap-4 mt-4 text-center">
+            <div className="grid grid-cols-2 md:grid-cols-4 gap-4 mt-4 text-center">
+               <div>
+                  <div className="text-2xl font-bold text-indigo-600">{status ? `${status.http.p50Ms.toFixed(0)}ms` : '-'}</div>
+                  <div className="text-xs text-slate-400">Latensi p50 (p99: {status ? `${status.http.p99Ms.toFixed(0)}ms` : '-'})</div>
+               </div>
                <div>
-                  <div className="text-2xl font-bold text-indigo-600">42ms</div>
-                  <div className="text-xs text-slate-400">Rata-rata Latensi</div>
+                  <div className="text-2xl font-bold text-emerald-600">{status ? formatUptime(status.uptimeSeconds) : '-'}</div>
+                  <div className="text-xs text-slate-400">Uptime Server (v{status?.version ?? '-'})</div>
                </div>
                <div>
-                  <div className="text-2xl font-bold text-emerald-600">99.9%</div>
-                  <div className="text-xs text-slate-400">Uptime Server</div>
+                  <div className="text-2xl font-bold text-blue-600">{status ? formatPercent(status.http.errorRate) : '-'}</div>
+                  <div className="text-xs text-slate-400">Error Rate ({status?.http.count ?? 0} request)</div>
                </div>
                <div>
-                  <div className="text-2xl font-bold text-blue-600">0.0%</div>
-                  <div className="text-xs text-slate-400">Packet Loss</div>
+                  <div className={`text-2xl font-bold ${status?.llm.circuit === 'open' ? 'text-red-600' : 'text-amber-600'}`}>
+                     {status && status.llm.count > 0 ? `${(status.llm.p50Ms / 1000).toFixed(1)}s` : '-'}
+                  </div>
+                  <div className="text-xs text-slate-400">
+                     {status ? CHECK_LABELS[status.llm.provider] || status.llm.provider : 'Model'} p50 ({status ? `${formatPercent(status.llm.errorRate)} error, ${CIRCUIT_LABELS[status.llm.circuit] || status.llm.circuit}` : '-'})
+                  </div>
                </div>
             </div>
          </div>
@@ -238,14 +290,17 @@ const SystemMonitor: React.FC = () => {
             <div className="bg-slate-900 p-4 rounded-xl shadow-sm border border-slate-800 h-[200px] flex flex-col">
                <h3 className="text-xs font-bold text-slate-400 uppercase tracking-wider mb-3 flex items-center gap-2">
                   <AlertCircle className="w-3 h-3" /> Live System Logs
+                  <span className={`ml-auto ${status?.status === 'ready' ? 'text-emerald-400' : 'text-red-400'}`}>
+                     {status ? status.status.toUpperCase() : '...'}
+                  </span>
                </h3>
                <div className="flex-1 overflow-hidden relative">
                   <div className="space-y-2 absolute inset-x-0 bottom-0">
-                     {alerts.map((msg, idx) => (
-                        <div key={idx} className="flex items-center gap-2 text-xs text-emerald-400 font-mono animate-in slide-in-from-bottom-2 fade-in duration-300">
-                           <span className="text-slate-600">[{new Date().toLocaleTimeString().split(' ')[0]}]</span>
-                           <CheckCircle className="w-3 h-3" />
-                           {msg}
+                     {logs.map((log, idx) => (
+                        <div key={idx} className={`flex items-center gap-2 text-xs font-mono animate-in slide-in-from-bottom-2 fade-in duration-300 ${log.ok ? 'text-emerald-400' : 'text-red-400'}`}>
+                           <span className="text-slate-600">[{lastUpdate ? lastUpdate.toLocaleTimeString().split(' ')[0] : '--:--:--'}]</span>
+                           {log.ok ? <CheckCircle className="w-3 h-3 shrink-0" /> : <AlertCircle className="w-3 h-3 shrink-0" />}
+                           <span className="truncate">{log.message}</span>
                         </div>
                      ))}
                   </div>
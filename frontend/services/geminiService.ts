@@ -1,8 +1,16 @@
 
-import { TransactionInput, AnalysisResult } from '../types';
+import { TransactionInput, AnalysisResult, SystemStatus } from '../types';
 
 const API_BASE_URL = import.meta.env.VITE_API_URL || 'http://localhost:8087/api';
 
+// Semua endpoint selain /health memerlukan JWT (disimpan setelah login) atau API key (khusus development)
+const authHeaders = (): Record<string, string> => {
+    const token = localStorage.getItem('halalguard_token');
+    if (token) return { Authorization: `Bearer ${token}` };
+    const apiKey = import.meta.env.VITE_API_KEY;
+    return apiKey ? { 'X-API-Key': apiKey } : {};
+};
+
 export const analyzeTransactions = async (transactions: TransactionInput[]): Promise<AnalysisResult[]> => {
     if (!transactions || transactions.length === 0) return [];
 
@@ -11,6 +19,7 @@ export const analyzeTransactions = async (transactions: TransactionInput[]): Pro
             method: 'POST',
             headers: {
                 'Content-Type': 'application/json',
+                ...authHeaders(),
             },
             body: JSON.stringify({ transactions }),
         });
@@ -30,7 +39,7 @@ export const analyzeTransactions = async (transactions: TransactionInput[]): Pro
 
 export const getAllTransactions = async () => {
     try {
-        const response = await fetch(`${API_BASE_URL}/transactions`);
+        const response = await fetch(`${API_BASE_URL}/transactions`, { headers: authHeaders() });
 
         if (!response.ok) {
             throw new Error('Failed to fetch transactions');
@@ -45,7 +54,7 @@ export const getAllTransactions = async () => {
 
 export const getTransactionById = async (id: string) => {
     try {
-        const response = await fetch(`${API_BASE_URL}/transactions/${id}`);
+        const response = await fetch(`${API_BASE_URL}/transactions/${id}`, { headers: authHeaders() });
 
         if (!response.ok) {
             throw new Error('Failed to fetch transaction');
@@ -67,3 +76,18 @@ export const healthCheck = async () => {
         throw error;
     }
 };
+
+export const getSystemStatus = async (): Promise<SystemStatus> => {
+    try {
+        const response = await fetch(`${API_BASE_URL}/system/status`, { headers: authHeaders() });
+
+        if (!response.ok) {
+            throw new Error('Failed to fetch system status');
+        }
+
+        return await response.json();
+    } catch (error) {
+        console.error('Failed to fetch system status:', error);
+        throw error;
+    }
+};
@@ -43,6 +43,15 @@ export interface MaslahahAnalysis {
   longTermProjection: string; // Prediksi dampak jangka panjang
 }
 
+export interface GenerationSettings {
+  model: string;
+  temperature: number;
+  topP: number;
+  topK: number;
+  maxOutputTokens: number;
+  seed?: number;
+}
+
 export interface AnalysisResult {
   transactionId: string;
   status: ComplianceStatus;
@@ -52,8 +61,62 @@ export interface AnalysisResult {
   reasoning: string;
   suggestedCorrection?: string;
   maslahahAnalysis?: MaslahahAnalysis; // New field for social impact
+  language?: 'id' | 'en' | 'ar'; // Language of reasoning
+  promptVersion?: string;
+  generation?: GenerationSettings; // Model and sampling parameters used
+}
+
+export interface AnalysisText {
+  language: 'id' | 'en' | 'ar';
+  reasoning: string;
+  suggestedCorrection?: string;
+  longTermProjection?: string;
+  promptVersion?: string;
+  createdAt: string;
 }
 
 export interface CombinedResult extends TransactionInput {
   analysis?: AnalysisResult;
+  texts?: AnalysisText[]; // Reasoning per analyzed language (GET /transactions/:id)
+}
+
+export interface HealthCheck {
+  status: 'ok' | 'degraded' | 'down';
+  latencyMs?: number;
+  error?: string;
+}
+
+export interface LatencySummary {
+  count: number;
+  errorRate: number; // 0-1
+  p50Ms: number;
+  p90Ms: number;
+  p99Ms: number;
+}
+
+export interface LatencyPoint extends LatencySummary {
+  time: string;
+}
+
+export interface SystemStatus {
+  status: 'ready' | 'not_ready';
+  version: string;
+  startedAt: string;
+  uptimeSeconds: number;
+  windowMinutes: number;
+  checks: Record<string, HealthCheck>;
+  http: LatencySummary;
+  llm: LatencySummary & {
+    provider: 'gemini' | 'openai' | 'ollama';
+    circuit: 'closed' | 'open' | 'half_open';
+    lastSuccessAt?: string;
+    lastFailureAt?: string;
+    lastError?: string;
+  };
+  analysis: {
+    lastAnalysisAt?: string;
+    lastFailedAnalysisAt?: string;
+    analysesLast24h: number;
+  };
+  series: LatencyPoint[];
 }